@@ -0,0 +1,309 @@
+// apply.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Planned actions of a bulk apply
+const (
+	planCreate    = "create"
+	planUpdate    = "update"
+	planUnchanged = "unchanged"
+)
+
+// States of a repository during a bulk apply
+const (
+	applyPending = "pending"
+	applyRunning = "running"
+	applyDone    = "done"
+	applyFailed  = "failed"
+	applySkipped = "skipped"
+)
+
+// DiffLine is a line of a line diff; Op is ' ' for a kept line, '-' for a removed one and
+// '+' for an added one
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// ApplyChange is what a bulk apply does with a saved item in a repository
+type ApplyChange struct {
+	Kind   string
+	Name   string
+	Action string     // planCreate, planUpdate or planUnchanged
+	Diff   []DiffLine // workflows only: from the file on GitHub to the saved one
+}
+
+// ApplyRepoPlan lists the changes for a repository; Error is set when it could not be checked
+type ApplyRepoPlan struct {
+	Repo    string
+	Changes []ApplyChange
+	Error   string
+}
+
+// ApplyPlan is the plan of a bulk apply, reviewed before it runs
+type ApplyPlan []ApplyRepoPlan
+
+// ApplyRepoReport is the outcome of a bulk apply in a repository
+type ApplyRepoReport struct {
+	Repo    string           `json:"repo"`
+	Status  string           `json:"status"`
+	Results OperationResults `json:"results"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// ApplyReport is the final report of a bulk apply
+type ApplyReport struct {
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Repos      []ApplyRepoReport `json:"repositories"`
+}
+
+// PlanApply compares saved secrets and workflows with each repository on GitHub. Secret
+// values cannot be read back, so a secret that exists is always updated; a workflow is
+// updated only when its file differs from the saved one.
+func PlanApply(ctx context.Context, ghm GHM, state StateProvider, repos, secrets, workflows []string) (ApplyPlan, error) {
+	saved := make(map[string]string, len(workflows))
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			for _, name := range workflows {
+				content, exists := tx.Workflow(name)
+				if !exists {
+					return fmt.Errorf("workflow '%s' not found", name)
+				}
+				saved[name] = content
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make(ApplyPlan, 0, len(repos))
+	for i, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reportProgress(ctx, "checking "+repo, float64(i)/float64(len(repos)))
+
+		repoPlan, err := planRepo(ctx, ghm, repo, secrets, workflows, saved)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			repoPlan = ApplyRepoPlan{Repo: repo, Error: err.Error()}
+		}
+		plan = append(plan, repoPlan)
+	}
+	return plan, nil
+}
+
+// planRepo plans the changes for one repository
+func planRepo(ctx context.Context, ghm GHM, repo string, secrets, workflows []string, saved map[string]string) (ApplyRepoPlan, error) {
+	plan := ApplyRepoPlan{Repo: repo}
+	if len(secrets) > 0 {
+		remote, err := ghm.ListRepoSecrets(ctx, repo)
+		if err != nil {
+			return plan, err
+		}
+		for _, name := range secrets {
+			action := planCreate
+			if contains(remote, name) {
+				action = planUpdate
+			}
+			plan.Changes = append(plan.Changes, ApplyChange{Kind: kindSecret, Name: name, Action: action})
+		}
+	}
+	for _, name := range workflows {
+		remote, found, err := ghm.WorkflowContent(ctx, repo, name)
+		if err != nil {
+			return plan, err
+		}
+		change := ApplyChange{Kind: kindWorkflow, Name: name, Action: planCreate, Diff: DiffLines(remote, saved[name])}
+		if found {
+			change.Action = planUpdate
+			if remote == saved[name] {
+				change.Action, change.Diff = planUnchanged, nil
+			}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	return plan, nil
+}
+
+// Count returns how many changes of the plan have the action
+func (p ApplyPlan) Count(action string) int {
+	count := 0
+	for _, repo := range p {
+		for _, change := range repo.Changes {
+			if change.Action == action {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// ExecuteApplyPlan pushes the planned changes repository by repository, leaving out
+// unchanged workflows and repositories that could not be checked. update is called when a
+// repository starts and when it ends. After a cancellation the remaining repositories are
+// skipped and the context's error is returned with the report.
+func ExecuteApplyPlan(ctx context.Context, ghm GHM, plan ApplyPlan, update func(ApplyRepoReport)) (ApplyReport, error) {
+	report := ApplyReport{StartedAt: time.Now()}
+	for _, repoPlan := range plan {
+		report.Repos = append(report.Repos, ApplyRepoReport{Repo: repoPlan.Repo, Status: applyPending})
+	}
+
+	var err error
+	for i, repoPlan := range plan {
+		repo := &report.Repos[i]
+		switch {
+		case err != nil:
+			repo.Status, repo.Error = applySkipped, "cancelled"
+			continue
+		case repoPlan.Error != "":
+			repo.Status, repo.Error = applySkipped, repoPlan.Error
+			update(*repo)
+			continue
+		}
+
+		repo.Status = applyRunning
+		update(*repo)
+		err = applyRepo(ctx, ghm, repoPlan, repo)
+		update(*repo)
+	}
+	report.FinishedAt = time.Now()
+	return report, err
+}
+
+// applyRepo pushes the changes for one repository and records the outcome in repo. It
+// returns an error only when the apply was cancelled.
+func applyRepo(ctx context.Context, ghm GHM, plan ApplyRepoPlan, repo *ApplyRepoReport) error {
+	var secrets, workflows []string
+	for _, change := range plan.Changes {
+		switch {
+		case change.Action == planUnchanged:
+			repo.Results = append(repo.Results, OperationResult{Kind: change.Kind, Name: change.Name, Repo: plan.Repo, Action: actionUnchanged})
+		case change.Kind == kindSecret:
+			secrets = append(secrets, change.Name)
+		default:
+			workflows = append(workflows, change.Name)
+		}
+	}
+
+	// The tracked repositories are recorded by the GHM as items are added, so the copy can be thrown away
+	scratch := &ReposConfig{Repositories: make(map[string]RepoConfig)}
+	var err error
+	if len(secrets) > 0 {
+		var added OperationResults
+		added, err = ghm.AddSecretsToRepo(ctx, plan.Repo, secrets, scratch)
+		repo.Results = append(repo.Results, added...)
+	}
+	if err == nil && len(workflows) > 0 {
+		var added OperationResults
+		added, err = ghm.AddWorkflowsToRepo(ctx, plan.Repo, workflows, scratch)
+		repo.Results = append(repo.Results, added...)
+	}
+
+	repo.Status = applyDone
+	switch {
+	case ctx.Err() != nil:
+		repo.Status, repo.Error = applyFailed, "cancelled"
+		return ctx.Err()
+	case err != nil:
+		repo.Status, repo.Error = applyFailed, err.Error()
+	case repo.Results.failed() > 0:
+		repo.Status = applyFailed
+	}
+	return nil
+}
+
+// Results returns the results of every repository in order
+func (r ApplyReport) Results() OperationResults {
+	var results OperationResults
+	for _, repo := range r.Repos {
+		results = append(results, repo.Results...)
+	}
+	return results
+}
+
+// Count returns how many repositories ended in the status
+func (r ApplyReport) Count(status string) int {
+	count := 0
+	for _, repo := range r.Repos {
+		if repo.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// SaveApplyReport writes the report as indented JSON; it never contains secret values
+func SaveApplyReport(report ApplyReport, path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// DiffLines returns the line diff from old to new, based on their longest common
+// subsequence of lines. An empty old text yields only added lines.
+func DiffLines(old, new string) []DiffLine {
+	a, b := splitLines(old), splitLines(new)
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var diff []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: ' ', Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, DiffLine{Op: '-', Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: '+', Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: '-', Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: '+', Text: b[j]})
+	}
+	return diff
+}
+
+// splitLines splits text into lines without the final newline; empty text has no lines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
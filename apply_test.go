@@ -0,0 +1,167 @@
+// apply_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *fakeGHM) ListRepos(ctx context.Context) ([]string, error) {
+	return f.repos, nil
+}
+
+func (f *fakeGHM) ListRepoSecrets(ctx context.Context, repo string) ([]string, error) {
+	if f.fail[repo] {
+		return nil, fmt.Errorf("not found")
+	}
+	return f.secrets[repo], nil
+}
+
+func (f *fakeGHM) WorkflowContent(ctx context.Context, repo, name string) (string, bool, error) {
+	if f.fail[repo] {
+		return "", false, fmt.Errorf("not found")
+	}
+	content, found := f.workflows[repo+"/"+name]
+	return content, found, nil
+}
+
+func (f *fakeGHM) AddSecretsToRepo(ctx context.Context, repo string, names []string, reposConfig *ReposConfig) (OperationResults, error) {
+	var results OperationResults
+	for _, name := range names {
+		result := OperationResult{Kind: "secret", Name: name, Repo: repo, Action: "added"}
+		if err := f.AddSecret(ctx, repo, name, "value of "+name); err != nil {
+			result.Action, result.Error = "failed", err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *fakeGHM) AddWorkflowsToRepo(ctx context.Context, repo string, names []string, reposConfig *ReposConfig) (OperationResults, error) {
+	var results OperationResults
+	for _, name := range names {
+		result := OperationResult{Kind: "workflow", Name: name, Repo: repo, Action: "added"}
+		if err := f.AddWorkflow(ctx, repo, name, ""); err != nil {
+			result.Action, result.Error = "failed", err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// openApplyTestStore opens a state store with a saved secret and two saved workflows
+func openApplyTestStore(t *testing.T) *StateStore {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	require.NoError(t, store.Update(func(tx *StateTx) error {
+		require.NoError(t, tx.PutSecret("API_KEY", "one"))
+		require.NoError(t, tx.PutWorkflow("ci.yml", "name: CI\non: [push]\njobs: {}\n"))
+		return tx.PutWorkflow("lint.yml", "name: Lint\n")
+	}))
+	return store
+}
+
+// TestDiffLines tests the line diff of changed, new and identical files
+func TestDiffLines(t *testing.T) {
+	diff := DiffLines("name: CI\non: [push]\njobs: {}\n", "name: CI\non: [push, pull_request]\njobs: {}\n")
+	assert.Equal(t, []DiffLine{
+		{Op: ' ', Text: "name: CI"},
+		{Op: '-', Text: "on: [push]"},
+		{Op: '+', Text: "on: [push, pull_request]"},
+		{Op: ' ', Text: "jobs: {}"},
+	}, diff)
+
+	assert.Equal(t, []DiffLine{{Op: '+', Text: "a"}, {Op: '+', Text: "b"}}, DiffLines("", "a\nb"))
+	assert.Equal(t, []DiffLine{{Op: ' ', Text: "a"}}, DiffLines("a\n", "a\n"))
+}
+
+// TestPlanApply tests creating, updating and keeping items, and repositories that cannot be checked
+func TestPlanApply(t *testing.T) {
+	store := openApplyTestStore(t)
+	ghm := &fakeGHM{
+		fail:      map[string]bool{"acme/gone": true},
+		secrets:   map[string][]string{"acme/api": {"API_KEY"}},
+		workflows: map[string]string{"acme/api/ci.yml": "name: CI\non: [push]\njobs: {}\n", "acme/web/ci.yml": "name: CI\n"},
+	}
+
+	plan, err := PlanApply(context.Background(), ghm, store, []string{"acme/api", "acme/web", "acme/gone"},
+		[]string{"API_KEY"}, []string{"ci.yml", "lint.yml"})
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+
+	assert.Equal(t, []ApplyChange{
+		{Kind: "secret", Name: "API_KEY", Action: "update"},
+		{Kind: "workflow", Name: "ci.yml", Action: "unchanged"},
+		{Kind: "workflow", Name: "lint.yml", Action: "create", Diff: []DiffLine{{Op: '+', Text: "name: Lint"}}},
+	}, plan[0].Changes)
+	assert.Equal(t, "create", plan[1].Changes[0].Action)
+	assert.Equal(t, "update", plan[1].Changes[1].Action)
+	assert.Equal(t, "not found", plan[2].Error)
+	assert.Equal(t, 3, plan.Count("create"))
+	assert.Equal(t, 2, plan.Count("update"))
+
+	_, err = PlanApply(context.Background(), ghm, store, []string{"acme/api"}, nil, []string{"missing.yml"})
+	assert.EqualError(t, err, "workflow 'missing.yml' not found")
+}
+
+// TestExecuteApplyPlan tests that only changes are pushed and that each repository is reported as it goes
+func TestExecuteApplyPlan(t *testing.T) {
+	ghm := &fakeGHM{fail: map[string]bool{"deploy.yml": true}}
+	plan := ApplyPlan{
+		{Repo: "acme/api", Changes: []ApplyChange{
+			{Kind: "secret", Name: "API_KEY", Action: "update"},
+			{Kind: "workflow", Name: "ci.yml", Action: "unchanged"},
+		}},
+		{Repo: "acme/web", Changes: []ApplyChange{{Kind: "workflow", Name: "deploy.yml", Action: "create"}}},
+		{Repo: "acme/gone", Error: "not found"},
+	}
+
+	var updates []string
+	report, err := ExecuteApplyPlan(context.Background(), ghm, plan, func(repo ApplyRepoReport) {
+		updates = append(updates, repo.Repo+" "+repo.Status)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"add-secret acme/api API_KEY=value of API_KEY", "add-workflow acme/web deploy.yml"}, ghm.calls)
+	assert.Equal(t, []string{"acme/api running", "acme/api done", "acme/web running", "acme/web failed", "acme/gone skipped"}, updates)
+	assert.Equal(t, 1, report.Count("done"))
+	assert.Len(t, report.Results(), 3)
+	assert.Equal(t, "unchanged", report.Repos[0].Results[0].Action)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = ExecuteApplyPlan(ctx, ghm, plan[:2], func(ApplyRepoReport) {})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "failed", report.Repos[0].Status)
+	assert.Equal(t, "skipped", report.Repos[1].Status)
+}
+
+// TestSaveApplyReport tests the JSON form of the report
+func TestSaveApplyReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := ApplyReport{Repos: []ApplyRepoReport{{Repo: "acme/api", Status: "done",
+		Results: OperationResults{{Kind: "secret", Name: "API_KEY", Repo: "acme/api", Action: "added"}}}}}
+	require.NoError(t, SaveApplyReport(report, path))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var saved map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Contains(t, saved, "started_at")
+	repos := saved["repositories"].([]interface{})
+	assert.Equal(t, "done", repos[0].(map[string]interface{})["status"])
+	assert.NotContains(t, string(data), "value of", "reports never contain secret values")
+}
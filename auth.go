@@ -0,0 +1,363 @@
+// auth.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Default endpoints for GitHub's OAuth device authorization flow
+const (
+	defaultDeviceCodeURL = "https://github.com/login/device/code"
+	defaultTokenURL      = "https://github.com/login/oauth/access_token"
+	defaultOAuthScopes   = "repo,workflow"
+
+	deviceGrantType  = "urn:ietf:params:oauth:grant-type:device_code"
+	refreshGrantType = "refresh_token"
+
+	// tokenRefreshMargin is how long before expiry a token is considered stale
+	tokenRefreshMargin = time.Minute
+)
+
+// Errors returned by the token endpoint while polling
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrDeviceCodeExpired    = errors.New("device code expired")
+	ErrAccessDenied         = errors.New("access denied by user")
+)
+
+// DeviceCode is the response of the device authorization endpoint
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// OAuthToken holds an access token and, when the OAuth app issues expiring tokens, its refresh token
+type OAuthToken struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	Scope                 string    `json:"scope"`
+	ExpiresIn             int       `json:"expires_in,omitempty"`
+	RefreshToken          string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresIn int       `json:"refresh_token_expires_in,omitempty"`
+	Expiry                time.Time `json:"-"`
+}
+
+// tokenResponse is the raw token endpoint response, which reports errors in the body
+type tokenResponse struct {
+	OAuthToken
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceFlow implements the OAuth 2.0 device authorization grant (RFC 8628)
+type DeviceFlow struct {
+	ClientID      string
+	Scopes        []string
+	DeviceCodeURL string
+	TokenURL      string
+	PollInterval  time.Duration // Overrides the server-provided interval when set
+	HTTPClient    *http.Client
+	Logger        *logrus.Logger
+}
+
+// NewDeviceFlow creates a DeviceFlow from the oauth_* configuration keys
+func NewDeviceFlow(logger *logrus.Logger) *DeviceFlow {
+	deviceCodeURL := viper.GetString("oauth_device_code_url")
+	if deviceCodeURL == "" {
+		deviceCodeURL = defaultDeviceCodeURL
+	}
+	tokenURL := viper.GetString("oauth_token_url")
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+	scopes := viper.GetString("oauth_scopes")
+	if scopes == "" {
+		scopes = defaultOAuthScopes
+	}
+
+	return &DeviceFlow{
+		ClientID:      viper.GetString("oauth_client_id"),
+		Scopes:        strings.Split(scopes, ","),
+		DeviceCodeURL: deviceCodeURL,
+		TokenURL:      tokenURL,
+		HTTPClient:    http.DefaultClient,
+		Logger:        logger,
+	}
+}
+
+// RequestCode asks the authorization server for a device and user code
+func (d *DeviceFlow) RequestCode(ctx context.Context) (*DeviceCode, error) {
+	if d.ClientID == "" {
+		return nil, fmt.Errorf("oauth client ID not configured")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", d.ClientID)
+	form.Set("scope", strings.Join(d.Scopes, " "))
+
+	code := &DeviceCode{}
+	if err := d.postForm(ctx, d.DeviceCodeURL, form, code); err != nil {
+		d.Logger.Errorf("Error requesting device code: %v", err)
+		return nil, err
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("device code response is missing codes")
+	}
+
+	return code, nil
+}
+
+// PollToken polls the token endpoint until the user authorizes the device, denies it, or the code expires
+func (d *DeviceFlow) PollToken(ctx context.Context, code *DeviceCode) (*OAuthToken, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	slowDownStep := 5 * time.Second
+	if d.PollInterval > 0 {
+		interval = d.PollInterval
+		slowDownStep = d.PollInterval
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	var deadline <-chan time.Time
+	if code.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(code.ExpiresIn) * time.Second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	form := url.Values{}
+	form.Set("client_id", d.ClientID)
+	form.Set("device_code", code.DeviceCode)
+	form.Set("grant_type", deviceGrantType)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, ErrDeviceCodeExpired
+		case <-time.After(interval):
+		}
+
+		token, err := d.requestToken(ctx, form)
+		switch {
+		case err == nil:
+			return token, nil
+		case errors.Is(err, ErrAuthorizationPending):
+			d.Logger.Debug("Authorization pending; polling again.")
+		case errors.Is(err, ErrSlowDown):
+			interval += slowDownStep
+			d.Logger.Debugf("Server asked to slow down; polling every %s.", interval)
+		default:
+			return nil, err
+		}
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token
+func (d *DeviceFlow) Refresh(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	if refreshToken == "" {
+		return nil, fmt.Errorf("no refresh token available")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", d.ClientID)
+	form.Set("grant_type", refreshGrantType)
+	form.Set("refresh_token", refreshToken)
+
+	token, err := d.requestToken(ctx, form)
+	if err != nil {
+		d.Logger.Errorf("Error refreshing token: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// requestToken calls the token endpoint and maps OAuth error codes to errors
+func (d *DeviceFlow) requestToken(ctx context.Context, form url.Values) (*OAuthToken, error) {
+	resp := &tokenResponse{}
+	if err := d.postForm(ctx, d.TokenURL, form, resp); err != nil {
+		return nil, err
+	}
+
+	switch resp.Error {
+	case "":
+	case "authorization_pending":
+		return nil, ErrAuthorizationPending
+	case "slow_down":
+		return nil, ErrSlowDown
+	case "expired_token":
+		return nil, ErrDeviceCodeExpired
+	case "access_denied":
+		return nil, ErrAccessDenied
+	default:
+		return nil, fmt.Errorf("token endpoint error %s: %s", resp.Error, resp.ErrorDescription)
+	}
+
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("token response is missing access token")
+	}
+
+	token := resp.OAuthToken
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// postForm posts a form and decodes the JSON response into out
+func (d *DeviceFlow) postForm(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := d.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", res.Status, endpoint)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// saveOAuthToken stores the access token, refresh token and expiry in the credential store
+func saveOAuthToken(token *OAuthToken, logger *logrus.Logger) error {
+	expiry := ""
+	if !token.Expiry.IsZero() {
+		expiry = token.Expiry.Format(time.RFC3339)
+	}
+
+	values := map[string]string{
+		"github_token":         token.AccessToken,
+		"github_refresh_token": token.RefreshToken,
+		"github_token_expiry":  expiry,
+	}
+	for key, value := range values {
+		storeConfig := &StoreConfigStrategy{
+			ConfigKey:   key,
+			ConfigValue: value,
+			Logger:      logger,
+		}
+		if err := storeConfig.Execute(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshTokenIfExpired renews the stored access token when it is about to expire
+func refreshTokenIfExpired(ctx context.Context, logger *logrus.Logger) error {
+	expiry := getCredential("github_token_expiry", logger)
+	refreshToken := getCredential("github_refresh_token", logger)
+	if expiry == "" || refreshToken == "" {
+		return nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		logger.Warnf("Ignoring invalid token expiry '%s': %v", expiry, err)
+		return nil
+	}
+	if time.Until(expiresAt) > tokenRefreshMargin {
+		return nil
+	}
+
+	logger.Info("GitHub token expired; refreshing.")
+	token, err := NewDeviceFlow(logger).Refresh(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return saveOAuthToken(token, logger)
+}
+
+// Initialize Auth Command
+func initAuthCmd(logger *logrus.Logger) *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Authenticate with GitHub",
+	}
+
+	authCmd.AddCommand(initAuthLoginCmd(logger))
+
+	return authCmd
+}
+
+// Initialize Auth Login Command
+func initAuthLoginCmd(logger *logrus.Logger) *cobra.Command {
+	var clientID, scopes string
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to GitHub using the OAuth device flow",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flow := NewDeviceFlow(logger)
+			if clientID != "" {
+				flow.ClientID = clientID
+			}
+			if scopes != "" {
+				flow.Scopes = strings.Split(scopes, ",")
+			}
+
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			code, err := flow.RequestCode(ctx)
+			if err != nil {
+				return err
+			}
+
+			PromptColor.Fprintf(os.Stderr, "First copy your one-time code: %s\n", code.UserCode)
+			InfoColor.Fprintf(os.Stderr, "Then open %s in your browser to authorize ghm.\n", code.VerificationURI)
+
+			token, err := flow.PollToken(ctx, code)
+			if err != nil {
+				logger.Errorf("Error waiting for authorization: %v", err)
+				return err
+			}
+
+			if err := saveOAuthToken(token, logger); err != nil {
+				logger.Errorf("Error storing GitHub token: %v", err)
+				return err
+			}
+
+			SuccessColor.Fprintln(os.Stderr, "Logged in to GitHub successfully.")
+			return printResult(cmd, OperationResult{Kind: kindCredential, Name: "github_token", Action: actionStored})
+		},
+	}
+
+	loginCmd.Flags().StringVar(&clientID, "client-id", "", "OAuth app client ID (defaults to the oauth_client_id config key)")
+	loginCmd.Flags().StringVar(&scopes, "scopes", "", "Comma-separated OAuth scopes to request")
+
+	return loginCmd
+}
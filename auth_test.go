@@ -0,0 +1,123 @@
+// auth_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeviceFlowServer starts a stand-in for GitHub's device flow endpoints.
+// The token endpoint answers with the given OAuth errors before issuing a token.
+func newDeviceFlowServer(t *testing.T, pending ...string) (*httptest.Server, *int32) {
+	var polls int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/device/code", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "test-client", r.PostForm.Get("client_id"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-123",
+			"user_code":        "ABCD-1234",
+			"verification_uri": "https://github.com/login/device",
+			"expires_in":       60,
+			"interval":         5,
+		})
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		if r.PostForm.Get("grant_type") == "refresh_token" {
+			assert.Equal(t, "refresh-1", r.PostForm.Get("refresh_token"))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "access-2",
+				"refresh_token": "refresh-2",
+				"expires_in":    28800,
+			})
+			return
+		}
+
+		assert.Equal(t, "device-123", r.PostForm.Get("device_code"))
+		n := int(atomic.AddInt32(&polls, 1))
+		if n <= len(pending) {
+			json.NewEncoder(w).Encode(map[string]string{"error": pending[n-1]})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-1",
+			"token_type":    "bearer",
+			"refresh_token": "refresh-1",
+			"expires_in":    28800,
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &polls
+}
+
+// newTestDeviceFlow returns a DeviceFlow pointed at the stand-in server
+func newTestDeviceFlow(server *httptest.Server) *DeviceFlow {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	return &DeviceFlow{
+		ClientID:      "test-client",
+		Scopes:        []string{"repo"},
+		DeviceCodeURL: server.URL + "/login/device/code",
+		TokenURL:      server.URL + "/login/oauth/access_token",
+		PollInterval:  time.Millisecond,
+		HTTPClient:    server.Client(),
+		Logger:        logger,
+	}
+}
+
+// TestDeviceFlowLogin tests polling through pending and slow_down responses
+func TestDeviceFlowLogin(t *testing.T) {
+	server, polls := newDeviceFlowServer(t, "authorization_pending", "slow_down")
+	flow := newTestDeviceFlow(server)
+
+	code, err := flow.RequestCode(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-1234", code.UserCode)
+
+	token, err := flow.PollToken(context.Background(), code)
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(8*time.Hour), token.Expiry, time.Minute)
+	assert.Equal(t, int32(3), atomic.LoadInt32(polls))
+}
+
+// TestDeviceFlowAccessDenied tests that a denied authorization stops polling
+func TestDeviceFlowAccessDenied(t *testing.T) {
+	server, _ := newDeviceFlowServer(t, "authorization_pending", "access_denied")
+	flow := newTestDeviceFlow(server)
+
+	code, err := flow.RequestCode(context.Background())
+	require.NoError(t, err)
+
+	_, err = flow.PollToken(context.Background(), code)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
+
+// TestDeviceFlowRefresh tests exchanging a refresh token
+func TestDeviceFlowRefresh(t *testing.T) {
+	server, _ := newDeviceFlowServer(t)
+	flow := newTestDeviceFlow(server)
+
+	token, err := flow.Refresh(context.Background(), "refresh-1")
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token.AccessToken)
+	assert.Equal(t, "refresh-2", token.RefreshToken)
+}
@@ -0,0 +1,435 @@
+// batch.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Operations of a batch file
+const (
+	opAddSecret    = "add-secret"
+	opAddWorkflow  = "add-workflow"
+	opRemoveSecret = "remove-secret"
+	opStoreConfig  = "store-config"
+)
+
+// batchProgressDir holds the progress of unfinished batch runs, below the data directory
+const batchProgressDir = "batches"
+
+// BatchOperation is one line of a batch file. Relative file paths are resolved against
+// the directory of the batch file.
+type BatchOperation struct {
+	Op        string `json:"op"`
+	Repo      string `json:"repo,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Value     string `json:"value,omitempty"`
+	ValueFile string `json:"value_file,omitempty"`
+	ValueEnv  string `json:"value_env,omitempty"`
+	ValueCmd  string `json:"value_cmd,omitempty"`
+	Content   string `json:"content,omitempty"`
+	File      string `json:"file,omitempty"`
+	Local     bool   `json:"local,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Force     bool   `json:"force,omitempty"`
+}
+
+// BatchLine is an operation and its line number in the batch file
+type BatchLine struct {
+	Number    int
+	Operation BatchOperation
+}
+
+// batchProgress records the last line of a batch file that completed successfully
+type batchProgress struct {
+	File    string    `json:"file"`
+	Hash    string    `json:"hash"`
+	Line    int       `json:"line"`
+	Updated time.Time `json:"updated"`
+}
+
+// ParseBatch parses a JSONL batch file; blank lines are skipped and unknown fields are errors
+func ParseBatch(data []byte) ([]BatchLine, error) {
+	var lines []BatchLine
+	for i, text := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		var op BatchOperation
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&op); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		lines = append(lines, BatchLine{Number: i + 1, Operation: op})
+	}
+	return lines, nil
+}
+
+// Validate checks that the operation is complete without running it. Secret names, value
+// files and value variables are checked; value commands are not run.
+func (op BatchOperation) Validate() error {
+	switch op.Op {
+	case opAddSecret, opAddWorkflow, opRemoveSecret:
+		if op.Repo == "" {
+			return fmt.Errorf("repo is required")
+		}
+		if _, _, err := splitRepo(op.Repo); err != nil {
+			return err
+		}
+		if op.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+	case opStoreConfig:
+		if op.Key == "" {
+			return fmt.Errorf("key is required")
+		}
+		if op.Value == "" {
+			return fmt.Errorf("value is required")
+		}
+		return ValidateSetting(op.Key, op.Value, op.Force)
+	case "":
+		return fmt.Errorf("op is required")
+	default:
+		return fmt.Errorf("unknown op '%s'; use %s, %s, %s or %s", op.Op, opAddSecret, opAddWorkflow, opRemoveSecret, opStoreConfig)
+	}
+
+	switch op.Op {
+	case opAddSecret:
+		if err := ValidateSecretName(op.Name, secretNameRules()); err != nil {
+			return err
+		}
+		source := op.valueSource()
+		if !source.IsSet() {
+			return fmt.Errorf("one of value, value_file, value_env or value_cmd is required")
+		}
+		if err := source.checkSingle(); err != nil {
+			return err
+		}
+		if source.Command == "" {
+			if _, err := source.Read(nil); err != nil {
+				return err
+			}
+		}
+	case opAddWorkflow:
+		if (op.Content == "") == (op.File == "") {
+			return fmt.Errorf("exactly one of content or file is required")
+		}
+		if op.File != "" {
+			if _, err := os.Stat(op.File); err != nil {
+				return err
+			}
+		}
+	case opRemoveSecret:
+		// Team conventions do not apply, so secrets predating them can still be removed
+		if err := ValidateSecretName(op.Name, SecretNameRules{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueSource returns where an add-secret operation reads its value
+func (op BatchOperation) valueSource() SecretValueSource {
+	return SecretValueSource{Value: op.Value, HasValue: op.Value != "", File: op.ValueFile, Env: op.ValueEnv, Command: op.ValueCmd}
+}
+
+// resolvePaths makes the file paths of the operation relative to dir
+func (op *BatchOperation) resolvePaths(dir string) {
+	if op.File != "" && !filepath.IsAbs(op.File) {
+		op.File = filepath.Join(dir, op.File)
+	}
+	if op.ValueFile != "" && !filepath.IsAbs(op.ValueFile) {
+		op.ValueFile = filepath.Join(dir, op.ValueFile)
+	}
+}
+
+// needsToken reports whether the operation calls the GitHub API
+func (op BatchOperation) needsToken() bool {
+	return op.Op != opStoreConfig
+}
+
+// result describes the operation with the given action
+func (op BatchOperation) result(line int, action string) BatchResult {
+	result := BatchResult{Line: line, Op: op.Op}
+	result.Name = op.Name
+	result.Repo = op.Repo
+	result.Action = action
+	switch op.Op {
+	case opAddSecret, opRemoveSecret:
+		result.Kind = kindSecret
+	case opAddWorkflow:
+		result.Kind = kindWorkflow
+	case opStoreConfig:
+		result.Kind = kindConfig
+		result.Name = op.Key
+	}
+	return result
+}
+
+// action returns the action reported when the operation succeeds
+func (op BatchOperation) action() string {
+	switch op.Op {
+	case opRemoveSecret:
+		return actionRemoved
+	case opStoreConfig:
+		return actionStored
+	}
+	return actionAdded
+}
+
+// Execute runs the operation
+func (op BatchOperation) Execute(ctx context.Context, ghm GHM, logger *logrus.Logger) error {
+	switch op.Op {
+	case opAddSecret:
+		value, err := op.valueSource().Read(nil)
+		if err != nil {
+			return err
+		}
+		return ghm.AddSecret(ctx, op.Repo, op.Name, value)
+
+	case opAddWorkflow:
+		content := op.Content
+		if op.File != "" {
+			data, err := ioutil.ReadFile(op.File)
+			if err != nil {
+				return err
+			}
+			content = string(data)
+		}
+		return ghm.AddWorkflow(ctx, op.Repo, op.Name, content)
+
+	case opRemoveSecret:
+		if err := ghm.RemoveSecret(ctx, op.Repo, op.Name); err != nil {
+			return err
+		}
+		if !op.Local {
+			return nil
+		}
+		return withStateStore(logger, func(store *StateStore) error {
+			return store.Update(func(tx *StateTx) error {
+				return tx.DeleteSecret(op.Name)
+			})
+		})
+
+	case opStoreConfig:
+		return ghm.StoreConfig(ctx, op.Key, op.Value)
+	}
+	return fmt.Errorf("unknown op '%s'", op.Op)
+}
+
+// ValidateBatch checks every line and reports each as it would run, or as failed
+func ValidateBatch(lines []BatchLine) (BatchResults, bool) {
+	results := make(BatchResults, 0, len(lines))
+	valid := true
+	for _, line := range lines {
+		result := line.Operation.result(line.Number, line.Operation.action())
+		result.DryRun = true
+		if err := line.Operation.Validate(); err != nil {
+			result.Action = actionFailed
+			result.Error = err.Error()
+			valid = false
+		}
+		results = append(results, result)
+	}
+	return results, valid
+}
+
+// RunBatch runs the lines after line start in order and stops at the first failure.
+// done is called after each successful line, so a later run can resume after it.
+func RunBatch(ctx context.Context, lines []BatchLine, start int, ghm GHM, done func(line int) error, logger *logrus.Logger) BatchResults {
+	results := make(BatchResults, 0, len(lines))
+	for _, line := range lines {
+		op := line.Operation
+		if line.Number <= start {
+			results = append(results, op.result(line.Number, actionSkipped))
+			continue
+		}
+
+		if err := op.Execute(ctx, ghm, logger); err != nil {
+			logger.Errorf("Line %d (%s) failed: %v", line.Number, op.Op, err)
+			result := op.result(line.Number, actionFailed)
+			result.Error = err.Error()
+			return append(results, result)
+		}
+		results = append(results, op.result(line.Number, op.action()))
+
+		if err := done(line.Number); err != nil {
+			logger.Warnf("Could not record batch progress: %v", err)
+		}
+	}
+	return results
+}
+
+// batchProgressPath returns where the progress of the batch file at path is recorded
+func batchProgressPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return dataPath(filepath.Join(batchProgressDir, hex.EncodeToString(sum[:8])+".json"))
+}
+
+// loadBatchProgress returns the last successful line of an earlier run of the same file
+// content, or 0 when there is none or the file has changed since
+func loadBatchProgress(path, hash string) (int, error) {
+	data, err := ioutil.ReadFile(batchProgressPath(path))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var progress batchProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return 0, err
+	}
+	if progress.File != path || progress.Hash != hash {
+		return 0, nil
+	}
+	return progress.Line, nil
+}
+
+// saveBatchProgress records the last successful line, replacing the progress file atomically
+func saveBatchProgress(path, hash string, line int) error {
+	progressPath := batchProgressPath(path)
+	if err := os.MkdirAll(filepath.Dir(progressPath), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(batchProgress{File: path, Hash: hash, Line: line, Updated: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := progressPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, progressPath)
+}
+
+// Initialize Batch Command Group
+func initBatchCmd(logger *logrus.Logger) *cobra.Command {
+	batchCmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run operations from a JSONL batch file",
+	}
+
+	batchCmd.AddCommand(initBatchRunCmd(logger))
+
+	return batchCmd
+}
+
+// Initialize Batch Run Command
+func initBatchRunCmd(logger *logrus.Logger) *cobra.Command {
+	var dryRun, restart bool
+
+	batchRunCmd := &cobra.Command{
+		Use:   "run FILE",
+		Short: "Run the operations of a JSONL batch file in order",
+		Long: `Run the operations of a JSONL batch file in order, one JSON object per line:
+
+  {"op": "add-secret", "repo": "owner/repo", "name": "API_KEY", "value_env": "API_KEY"}
+  {"op": "add-workflow", "repo": "owner/repo", "name": "ci.yml", "file": "workflows/ci.yml"}
+  {"op": "remove-secret", "repo": "owner/repo", "name": "OLD_KEY", "local": true}
+  {"op": "store-config", "key": "secret_name_prefix", "value": "PROD_"}
+
+Secret values come from value, value_file, value_env or value_cmd. store-config
+refuses keys that are not known settings unless "force" is true. Every line is
+validated before the first one runs. The run stops at the first failure; running
+the same file again resumes after the last successful line.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				logger.Errorf("Error reading batch file: %v", err)
+				return err
+			}
+			lines, err := ParseBatch(data)
+			if err != nil {
+				logger.Errorf("Error parsing batch file: %v", err)
+				return fmt.Errorf("parsing %s: %w", args[0], err)
+			}
+			if len(lines) == 0 {
+				logger.Info("No operations found.")
+				return nil
+			}
+			for i := range lines {
+				lines[i].Operation.resolvePaths(filepath.Dir(path))
+			}
+
+			// Validate every line before the first one runs
+			validation, valid := ValidateBatch(lines)
+			if dryRun || !valid {
+				if err := printResult(cmd, validation); err != nil {
+					return err
+				}
+				if !valid {
+					return fmt.Errorf("%d of %d lines are invalid", validation.failed(), len(lines))
+				}
+				logger.Info("Dry run: no changes were made.")
+				return nil
+			}
+
+			sum := sha256.Sum256(data)
+			hash := hex.EncodeToString(sum[:])
+			start := 0
+			if !restart {
+				if start, err = loadBatchProgress(path, hash); err != nil {
+					logger.Warnf("Could not read batch progress, starting from the first line: %v", err)
+					start = 0
+				}
+				if start > 0 {
+					logger.Infof("Resuming after line %d; use --restart to run every line again.", start)
+				}
+			}
+
+			var ghm GHM
+			needsToken := false
+			for _, line := range lines {
+				if line.Number > start && line.Operation.needsToken() {
+					needsToken = true
+				}
+			}
+			if needsToken {
+				if ghm, err = requireGHM(logger); err != nil {
+					return err
+				}
+			} else {
+				ghm = NewGHM(githubToken(logger), logger)
+			}
+
+			results := RunBatch(context.Background(), lines, start, ghm, func(line int) error {
+				return saveBatchProgress(path, hash, line)
+			}, logger)
+
+			if err := printResult(cmd, results); err != nil {
+				return err
+			}
+			if failed := results.failed(); failed > 0 {
+				last := results[len(results)-1]
+				return fmt.Errorf("line %d failed; run the file again to resume from it", last.Line)
+			}
+			if err := os.Remove(batchProgressPath(path)); err != nil && !os.IsNotExist(err) {
+				logger.Warnf("Could not remove batch progress: %v", err)
+			}
+			return nil
+		},
+	}
+
+	batchRunCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Validate every line without running anything")
+	batchRunCmd.Flags().BoolVar(&restart, "restart", false, "Run every line, ignoring the progress of an earlier run")
+
+	return batchRunCmd
+}
@@ -0,0 +1,122 @@
+// batch_test.go
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGHM records calls and fails the secrets and workflows named in fail. The lists of
+// repositories and remote items serve the bulk apply tests.
+type fakeGHM struct {
+	GHM
+	calls     []string
+	fail      map[string]bool
+	repos     []string            // repositories the token can access
+	secrets   map[string][]string // secret names by repository
+	workflows map[string]string   // workflow files by "owner/repo/name"
+}
+
+func (f *fakeGHM) AddSecret(ctx context.Context, repo, name, value string) error {
+	f.calls = append(f.calls, fmt.Sprintf("add-secret %s %s=%s", repo, name, value))
+	if f.fail[name] || f.fail[repo] {
+		return fmt.Errorf("forbidden")
+	}
+	return nil
+}
+
+func (f *fakeGHM) AddWorkflow(ctx context.Context, repo, name, content string) error {
+	f.calls = append(f.calls, fmt.Sprintf("add-workflow %s %s", repo, name))
+	if f.fail[name] {
+		return fmt.Errorf("forbidden")
+	}
+	return nil
+}
+
+func (f *fakeGHM) StoreConfig(ctx context.Context, key, value string) error {
+	f.calls = append(f.calls, fmt.Sprintf("store-config %s=%s", key, value))
+	return nil
+}
+
+const testBatch = `{"op": "add-secret", "repo": "owner/repo", "name": "API_KEY", "value": "one"}
+
+{"op": "add-workflow", "repo": "owner/repo", "name": "ci.yml", "content": "name: CI"}
+{"op": "add-secret", "repo": "owner/repo", "name": "DB_PASS", "value_env": "GHM_TEST_DB_PASS"}
+{"op": "store-config", "key": "secret_name_prefix", "value": "PROD_"}
+`
+
+// TestParseBatch tests line numbers and rejection of unknown fields
+func TestParseBatch(t *testing.T) {
+	lines, err := ParseBatch([]byte(testBatch))
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+	assert.Equal(t, 3, lines[1].Number)
+	assert.Equal(t, "GHM_TEST_DB_PASS", lines[2].Operation.ValueEnv)
+
+	_, err = ParseBatch([]byte(`{"op": "add-secret"}` + "\n" + `{"op": "add-secret", "vaule": "typo"}`))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+// TestValidateBatch tests that invalid lines are reported without running anything
+func TestValidateBatch(t *testing.T) {
+	lines, err := ParseBatch([]byte(`{"op": "add-secret", "repo": "owner/repo", "name": "api-key", "value": "x"}
+{"op": "add-secret", "repo": "owner/repo", "name": "API_KEY"}
+{"op": "add-workflow", "repo": "repo-without-owner", "name": "ci.yml", "content": "x"}
+{"op": "delete-everything"}
+{"op": "store-config", "key": "secret_name_prefix", "value": "PROD_"}
+{"op": "store-config", "key": "githb_token", "value": "v"}
+{"op": "store-config", "key": "k", "value": "v", "force": true}`))
+	require.NoError(t, err)
+
+	results, valid := ValidateBatch(lines)
+	assert.False(t, valid)
+	require.Len(t, results, 7)
+	assert.Contains(t, results[0].Error, "did you mean 'API_KEY'?")
+	assert.Contains(t, results[1].Error, "one of value, value_file, value_env or value_cmd is required")
+	assert.Contains(t, results[2].Error, "invalid repository format")
+	assert.Contains(t, results[3].Error, "unknown op")
+	assert.Equal(t, "stored", results[4].Action)
+	assert.True(t, results[4].DryRun)
+	assert.Contains(t, results[5].Error, "did you mean 'github_token'?")
+	assert.Equal(t, "stored", results[6].Action)
+}
+
+// TestRunBatch tests per-line results, stopping at the first failure and resuming
+func TestRunBatch(t *testing.T) {
+	t.Setenv("GHM_TEST_DB_PASS", "two")
+	lines, err := ParseBatch([]byte(testBatch))
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	var done []int
+	record := func(line int) error {
+		done = append(done, line)
+		return nil
+	}
+
+	ghm := &fakeGHM{fail: map[string]bool{"DB_PASS": true}}
+	results := RunBatch(context.Background(), lines, 0, ghm, record, logger)
+	require.Len(t, results, 3)
+	assert.Equal(t, "added", results[0].Action)
+	assert.Equal(t, "failed", results[2].Action)
+	assert.Equal(t, "forbidden", results[2].Error)
+	assert.Equal(t, []int{1, 3}, done)
+
+	// Resume after the last successful line
+	ghm = &fakeGHM{}
+	results = RunBatch(context.Background(), lines, 3, ghm, record, logger)
+	require.Len(t, results, 4)
+	assert.Equal(t, "skipped", results[0].Action)
+	assert.Equal(t, "skipped", results[1].Action)
+	assert.Equal(t, []string{"add-secret owner/repo DB_PASS=two", "store-config secret_name_prefix=PROD_"}, ghm.calls)
+	assert.Equal(t, []int{1, 3, 4, 5}, done)
+}
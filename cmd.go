@@ -3,7 +3,7 @@ package main
 
 import (
 	"context"
-	"encoding/json" 
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,7 +12,6 @@ import (
 	"github.com/manifoldco/promptui"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"golang.org/x/term"
 )
 
@@ -21,65 +20,135 @@ func initRootCmd(logger *logrus.Logger) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use: "ghm",
 		Short: HeaderColor.Sprintf("GitHub Management CLI"), // Correct way to apply color formatting
-    PersistentPreRun: func(cmd *cobra.Command, args []string) {
-        // Ensure GitHub token is available
-        token := viper.GetString("github_token")
-        if token == "" {
-            // Prompt user for the token with a colored prompt
-            PromptColor.Print("Enter your GitHub token: ")
-            byteToken, err := term.ReadPassword(int(os.Stdin.Fd()))
-            fmt.Println() // Move to the next line after input
-            if err != nil {
-                ErrorColor.Printf("Error reading token: %v\n", err)
-                logger.Fatalf("Error reading token: %v", err)
-            }
-            token = strings.TrimSpace(string(byteToken))
-            viper.Set("github_token", token)
-    
-            // Attempt to write the config
-            if err := viper.WriteConfigAs("config.yaml"); err != nil { // Explicitly specify the file type
-                if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-                    WarningColor.Println("Config file doesn't exist; creating one.")
-                    viper.SetConfigName("config")
-                    viper.SetConfigType("yaml") // Specify the type here
-                    viper.AddConfigPath(".")    // Specify the path
-    
-                    if err := viper.WriteConfig(); err != nil {
-                        ErrorColor.Printf("Error creating config file: %v\n", err)
-                        logger.Fatalf("Error creating config file: %v", err)
-                    }
-                } else {
-                    ErrorColor.Printf("Error writing config: %v\n", err)
-                    logger.Fatalf("Error writing config: %v", err)
-                }
-            }
-    
-            SuccessColor.Println("GitHub token saved successfully.")
-            logger.Info("GitHub token saved successfully.")
-        } else {
-            SuccessColor.Println("GitHub token loaded from config.")
-            logger.Info("GitHub token loaded from config.")
-        }
-    },
+		// Configuration and the GitHub token are loaded only for commands that run,
+		// so --help and completion work without either
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Shell completion requests must not prompt or print anything
+			if isCompletionRequest(cmd) {
+				return
+			}
+			applyGlobalFlags(cmd, logger)
+		},
+		// Errors are reported on their own so they stay readable in CI logs
+		SilenceUsage: true,
 	}
 
-	// Add subcommands with logger
-	rootCmd.AddCommand(initAddSecretCmd(logger))
-	rootCmd.AddCommand(initAddWorkflowCmd(logger))
-	rootCmd.AddCommand(initStoreConfigCmd(logger))
-	rootCmd.AddCommand(initAddSavedSecretCmd(logger))
-	rootCmd.AddCommand(initAddSavedWorkflowCmd(logger))
-	rootCmd.AddCommand(initListReposCmd(logger))
+	rootCmd.PersistentFlags().StringVar(&stateDirOverride, "state-dir", "", "Directory for config and state files (overrides the XDG directories)")
+	rootCmd.PersistentFlags().StringVar(&contextOverride, "context", "", "Named configuration context to use (defaults to current_context)")
+	rootCmd.RegisterFlagCompletionFunc("context", completeContexts)
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format: table, json, yaml or jsonpath=<template>")
+	rootCmd.RegisterFlagCompletionFunc("output", completeOutputFormats)
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt; fail when an input is missing (default when stdin is not a terminal)")
+
+	// Generate completions from the command tree instead of cobra's default command
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(initCompletionCmd(logger))
+
+	// Add command groups with logger
+	rootCmd.AddCommand(initSecretCmd(logger))
+	rootCmd.AddCommand(initWorkflowCmd(logger))
+	rootCmd.AddCommand(initConfigCmd(logger))
+	rootCmd.AddCommand(initRepoCmd(logger))
+	rootCmd.AddCommand(initBatchCmd(logger))
+	rootCmd.AddCommand(initAuthCmd(logger))
+	rootCmd.AddCommand(initStateCmd(logger))
+	rootCmd.AddCommand(initHistoryCmd(logger))
+	rootCmd.AddCommand(initTUICmd(logger))
+
+	// Keep the old flat command names working for existing scripts
+	rootCmd.AddCommand(deprecatedAlias(initAddSecretCmd(logger), "add-secret", "ghm secret add"))
+	rootCmd.AddCommand(deprecatedAlias(initAddWorkflowCmd(logger), "add-workflow", "ghm workflow add"))
+	rootCmd.AddCommand(deprecatedAlias(initStoreConfigCmd(logger), "store-config", "ghm config store"))
+	rootCmd.AddCommand(deprecatedAlias(initAddSavedSecretCmd(logger), "add-saved-secrets", "ghm secret apply"))
+	rootCmd.AddCommand(deprecatedAlias(initAddSavedWorkflowCmd(logger), "add-saved-workflows", "ghm workflow apply"))
+	rootCmd.AddCommand(deprecatedAlias(initListReposCmd(logger), "list-repos", "ghm repo list"))
+	rootCmd.AddCommand(deprecatedAlias(initMigrateStateCmd(logger), "migrate-state", "ghm state migrate"))
 
 	return rootCmd
 }
 
+// requireGHM creates a GHM client, prompting for a GitHub token when none is configured
+func requireGHM(logger *logrus.Logger) (GHM, error) {
+	token, err := GetGitHubToken(logger)
+	if err != nil {
+		return nil, err
+	}
+	return NewGHM(token, logger), nil
+}
+
+// deprecatedAlias renames a command to its old flat name, hides it from help and prints a deprecation notice
+func deprecatedAlias(cmd *cobra.Command, oldName, replacement string) *cobra.Command {
+	cmd.Use = oldName
+	cmd.Hidden = true
+	cmd.Deprecated = fmt.Sprintf("use '%s' instead", replacement)
+	return cmd
+}
+
+// Initialize Secret Command Group
+func initSecretCmd(logger *logrus.Logger) *cobra.Command {
+	secretCmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage GitHub Actions secrets",
+	}
+
+	secretCmd.AddCommand(initAddSecretCmd(logger))
+	secretCmd.AddCommand(initImportSecretCmd(logger))
+	secretCmd.AddCommand(initRemoveSecretCmd(logger))
+	secretCmd.AddCommand(initListSecretsCmd(logger))
+	secretCmd.AddCommand(initAddSavedSecretCmd(logger))
+
+	return secretCmd
+}
+
+// Initialize Workflow Command Group
+func initWorkflowCmd(logger *logrus.Logger) *cobra.Command {
+	workflowCmd := &cobra.Command{
+		Use:   "workflow",
+		Short: "Manage GitHub Actions workflows",
+	}
+
+	workflowCmd.AddCommand(initAddWorkflowCmd(logger))
+	workflowCmd.AddCommand(initListWorkflowsCmd(logger))
+	workflowCmd.AddCommand(initAddSavedWorkflowCmd(logger))
+
+	return workflowCmd
+}
+
+// Initialize Config Command Group
+func initConfigCmd(logger *logrus.Logger) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage ghm configuration",
+	}
+
+	configCmd.AddCommand(initStoreConfigCmd(logger))
+	configCmd.AddCommand(initGetConfigCmd(logger))
+	configCmd.AddCommand(initListConfigCmd(logger))
+	configCmd.AddCommand(initUnsetConfigCmd(logger))
+
+	return configCmd
+}
+
+// Initialize Repo Command Group
+func initRepoCmd(logger *logrus.Logger) *cobra.Command {
+	repoCmd := &cobra.Command{
+		Use:   "repo",
+		Short: "Inspect tracked repositories",
+	}
+
+	repoCmd.AddCommand(initListReposCmd(logger))
+
+	return repoCmd
+}
+
 // Initialize Add Secret Command
 func initAddSecretCmd(logger *logrus.Logger) *cobra.Command {
 	var repo, secretName, secretValue string
+	var source SecretValueSource
+	var fixName bool
 
 	addSecretCmd := &cobra.Command{
-		Use:   "add-secret",
+		Use:   "add",
 		Short: "Add a secret to a GitHub repository",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if repo == "" {
@@ -94,35 +163,216 @@ func initAddSecretCmd(logger *logrus.Logger) *cobra.Command {
 				logger.Error("Secret name must be provided.")
 				return fmt.Errorf("secret name not provided")
 			}
-			if secretValue == "" {
-				fmt.Print("Enter the secret value: ")
+			name, err := checkSecretName(secretName, fixName, logger)
+			if err != nil {
+				return err
+			}
+			secretName = name
+			source.Value = secretValue
+			source.HasValue = cmd.Flags().Changed("value")
+			if source.IsSet() {
+				value, err := source.Read(os.Stdin)
+				if err != nil {
+					logger.Errorf("Error reading secret value: %v", err)
+					return err
+				}
+				secretValue = value
+			} else {
+				if !isInteractive() {
+					logger.Error("Secret value must be provided with --value-file, --value-stdin, --value-env, --value-cmd or --value in non-interactive mode.")
+					return fmt.Errorf("secret value not provided")
+				}
+				fmt.Fprint(os.Stderr, "Enter the secret value: ")
 				byteSecret, err := term.ReadPassword(int(os.Stdin.Fd()))
-				fmt.Println() // Move to the next line after input
+				fmt.Fprintln(os.Stderr) // Move to the next line after input
 				if err != nil {
 					logger.Errorf("Error reading secret value: %v", err)
 					return err
 				}
-				secretValue = strings.TrimSpace(string(byteSecret))
+				// The value is used exactly as typed
+				secretValue = string(byteSecret)
+				if err := checkSecretSize(secretValue); err != nil {
+					return err
+				}
 			}
 
-			ghm := NewGHM(viper.GetString("github_token"), logger) // Pass both arguments
-			return ghm.AddSecret(context.Background(), repo, secretName, secretValue)
+			ghm, err := requireGHM(logger)
+			if err != nil {
+				return err
+			}
+			if err := ghm.AddSecret(context.Background(), repo, secretName, secretValue); err != nil {
+				return err
+			}
+			return printResult(cmd, OperationResult{Kind: kindSecret, Name: secretName, Repo: repo, Action: actionAdded})
 		},
 	}
 
 	addSecretCmd.Flags().StringVarP(&repo, "repo", "r", "", "Repository name in 'owner/repo' format")
 	addSecretCmd.Flags().StringVarP(&secretName, "name", "n", "", "Name of the secret")
-	addSecretCmd.Flags().StringVarP(&secretValue, "value", "v", "", "Value of the secret")
+	addSecretCmd.Flags().BoolVar(&fixName, "fix-name", false, "Use the suggested fix when the secret name is invalid")
+	addSecretCmd.Flags().StringVarP(&secretValue, "value", "v", "", "Value of the secret (visible in shell history and ps; prefer the other sources)")
+	addSecretCmd.Flags().StringVar(&source.File, "value-file", "", "Read the value from a file, byte for byte (e.g. a PEM key)")
+	addSecretCmd.Flags().BoolVar(&source.Stdin, "value-stdin", false, "Read the value from stdin, byte for byte")
+	addSecretCmd.Flags().StringVar(&source.Env, "value-env", "", "Read the value from an environment variable")
+	addSecretCmd.Flags().StringVar(&source.Command, "value-cmd", "", "Use the output of a shell command, such as a password manager CLI")
+	addSecretCmd.MarkFlagsMutuallyExclusive("value", "value-file", "value-stdin", "value-env", "value-cmd")
+
+	addSecretCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	addSecretCmd.RegisterFlagCompletionFunc("name", completeSecretNames)
 
 	return addSecretCmd
 }
 
+// Initialize Import Secret Command
+func initImportSecretCmd(logger *logrus.Logger) *cobra.Command {
+	var format string
+	var repos []string
+	var mapping SecretNameMapping
+	var dryRun bool
+
+	importSecretCmd := &cobra.Command{
+		Use:   "import FILE...",
+		Short: "Import secrets from dotenv, JSON or YAML files",
+		Long: `Import secrets from dotenv, JSON or YAML files into the local store, and optionally
+add them to repositories. Later files override earlier ones; '-' reads from stdin.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, repo := range repos {
+				if !strings.Contains(repo, "/") {
+					logger.Errorf("Invalid repository format '%s'. Use 'owner/repo'.", repo)
+					return fmt.Errorf("invalid repository format")
+				}
+			}
+
+			// Parse all files before touching the store
+			values := make(map[string]string)
+			for _, path := range args {
+				fileFormat, err := secretsFileFormat(path, format)
+				if err != nil {
+					return err
+				}
+				var data []byte
+				if path == "-" {
+					data, err = ioutil.ReadAll(os.Stdin)
+				} else {
+					data, err = ioutil.ReadFile(path)
+				}
+				if err != nil {
+					logger.Errorf("Error reading '%s': %v", path, err)
+					return err
+				}
+				parsed, err := ParseSecretsFile(data, fileFormat)
+				if err != nil {
+					logger.Errorf("Error parsing '%s': %v", path, err)
+					return fmt.Errorf("parsing %s: %w", path, err)
+				}
+				for key, value := range parsed {
+					values[key] = value
+				}
+			}
+			if len(values) == 0 {
+				logger.Info("No secrets found.")
+				return nil
+			}
+
+			existing, err := loadSecretsConfig(logger)
+			if err != nil {
+				return err
+			}
+			entries, err := PlanSecretImport(values, mapping, secretNameRules(), existing)
+			if err != nil {
+				logger.Errorf("Error planning import: %v", err)
+				return err
+			}
+
+			names := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				if entry.Source != entry.Name {
+					logger.Infof("Importing '%s' as '%s'.", entry.Source, entry.Name)
+				}
+				names = append(names, entry.Name)
+			}
+			results := SecretImportResults(entries, repos, dryRun)
+			changed, unchanged := CountSecretImport(entries)
+
+			if dryRun {
+				logger.Infof("Dry run: %d secrets would be imported, %d unchanged; no changes were made.", changed, unchanged)
+				return printResult(cmd, results)
+			}
+
+			// Save all secrets in one transaction so a failure leaves the store unchanged
+			err = withStateStore(logger, func(store *StateStore) error {
+				warnIfInGitWorktree(store.Path, logger)
+				return store.Update(func(tx *StateTx) error {
+					for _, entry := range entries {
+						if entry.Action == actionUnchanged {
+							continue
+						}
+						if err := tx.PutSecret(entry.Name, entry.Value); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			})
+			if err != nil {
+				logger.Errorf("Error saving imported secrets: %v", err)
+				return err
+			}
+			logger.Infof("Imported %d secrets, %d unchanged.", changed, unchanged)
+
+			if len(repos) > 0 {
+				reposConfig, err := LoadReposConfig(logger)
+				if err != nil {
+					logger.Errorf("Error loading repos config: %v", err)
+					return err
+				}
+				ghm, err := requireGHM(logger)
+				if err != nil {
+					return err
+				}
+				for _, repo := range repos {
+					repoResults, err := ghm.AddSecretsToRepo(context.Background(), repo, names, reposConfig)
+					if err != nil {
+						logger.Errorf("Error adding secrets to repository: %v", err)
+						return err
+					}
+					results = append(results, repoResults...)
+				}
+			}
+
+			if err := printResult(cmd, results); err != nil {
+				return err
+			}
+			if failed := results.failed(); failed > 0 {
+				return fmt.Errorf("%d of %d secrets could not be added", failed, len(names)*len(repos))
+			}
+			return nil
+		},
+	}
+
+	importSecretCmd.Flags().StringVar(&format, "format", "", "File format: dotenv, json or yaml (default: guessed from the extension, else dotenv)")
+	importSecretCmd.Flags().StringSliceVarP(&repos, "repo", "r", nil, "Also add the imported secrets to these repositories in 'owner/repo' format (repeatable)")
+	importSecretCmd.Flags().StringVar(&mapping.Prefix, "prefix", "", "Prefix added to every secret name")
+	importSecretCmd.Flags().BoolVar(&mapping.Uppercase, "uppercase", false, "Convert secret names to upper case")
+	importSecretCmd.Flags().StringToStringVar(&mapping.Rename, "rename", nil, "Rename keys, e.g. --rename db_url=DATABASE_URL (applied before --prefix and --uppercase)")
+	importSecretCmd.Flags().BoolVar(&mapping.FixNames, "fix-names", false, "Replace invalid secret names with their suggested fix")
+	importSecretCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show which secrets would be created or overwritten without changing anything")
+
+	importSecretCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	importSecretCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return filterPrefix([]string{formatDotenv, formatJSON, formatYAML}, toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return importSecretCmd
+}
+
 // Initialize Add Workflow Command
 func initAddWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 	var repo, workflowName, workflowContent, workflowFile string
 
 	addWorkflowCmd := &cobra.Command{
-		Use:   "add-workflow",
+		Use:   "add",
 		Short: "Add a GitHub Actions workflow to a repository",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if repo == "" {
@@ -150,8 +400,14 @@ func initAddWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 				workflowContent = string(contentBytes)
 			}
 
-			ghm := NewGHM(viper.GetString("github_token"), logger) // Pass both arguments
-			return ghm.AddWorkflow(context.Background(), repo, workflowName, workflowContent)
+			ghm, err := requireGHM(logger)
+			if err != nil {
+				return err
+			}
+			if err := ghm.AddWorkflow(context.Background(), repo, workflowName, workflowContent); err != nil {
+				return err
+			}
+			return printResult(cmd, OperationResult{Kind: kindWorkflow, Name: workflowName, Repo: repo, Action: actionAdded})
 		},
 	}
 
@@ -160,15 +416,19 @@ func initAddWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 	addWorkflowCmd.Flags().StringVarP(&workflowContent, "content", "c", "", "Content of the workflow file")
 	addWorkflowCmd.Flags().StringVarP(&workflowFile, "file", "f", "", "Path to the workflow file to read content from")
 
+	addWorkflowCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	addWorkflowCmd.RegisterFlagCompletionFunc("name", completeWorkflowNames)
+
 	return addWorkflowCmd
 }
 
 // Initialize Store Config Command
 func initStoreConfigCmd(logger *logrus.Logger) *cobra.Command {
 	var configKey, configValue string
+	var force bool
 
 	storeConfigCmd := &cobra.Command{
-		Use:   "store-config",
+		Use:   "store",
 		Short: "Store a configuration key-value pair",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if configKey == "" {
@@ -179,13 +439,25 @@ func initStoreConfigCmd(logger *logrus.Logger) *cobra.Command {
 				logger.Error("Configuration value must be provided.")
 				return fmt.Errorf("configuration value not provided")
 			}
-			ghm := NewGHM(viper.GetString("github_token"), logger) // Pass both arguments
-			return ghm.StoreConfig(context.Background(), configKey, configValue)
+			if err := ValidateSetting(configKey, configValue, force); err != nil {
+				logger.Errorf("%v", err)
+				var unknown *UnknownSettingError
+				if errors.As(err, &unknown) {
+					return fmt.Errorf("%w; use --force to store it anyway", err)
+				}
+				return err
+			}
+			ghm := NewGHM(githubToken(logger), logger)
+			if err := ghm.StoreConfig(context.Background(), configKey, configValue); err != nil {
+				return err
+			}
+			return printResult(cmd, OperationResult{Kind: kindConfig, Name: configKey, Action: actionStored})
 		},
 	}
 
 	storeConfigCmd.Flags().StringVarP(&configKey, "key", "k", "", "Configuration key")
 	storeConfigCmd.Flags().StringVarP(&configValue, "value", "v", "", "Configuration value")
+	storeConfigCmd.Flags().BoolVar(&force, "force", false, "Store a key that is not a known setting")
 
 	return storeConfigCmd
 }
@@ -193,10 +465,12 @@ func initStoreConfigCmd(logger *logrus.Logger) *cobra.Command {
 // Initialize Add Saved Secret Command
 func initAddSavedSecretCmd(logger *logrus.Logger) *cobra.Command {
 	var targetRepo string
+	var names, patterns []string
+	var all bool
 
 	addSavedSecretCmd := &cobra.Command{
-		Use:   "add-saved-secrets",
-		Short: "Interactively add saved secrets to a target GitHub repository",
+		Use:   "apply",
+		Short: "Add saved secrets to a target GitHub repository",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if targetRepo == "" {
 				logger.Error("Target repository must be specified.")
@@ -213,15 +487,22 @@ func initAddSavedSecretCmd(logger *logrus.Logger) *cobra.Command {
 				logger.Errorf("Error loading saved secrets: %v", err)
 				return err
 			}
-			if len(secrets) == 0 {
+			// Named selections must fail below rather than succeed with nothing to do
+			if len(secrets) == 0 && len(names) == 0 && len(patterns) == 0 {
 				logger.Info("No saved secrets found.")
 				return nil
 			}
 
-			// Interactive selection
-			selectedSecrets, err := promptSelectItems("Select Secrets to Add", secrets)
+			// Load tracked repositories
+			reposConfig, err := LoadReposConfig(logger)
+			if err != nil {
+				logger.Errorf("Error loading repos config: %v", err)
+				return err
+			}
+
+			// Select from the flags, or with the picker when none were given
+			selectedSecrets, err := selectSavedItems(kindSecret, "secrets", secrets, names, all, patterns, reposConfig, logger)
 			if err != nil {
-				logger.Errorf("Error selecting secrets: %v", err)
 				return err
 			}
 			if len(selectedSecrets) == 0 {
@@ -229,33 +510,35 @@ func initAddSavedSecretCmd(logger *logrus.Logger) *cobra.Command {
 				return nil
 			}
 
-			// Load repos.json
-			reposConfig, err := LoadReposConfig(logger)
+			// Add selected secrets to the target repository
+			ghm, err := requireGHM(logger)
 			if err != nil {
-				logger.Errorf("Error loading repos config: %v", err)
 				return err
 			}
-
-			// Add selected secrets to the target repository
-			ghm := NewGHM(viper.GetString("github_token"), logger) // Pass both arguments
-			err = ghm.AddSecretsToRepo(context.Background(), targetRepo, selectedSecrets, reposConfig)
+			results, err := ghm.AddSecretsToRepo(context.Background(), targetRepo, selectedSecrets, reposConfig)
 			if err != nil {
 				logger.Errorf("Error adding secrets to repository: %v", err)
 				return err
 			}
 
-			// Save repos.json
-			err = SaveReposConfig(reposConfig, logger)
-			if err != nil {
-				logger.Errorf("Error saving repos config: %v", err)
+			// Each added item is recorded in the state store as it succeeds
+			if err := printResult(cmd, results); err != nil {
 				return err
 			}
-
+			if failed := results.failed(); failed > 0 {
+				return fmt.Errorf("%d of %d secrets could not be added", failed, len(results))
+			}
 			return nil
 		},
 	}
 
 	addSavedSecretCmd.Flags().StringVarP(&targetRepo, "repo", "r", "", "Target repository in 'owner/repo' format")
+	addSavedSecretCmd.Flags().StringSliceVar(&names, "secrets", nil, "Comma-separated names of the saved secrets to add")
+	addSavedSecretCmd.Flags().BoolVar(&all, "all", false, "Add all saved secrets")
+	addSavedSecretCmd.Flags().StringArrayVar(&patterns, "match", nil, "Add the saved secrets matching a glob pattern (repeatable)")
+
+	addSavedSecretCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	addSavedSecretCmd.RegisterFlagCompletionFunc("secrets", completeSecretNames)
 
 	return addSavedSecretCmd
 }
@@ -263,10 +546,12 @@ func initAddSavedSecretCmd(logger *logrus.Logger) *cobra.Command {
 // Initialize Add Saved Workflow Command
 func initAddSavedWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 	var targetRepo string
+	var names, patterns []string
+	var all bool
 
 	addSavedWorkflowCmd := &cobra.Command{
-		Use:   "add-saved-workflows",
-		Short: "Interactively add saved workflows to a target GitHub repository",
+		Use:   "apply",
+		Short: "Add saved workflows to a target GitHub repository",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if targetRepo == "" {
 				logger.Error("Target repository must be specified.")
@@ -283,15 +568,22 @@ func initAddSavedWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 				logger.Errorf("Error loading saved workflows: %v", err)
 				return err
 			}
-			if len(workflows) == 0 {
+			// Named selections must fail below rather than succeed with nothing to do
+			if len(workflows) == 0 && len(names) == 0 && len(patterns) == 0 {
 				logger.Info("No saved workflows found.")
 				return nil
 			}
 
-			// Interactive selection
-			selectedWorkflows, err := promptSelectItems("Select Workflows to Add", workflows)
+			// Load tracked repositories
+			reposConfig, err := LoadReposConfig(logger)
+			if err != nil {
+				logger.Errorf("Error loading repos config: %v", err)
+				return err
+			}
+
+			// Select from the flags, or with the picker when none were given
+			selectedWorkflows, err := selectSavedItems(kindWorkflow, "workflows", workflows, names, all, patterns, reposConfig, logger)
 			if err != nil {
-				logger.Errorf("Error selecting workflows: %v", err)
 				return err
 			}
 			if len(selectedWorkflows) == 0 {
@@ -299,33 +591,35 @@ func initAddSavedWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 				return nil
 			}
 
-			// Load repos.json
-			reposConfig, err := LoadReposConfig(logger)
+			// Add selected workflows to the target repository
+			ghm, err := requireGHM(logger)
 			if err != nil {
-				logger.Errorf("Error loading repos config: %v", err)
 				return err
 			}
-
-			// Add selected workflows to the target repository
-			ghm := NewGHM(viper.GetString("github_token"), logger) // Pass both arguments
-			err = ghm.AddWorkflowsToRepo(context.Background(), targetRepo, selectedWorkflows, reposConfig)
+			results, err := ghm.AddWorkflowsToRepo(context.Background(), targetRepo, selectedWorkflows, reposConfig)
 			if err != nil {
 				logger.Errorf("Error adding workflows to repository: %v", err)
 				return err
 			}
 
-			// Save repos.json
-			err = SaveReposConfig(reposConfig, logger)
-			if err != nil {
-				logger.Errorf("Error saving repos config: %v", err)
+			// Each added item is recorded in the state store as it succeeds
+			if err := printResult(cmd, results); err != nil {
 				return err
 			}
-
+			if failed := results.failed(); failed > 0 {
+				return fmt.Errorf("%d of %d workflows could not be added", failed, len(results))
+			}
 			return nil
 		},
 	}
 
 	addSavedWorkflowCmd.Flags().StringVarP(&targetRepo, "repo", "r", "", "Target repository in 'owner/repo' format")
+	addSavedWorkflowCmd.Flags().StringSliceVar(&names, "workflows", nil, "Comma-separated names of the saved workflows to add")
+	addSavedWorkflowCmd.Flags().BoolVar(&all, "all", false, "Add all saved workflows")
+	addSavedWorkflowCmd.Flags().StringArrayVar(&patterns, "match", nil, "Add the saved workflows matching a glob pattern (repeatable)")
+
+	addSavedWorkflowCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	addSavedWorkflowCmd.RegisterFlagCompletionFunc("workflows", completeWorkflowNames)
 
 	return addSavedWorkflowCmd
 }
@@ -333,7 +627,7 @@ func initAddSavedWorkflowCmd(logger *logrus.Logger) *cobra.Command {
 // Initialize List Repositories Command
 func initListReposCmd(logger *logrus.Logger) *cobra.Command {
 	listReposCmd := &cobra.Command{
-		Use:   "list-repos",
+		Use:   "list",
 		Short: "List all repositories and their added secrets/workflows",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			reposConfig, err := LoadReposConfig(logger)
@@ -344,139 +638,277 @@ func initListReposCmd(logger *logrus.Logger) *cobra.Command {
 
 			if len(reposConfig.Repositories) == 0 {
 				logger.Info("No repositories configured.")
-				return nil
 			}
 
-			for repo, config := range reposConfig.Repositories {
-				fmt.Printf("Repository: %s\n", repo)
-				fmt.Printf("  Last Update: %s\n", config.LastUpdate)
-				fmt.Printf("  Secrets:\n")
-				for _, secret := range config.Secrets {
-					fmt.Printf("    - %s\n", secret)
+			return printResult(cmd, repoResults(reposConfig))
+		},
+	}
+
+	return listReposCmd
+}
+
+// Initialize Remove Secret Command
+func initRemoveSecretCmd(logger *logrus.Logger) *cobra.Command {
+	var repo, secretName string
+	var local bool
+
+	removeSecretCmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a secret from a GitHub repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repo == "" {
+				logger.Error("Repository must be specified.")
+				return fmt.Errorf("repository not specified")
+			}
+			if !strings.Contains(repo, "/") {
+				logger.Error("Invalid repository format. Use 'owner/repo'.")
+				return fmt.Errorf("invalid repository format")
+			}
+			if secretName == "" {
+				logger.Error("Secret name must be provided.")
+				return fmt.Errorf("secret name not provided")
+			}
+
+			ghm, err := requireGHM(logger)
+			if err != nil {
+				return err
+			}
+			if err := ghm.RemoveSecret(context.Background(), repo, secretName); err != nil {
+				return err
+			}
+			results := OperationResults{{Kind: kindSecret, Name: secretName, Repo: repo, Action: actionRemoved}}
+
+			if local {
+				err := withStateStore(logger, func(store *StateStore) error {
+					return store.Update(func(tx *StateTx) error {
+						return tx.DeleteSecret(secretName)
+					})
+				})
+				if err != nil {
+					logger.Errorf("Error removing saved secret: %v", err)
+					return err
 				}
-				fmt.Printf("  Workflows:\n")
-				for _, workflow := range config.Workflows {
-					fmt.Printf("    - %s\n", workflow)
+				logger.Infof("Secret '%s' removed from the local store.", secretName)
+				results = append(results, OperationResult{Kind: kindSecret, Name: secretName, Action: actionDeleted})
+			}
+
+			return printResult(cmd, results)
+		},
+	}
+
+	removeSecretCmd.Flags().StringVarP(&repo, "repo", "r", "", "Repository name in 'owner/repo' format")
+	removeSecretCmd.Flags().StringVarP(&secretName, "name", "n", "", "Name of the secret")
+	removeSecretCmd.Flags().BoolVar(&local, "local", false, "Also delete the saved value from the local store")
+
+	removeSecretCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+	removeSecretCmd.RegisterFlagCompletionFunc("name", completeSecretNames)
+
+	return removeSecretCmd
+}
+
+// Initialize List Secrets Command
+func initListSecretsCmd(logger *logrus.Logger) *cobra.Command {
+	var repo string
+
+	listSecretsCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved secrets, or the secrets set on a repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var names []string
+			var err error
+			if repo != "" {
+				var ghm GHM
+				if ghm, err = requireGHM(logger); err != nil {
+					return err
 				}
-				fmt.Println()
+				names, err = ghm.ListRepoSecrets(context.Background(), repo)
+			} else {
+				names, err = loadSavedSecrets(logger)
+			}
+			if err != nil {
+				return err
 			}
 
-			return nil
+			if len(names) == 0 {
+				logger.Info("No secrets found.")
+			}
+			return printResult(cmd, itemResults(kindSecret, repo, names))
 		},
 	}
 
-	return listReposCmd
+	listSecretsCmd.Flags().StringVarP(&repo, "repo", "r", "", "List the secrets of this repository instead of the saved ones")
+
+	listSecretsCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+
+	return listSecretsCmd
 }
 
-// loadSavedSecrets loads secrets from secrets.json
-func loadSecretsConfig(logger *logrus.Logger) (map[string]string, error) {
-    secretsFile := "secrets.json"
-    secrets := make(map[string]string)
-
-    if _, err := os.Stat(secretsFile); os.IsNotExist(err) {
-        // Create an empty secrets.json file
-        file, err := os.Create(secretsFile)
-        if err != nil {
-            logger.Errorf("Error creating secrets.json: %v", err)
-            return nil, err
-        }
-        defer file.Close()
-
-        // Initialize with an empty map
-        encoder := json.NewEncoder(file)
-        encoder.SetIndent("", "  ")
-        err = encoder.Encode(secrets)
-        if err != nil {
-            logger.Errorf("Error encoding secrets.json: %v", err)
-            return nil, err
-        }
-        return secrets, nil
-    }
-
-    // Continue with reading the existing file...
+// Initialize List Workflows Command
+func initListWorkflowsCmd(logger *logrus.Logger) *cobra.Command {
+	var repo string
+
+	listWorkflowsCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved workflows, or the workflows in a repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var names []string
+			var err error
+			if repo != "" {
+				var ghm GHM
+				if ghm, err = requireGHM(logger); err != nil {
+					return err
+				}
+				names, err = ghm.ListRepoWorkflows(context.Background(), repo)
+			} else {
+				names, err = loadSavedWorkflows(logger)
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(names) == 0 {
+				logger.Info("No workflows found.")
+			}
+			return printResult(cmd, itemResults(kindWorkflow, repo, names))
+		},
+	}
+
+	listWorkflowsCmd.Flags().StringVarP(&repo, "repo", "r", "", "List the workflows of this repository instead of the saved ones")
+
+	listWorkflowsCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+
+	return listWorkflowsCmd
 }
 
-// loadSavedWorkflows loads workflows from workflows.json
-func loadSavedWorkflows(logger *logrus.Logger) ([]string, error) {
-	workflowsFile := "workflows.json"
-	workflows := make(map[string]string)
+// Initialize List Config Command
+func initListConfigCmd(logger *logrus.Logger) *cobra.Command {
+	listConfigCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List known settings and other configured keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printResult(cmd, configEntries(logger))
+		},
+	}
+
+	return listConfigCmd
+}
 
-	if _, err := os.Stat(workflowsFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("workflows.json does not exist")
+// Initialize Get Config Command
+func initGetConfigCmd(logger *logrus.Logger) *cobra.Command {
+	getConfigCmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Show the value of a configuration key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry := configEntry(args[0], logger)
+			if _, ok := LookupSetting(entry.Key); !ok && entry.Source == "" {
+				err := &UnknownSettingError{Key: entry.Key, Suggestion: closestSetting(entry.Key)}
+				logger.Errorf("%v", err)
+				return err
+			}
+			return printResult(cmd, ConfigEntries{entry})
+		},
 	}
 
-	file, err := os.Open(workflowsFile)
-	if err != nil {
-		logger.Errorf("Error opening workflows.json: %v", err)
-		return nil, err
+	return getConfigCmd
+}
+
+// Initialize Unset Config Command
+func initUnsetConfigCmd(logger *logrus.Logger) *cobra.Command {
+	unsetConfigCmd := &cobra.Command{
+		Use:   "unset KEY",
+		Short: "Remove a configuration key so its default applies",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := strings.ToLower(args[0])
+			ghm := NewGHM(githubToken(logger), logger)
+			if err := ghm.UnsetConfig(context.Background(), key); err != nil {
+				return err
+			}
+			return printResult(cmd, OperationResult{Kind: kindConfig, Name: key, Action: actionRemoved})
+		},
+	}
+
+	return unsetConfigCmd
+}
+
+// checkSecretName validates a secret name before anything is written. An invalid name is
+// replaced by its suggested fix with --fix-name, or after confirmation on a terminal.
+func checkSecretName(name string, fix bool, logger *logrus.Logger) (string, error) {
+	err := ValidateSecretName(name, secretNameRules())
+	if err == nil {
+		return name, nil
+	}
+
+	var nameErr *SecretNameError
+	if !errors.As(err, &nameErr) || nameErr.Suggestion == "" {
+		logger.Errorf("%v", err)
+		return "", err
+	}
+	if fix {
+		logger.Infof("Using secret name '%s' instead of '%s'.", nameErr.Suggestion, name)
+		return nameErr.Suggestion, nil
+	}
+	if !isInteractive() {
+		logger.Errorf("%v", err)
+		return "", fmt.Errorf("%w; use --fix-name to accept the suggestion", err)
+	}
+
+	WarningColor.Fprintf(os.Stderr, "%v\n", err)
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Use '%s' instead", nameErr.Suggestion),
+		IsConfirm: true,
+		Stdout:    os.Stderr,
 	}
-	defer file.Close()
+	if _, err := prompt.Run(); err != nil {
+		return "", fmt.Errorf("invalid secret name '%s'", name)
+	}
+	return nameErr.Suggestion, nil
+}
+
+// loadSecretsConfig loads the saved secrets from the state store
+func loadSecretsConfig(logger *logrus.Logger) (map[string]string, error) {
+	var secrets map[string]string
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&workflows)
+	err := withStateStore(logger, func(store *StateStore) error {
+		warnIfInGitWorktree(store.Path, logger)
+		return store.View(func(tx *StateTx) error {
+			secrets = tx.Secrets()
+			return nil
+		})
+	})
 	if err != nil {
-		logger.Errorf("Error decoding workflows.json: %v", err)
+		logger.Errorf("Error loading saved secrets: %v", err)
 		return nil, err
 	}
 
-	var workflowNames []string
-	for name := range workflows {
-		workflowNames = append(workflowNames, name)
+	return secrets, nil
+}
+
+// loadSavedSecrets returns the names of the saved secrets
+func loadSavedSecrets(logger *logrus.Logger) ([]string, error) {
+	secrets, err := loadSecretsConfig(logger)
+	if err != nil {
+		return nil, err
 	}
 
-	return workflowNames, nil
+	return sortedKeys(secrets), nil
 }
 
-// promptSelectItems presents an interactive menu for selection
-func promptSelectItems(label string, items []string) ([]string, error) {
-	selectedItems := []string{}
-
-	for {
-		prompt := promptui.Select{
-			Label: label,
-			Items: items,
-			Size:  10,
-		}
-
-		_, result, err := prompt.Run()
-		if err != nil {
-			if err == promptui.ErrInterrupt || err == promptui.ErrEOF {
-				return selectedItems, nil
-			}
-			return selectedItems, err
-		}
-
-		// Confirm selection
-		confirmPrompt := promptui.Prompt{
-			Label:     fmt.Sprintf("Add '%s'?", result),
-			IsConfirm: true,
-		}
-
-		confirm, err := confirmPrompt.Run()
-		if err != nil {
-			if err == promptui.ErrInterrupt || err == promptui.ErrEOF {
-				return selectedItems, nil
-			}
-			return selectedItems, err
-		}
-
-		if strings.ToLower(confirm) == "y" || confirm == "" {
-			selectedItems = append(selectedItems, result)
-			fmt.Printf("Added '%s' to the selection.\n", result)
-		}
-
-		// Ask if the user wants to continue selecting
-		continuePrompt := promptui.Prompt{
-			Label:     "Select another item?",
-			IsConfirm: true,
-			Default:   "y",
-		}
-
-		cont, err := continuePrompt.Run()
-		if err != nil || strings.ToLower(cont) != "y" {
-			break
-		}
+// loadSavedWorkflows returns the names of the saved workflows
+func loadSavedWorkflows(logger *logrus.Logger) ([]string, error) {
+	var workflows map[string]string
+
+	err := withStateStore(logger, func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			workflows = tx.Workflows()
+			return nil
+		})
+	})
+	if err != nil {
+		logger.Errorf("Error loading saved workflows: %v", err)
+		return nil, err
 	}
 
-	return selectedItems, nil
-}
\ No newline at end of file
+	return sortedKeys(workflows), nil
+}
@@ -0,0 +1,98 @@
+// cmd_test.go
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runGHM runs the command line in a fresh command tree against an empty state directory and
+// returns what it wrote to stdout and stderr
+func runGHM(t *testing.T, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	// The global flags outlive the command tree
+	t.Cleanup(func() {
+		stateDirOverride, contextOverride, outputFormat, nonInteractive = "", "", outputTable, false
+	})
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	rootCmd := initRootCmd(logger)
+	var out, errOut bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	rootCmd.SetArgs(append([]string{"--non-interactive"}, args...))
+	err = rootCmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+// TestCommandGroups tests that the commands are grouped by noun with consistent flags
+func TestCommandGroups(t *testing.T) {
+	rootCmd := initRootCmd(logrus.New())
+	for _, path := range []string{
+		"secret add", "secret import", "secret remove", "secret list", "secret apply",
+		"workflow add", "workflow list", "workflow apply",
+		"config store", "config get", "config list", "config unset",
+		"repo list", "auth login",
+	} {
+		cmd, _, err := rootCmd.Find(strings.Fields(path))
+		require.NoError(t, err, path)
+		assert.Equal(t, "ghm "+path, cmd.CommandPath())
+		assert.False(t, cmd.Hidden, path)
+	}
+
+	// Commands on one repository take it with --repo or -r
+	for _, path := range []string{"secret add", "secret remove", "secret apply", "workflow add", "workflow apply"} {
+		cmd, _, _ := rootCmd.Find(strings.Fields(path))
+		flag := cmd.Flags().Lookup("repo")
+		require.NotNil(t, flag, path)
+		assert.Equal(t, "r", flag.Shorthand, path)
+	}
+
+	stdout, _, err := runGHM(t, "--help")
+	require.NoError(t, err)
+	for _, group := range []string{"secret", "workflow", "config", "repo", "auth"} {
+		assert.Contains(t, stdout, "\n  "+group+" ")
+	}
+	assert.NotContains(t, stdout, "add-secret")
+}
+
+// TestDeprecatedAliases tests that the old flat command names still run, hidden and with a
+// pointer to their replacement
+func TestDeprecatedAliases(t *testing.T) {
+	rootCmd := initRootCmd(logrus.New())
+	for old, replacement := range map[string]string{
+		"add-secret":          "ghm secret add",
+		"add-workflow":        "ghm workflow add",
+		"store-config":        "ghm config store",
+		"add-saved-secrets":   "ghm secret apply",
+		"add-saved-workflows": "ghm workflow apply",
+		"list-repos":          "ghm repo list",
+		"migrate-state":       "ghm state migrate",
+	} {
+		cmd, _, err := rootCmd.Find([]string{old})
+		require.NoError(t, err, old)
+		assert.Equal(t, old, cmd.Name())
+		assert.True(t, cmd.Hidden, old)
+		assert.Equal(t, "use '"+replacement+"' instead", cmd.Deprecated)
+	}
+
+	// Cobra prints the notice to the output writer, which is stderr unless a test replaces it
+	stdout, _, err := runGHM(t, "list-repos", "-o", "json")
+	require.NoError(t, err)
+	notice := "Command \"list-repos\" is deprecated, use 'ghm repo list' instead\n"
+	require.True(t, strings.HasPrefix(stdout, notice), stdout)
+	assert.JSONEq(t, "[]", strings.TrimPrefix(stdout, notice))
+}
@@ -1,9 +1,14 @@
 // colors.go
 package main
 
-import "github.com/fatih/color"
+import (
+    "io"
+    "os"
 
-// Define color variables
+    "github.com/fatih/color"
+)
+
+// Define color variables; applyTheme replaces them with the palette of the active theme
 var (
     HeaderColor   = color.New(color.FgCyan, color.Bold)
     SuccessColor  = color.New(color.FgGreen)
@@ -12,4 +17,8 @@ var (
     InfoColor     = color.New(color.FgBlue)
     PromptColor   = color.New(color.FgMagenta)
     ResetColor    = color.New(color.Reset)
-)
\ No newline at end of file
+)
+
+// messageOutput receives colored messages printed outside of command results. The TUI
+// discards them while it owns the screen; they are logged as well.
+var messageOutput io.Writer = os.Stderr
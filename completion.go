@@ -0,0 +1,145 @@
+// completion.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// completionTimeout bounds the GitHub API calls made while completing a flag
+const completionTimeout = 3 * time.Second
+
+// Initialize Completion Command
+func initCompletionCmd(logger *logrus.Logger) *cobra.Command {
+	completionCmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the shell completion script",
+		Long: `Generate the shell completion script for ghm.
+
+  bash:       source <(ghm completion bash)
+  zsh:        ghm completion zsh > "${fpath[1]}/_ghm"
+  fish:       ghm completion fish > ~/.config/fish/completions/ghm.fish
+  powershell: ghm completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.ExactValidArgs(1),
+		DisableFlagsInUseLine: true,
+		// Completion scripts are generated without a token or configuration
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(out)
+			}
+
+			logger.Errorf("Unsupported shell '%s'", args[0])
+			return fmt.Errorf("unsupported shell '%s'", args[0])
+		},
+	}
+
+	return completionCmd
+}
+
+// isCompletionRequest reports whether cmd is the hidden command the completion scripts call
+func isCompletionRequest(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd
+}
+
+// completionSetup loads the configuration for a completion request and returns a logger
+// that keeps log output out of the completion results. An unknown context is ignored.
+func completionSetup(cmd *cobra.Command) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	if cmd.Flags().Changed("state-dir") {
+		viper.Reset()
+	}
+	initConfig(logger)
+	applyContext()
+
+	return logger
+}
+
+// completeRepos completes --repo from tracked repositories and the repositories the token can access
+func completeRepos(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	logger := completionSetup(cmd)
+
+	var repos []string
+	if reposConfig, err := LoadReposConfig(logger); err == nil {
+		for repo := range reposConfig.Repositories {
+			repos = append(repos, repo)
+		}
+	}
+
+	if token := githubToken(logger); token != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		if remote, err := NewGHM(token, logger).ListRepos(ctx); err == nil {
+			repos = appendMissing(repos, remote...)
+		}
+	}
+
+	sort.Strings(repos)
+	return filterPrefix(repos, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSecretNames completes --name from saved secrets, and from the secrets of --repo when it is set
+func completeSecretNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	logger := completionSetup(cmd)
+
+	names, _ := loadSavedSecrets(logger)
+
+	repo, _ := cmd.Flags().GetString("repo")
+	if token := githubToken(logger); repo != "" && token != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		if remote, err := NewGHM(token, logger).ListRepoSecrets(ctx, repo); err == nil {
+			names = appendMissing(names, remote...)
+			sort.Strings(names)
+		}
+	}
+
+	return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWorkflowNames completes --name from saved workflows
+func completeWorkflowNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	logger := completionSetup(cmd)
+
+	names, _ := loadSavedWorkflows(logger)
+	return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContexts completes --context from the configured contexts
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completionSetup(cmd)
+
+	return filterPrefix(contextNames(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// filterPrefix returns the values starting with prefix
+func filterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,41 @@
+// completion_test.go
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompleteContexts tests that --context completes from the contexts of the config file
+func TestCompleteContexts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GHM_STATE_DIR", dir)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	config := "contexts:\n  work:\n    secret_name_prefix: WORK_\n  home:\n    secret_name_prefix: HOME_\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0600))
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	complete := func(prefix string) []string {
+		rootCmd := initRootCmd(logger)
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"__complete", "--context", prefix})
+		require.NoError(t, rootCmd.Execute())
+		return strings.Split(strings.TrimSpace(out.String()), "\n")
+	}
+
+	assert.Equal(t, []string{"home", "work", ":4"}, complete(""))
+	assert.Equal(t, []string{"work", ":4"}, complete("w"))
+}
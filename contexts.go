@@ -0,0 +1,44 @@
+// contexts.go
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// contextOverride is set by the --context flag
+var contextOverride string
+
+// contextNames returns the names of the contexts configured under the contexts key
+func contextNames() []string {
+	contexts := viper.GetStringMap("contexts")
+	names := make([]string, 0, len(contexts))
+	for name := range contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyContext overlays the settings of the selected context on the top-level configuration.
+// The context is chosen with --context, falling back to the current_context key.
+func applyContext() error {
+	name := contextOverride
+	if name == "" {
+		name = viper.GetString("current_context")
+	}
+	if name == "" {
+		return nil
+	}
+
+	settings := viper.GetStringMap("contexts." + name)
+	if len(settings) == 0 {
+		return fmt.Errorf("context '%s' is not configured", name)
+	}
+	for key, value := range settings {
+		viper.Set(key, value)
+	}
+	return nil
+}
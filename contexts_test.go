@@ -0,0 +1,34 @@
+// contexts_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestApplyContext tests that the selected context overrides the top-level settings
+func TestApplyContext(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { contextOverride = "" })
+	viper.Set("secret_name_prefix", "TOP_")
+	viper.Set("current_context", "home")
+	viper.Set("contexts", map[string]interface{}{
+		"work": map[string]interface{}{"secret_name_prefix": "WORK_"},
+		"home": map[string]interface{}{"secret_name_prefix": "HOME_"},
+	})
+
+	assert.Equal(t, []string{"home", "work"}, contextNames())
+	assert.NoError(t, applyContext())
+	assert.Equal(t, "HOME_", viper.GetString("secret_name_prefix"))
+
+	contextOverride = "work"
+	assert.NoError(t, applyContext())
+	assert.Equal(t, "WORK_", viper.GetString("secret_name_prefix"))
+
+	contextOverride = "gone"
+	assert.EqualError(t, applyContext(), "context 'gone' is not configured")
+}
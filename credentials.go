@@ -0,0 +1,619 @@
+// credentials.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credential keys kept out of the plaintext config file
+var credentialKeys = []string{"github_token", "github_refresh_token", "github_token_expiry"}
+
+// plainCredentialKeys are credential keys whose values are not secrets. Git credential
+// helpers only hold passwords, so these stay in the encrypted file instead.
+var plainCredentialKeys = []string{"github_token_expiry"}
+
+// Files used by the encrypted credential store
+const (
+	credentialsFile    = "credentials.enc"
+	credentialsKeyFile = "credentials.key"
+
+	// credentialPassphraseEnv derives the encryption key from a passphrase instead of a key file
+	credentialPassphraseEnv = "GHM_CREDENTIAL_PASSPHRASE"
+
+	secretServiceName = "ghm"
+
+	// credentialHost is the host of the credentials ghm keeps in a git credential helper.
+	// It can never be a real host, so git never offers them for a remote.
+	credentialHost = "ghm.invalid"
+	// legacyCredentialHost is where earlier versions kept them, mixed with the git credentials
+	legacyCredentialHost = "github.com"
+)
+
+// ErrCredentialNotFound is returned when a credential is not in the store
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore abstracts where credentials such as the GitHub token are kept
+type CredentialStore interface {
+	Name() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// isCredentialKey reports whether a config key holds a credential
+func isCredentialKey(key string) bool {
+	for _, k := range credentialKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCredentialStore selects the credential store from the credential_helper and credential_store config keys.
+// Without explicit configuration the Secret Service keyring is used when available, then the encrypted file.
+func NewCredentialStore(logger *logrus.Logger) CredentialStore {
+	if helper := viper.GetString("credential_helper"); helper != "" {
+		return &CredentialHelperStore{Helper: helper, Logger: logger}
+	}
+
+	switch viper.GetString("credential_store") {
+	case "file":
+		return NewEncryptedFileStore(logger)
+	case "keyring":
+		return &SecretServiceStore{Logger: logger}
+	}
+
+	if secretServiceAvailable() {
+		return &SecretServiceStore{Logger: logger}
+	}
+	return NewEncryptedFileStore(logger)
+}
+
+// githubToken returns the GitHub token from the environment or the credential store
+func githubToken(logger *logrus.Logger) string {
+	return getCredential("github_token", logger)
+}
+
+// getCredential reads a credential, falling back to the environment or legacy config
+func getCredential(key string, logger *logrus.Logger) string {
+	if value := viper.GetString(key); value != "" {
+		return value
+	}
+
+	value, err := NewCredentialStore(logger).Get(key)
+	if err != nil && !errors.Is(err, ErrCredentialNotFound) {
+		logger.Errorf("Error reading credential '%s': %v", key, err)
+	}
+	return value
+}
+
+// storeCredential writes a credential to the configured store
+func storeCredential(key, value string, logger *logrus.Logger) error {
+	store := NewCredentialStore(logger)
+	if value == "" {
+		if err := store.Delete(key); err != nil && !errors.Is(err, ErrCredentialNotFound) {
+			return err
+		}
+		return nil
+	}
+	if err := store.Set(key, value); err != nil {
+		logger.Errorf("Error storing credential '%s' in %s: %v", key, store.Name(), err)
+		return err
+	}
+	logger.Debugf("Credential '%s' stored in %s.", key, store.Name())
+	return nil
+}
+
+// migratePlaintextCredentials moves credentials found in the config file into the credential
+// store, and those an earlier version kept in the credential helper under github.com
+func migratePlaintextCredentials(logger *logrus.Logger) error {
+	if err := migrateLegacyHelperCredentials(logger); err != nil {
+		logger.Errorf("Error moving credentials out of the %s git credentials: %v", legacyCredentialHost, err)
+		return err
+	}
+
+	if viper.ConfigFileUsed() == "" {
+		return nil
+	}
+
+	var migrated []string
+	for _, key := range credentialKeys {
+		if !viper.InConfig(key) {
+			continue
+		}
+		if value := viper.GetString(key); value != "" {
+			if err := storeCredential(key, value, logger); err != nil {
+				return err
+			}
+		}
+		migrated = append(migrated, key)
+	}
+	if len(migrated) == 0 {
+		return nil
+	}
+
+	if err := removeConfigKeys(migrated...); err != nil {
+		logger.Errorf("Error removing plaintext credentials from config: %v", err)
+		return err
+	}
+
+	WarningColor.Fprintln(messageOutput, "Moved plaintext credentials from the config file to the credential store.")
+	logger.Infof("Migrated %d plaintext credential(s) to the credential store.", len(migrated))
+	return nil
+}
+
+// migrateLegacyHelperCredentials moves the credentials that earlier versions stored under
+// legacyCredentialHost once per helper. The state store records the helper it ran for, so
+// that lookups never ask the helper for github.com credentials, which may prompt for a login.
+func migrateLegacyHelperCredentials(logger *logrus.Logger) error {
+	helper, ok := NewCredentialStore(logger).(*CredentialHelperStore)
+	if !ok {
+		return nil
+	}
+
+	return DataDirState(logger).WithStore(func(state *StateStore) error {
+		var migrated string
+		err := state.View(func(tx *StateTx) error {
+			migrated, _ = tx.Meta(helperMigratedKey)
+			return nil
+		})
+		if err != nil || migrated == helper.Helper {
+			return err
+		}
+
+		moved, err := helper.moveLegacyCredentials()
+		if err != nil {
+			return err
+		}
+		for _, key := range moved {
+			logger.Infof("Moved credential '%s' in %s away from the %s git credentials.", key, helper.Name(), legacyCredentialHost)
+		}
+		return state.Update(func(tx *StateTx) error {
+			return tx.PutMeta(helperMigratedKey, helper.Helper)
+		})
+	})
+}
+
+// removeConfigKeys rewrites the config file without the given keys and reloads it
+func removeConfigKeys(keys ...string) error {
+	configFile := viper.ConfigFileUsed()
+
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(configFile)
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return err
+	}
+
+	settings := fileConfig.AllSettings()
+	for _, key := range keys {
+		deleteNestedKey(settings, strings.Split(strings.ToLower(key), "."))
+	}
+
+	rewritten := viper.New()
+	rewritten.SetConfigFile(configFile)
+	for key, value := range settings {
+		rewritten.Set(key, value)
+	}
+	if err := rewritten.WriteConfig(); err != nil {
+		return err
+	}
+
+	return viper.ReadInConfig()
+}
+
+// deleteNestedKey removes a dotted key such as contexts.work.oauth_client_id from settings
+func deleteNestedKey(settings map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(settings, path[0])
+		return
+	}
+	if nested, ok := settings[path[0]].(map[string]interface{}); ok {
+		deleteNestedKey(nested, path[1:])
+	}
+}
+
+// EncryptedFileStore keeps credentials in a secretbox-encrypted JSON file.
+// The key comes from GHM_CREDENTIAL_PASSPHRASE when set, otherwise from a random key file.
+// That key file sits next to the credentials, so without a passphrase the encryption does
+// not protect them from anyone who can read the data directory.
+type EncryptedFileStore struct {
+	Path    string
+	KeyPath string
+	Logger  *logrus.Logger
+}
+
+// encryptedFile is the on-disk layout of the encrypted credential store
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    string `json:"salt,omitempty"`
+	Nonce   string `json:"nonce"`
+	Data    string `json:"data"`
+}
+
+// NewEncryptedFileStore creates an EncryptedFileStore in the data directory
+func NewEncryptedFileStore(logger *logrus.Logger) *EncryptedFileStore {
+	return &EncryptedFileStore{
+		Path:    dataPath(credentialsFile),
+		KeyPath: dataPath(credentialsKeyFile),
+		Logger:  logger,
+	}
+}
+
+// Name returns the display name of the store
+func (s *EncryptedFileStore) Name() string {
+	return "encrypted file " + s.Path
+}
+
+// Get returns a credential from the encrypted file
+func (s *EncryptedFileStore) Get(key string) (string, error) {
+	credentials, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, exists := credentials[key]
+	if !exists {
+		return "", ErrCredentialNotFound
+	}
+	return value, nil
+}
+
+// Set stores a credential in the encrypted file
+func (s *EncryptedFileStore) Set(key, value string) error {
+	credentials, err := s.load()
+	if err != nil {
+		return err
+	}
+	credentials[key] = value
+	return s.save(credentials)
+}
+
+// Delete removes a credential from the encrypted file
+func (s *EncryptedFileStore) Delete(key string) error {
+	credentials, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, exists := credentials[key]; !exists {
+		return ErrCredentialNotFound
+	}
+	delete(credentials, key)
+	return s.save(credentials)
+}
+
+// load decrypts the credential file, returning an empty map when it does not exist
+func (s *EncryptedFileStore) load() (map[string]string, error) {
+	credentials := make(map[string]string)
+
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", s.Path, err)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(file.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	nonceBytes, err := base64.StdEncoding.DecodeString(file.Nonce)
+	if err != nil || len(nonceBytes) != 24 {
+		return nil, fmt.Errorf("invalid nonce in %s", s.Path)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(file.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+
+	key, err := s.key(salt, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], nonceBytes)
+	plaintext, ok := secretbox.Open(nil, sealed, &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("failed to decrypt %s: wrong key or corrupted file", s.Path)
+	}
+
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// save encrypts the credentials with a fresh nonce and writes them with owner-only permissions
+func (s *EncryptedFileStore) save(credentials map[string]string) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	var salt []byte
+	if os.Getenv(credentialPassphraseEnv) != "" {
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+	}
+
+	key, err := s.key(salt, true)
+	if err != nil {
+		return err
+	}
+
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Version: 1,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Nonce:   base64.StdEncoding.EncodeToString(nonce[:]),
+		Data:    base64.StdEncoding.EncodeToString(secretbox.Seal(nil, plaintext, &nonce, key)),
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.Path, data, 0600)
+}
+
+// key returns the encryption key, deriving it from the passphrase or reading (and optionally creating) the key file
+func (s *EncryptedFileStore) key(salt []byte, create bool) (*[32]byte, error) {
+	var key [32]byte
+
+	if passphrase := os.Getenv(credentialPassphraseEnv); passphrase != "" {
+		if len(salt) == 0 {
+			return nil, fmt.Errorf("%s is set but %s was not encrypted with a passphrase", credentialPassphraseEnv, s.Path)
+		}
+		derived, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+		if err != nil {
+			return nil, err
+		}
+		copy(key[:], derived)
+		return &key, nil
+	}
+	if len(salt) > 0 {
+		return nil, fmt.Errorf("%s is encrypted with a passphrase; set %s", s.Path, credentialPassphraseEnv)
+	}
+
+	data, err := ioutil.ReadFile(s.KeyPath)
+	if os.IsNotExist(err) && create {
+		if _, err := io.ReadFull(rand.Reader, key[:]); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(s.KeyPath), 0700); err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(key[:])
+		if err := ioutil.WriteFile(s.KeyPath, []byte(encoded), 0600); err != nil {
+			return nil, err
+		}
+		return &key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential key %s: %w", s.KeyPath, err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("invalid credential key in %s", s.KeyPath)
+	}
+	copy(key[:], decoded)
+	return &key, nil
+}
+
+// SecretServiceStore keeps credentials in the freedesktop Secret Service keyring through secret-tool
+type SecretServiceStore struct {
+	Logger *logrus.Logger
+}
+
+// secretServiceAvailable reports whether secret-tool and a session bus are present
+func secretServiceAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+// Name returns the display name of the store
+func (s *SecretServiceStore) Name() string {
+	return "Secret Service keyring"
+}
+
+// Get returns a credential from the keyring
+func (s *SecretServiceStore) Get(key string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", secretServiceName, "key", key)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits non-zero when no item matches
+		if _, ok := err.(*exec.ExitError); ok {
+			return "", ErrCredentialNotFound
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// Set stores a credential in the keyring
+func (s *SecretServiceStore) Set(key, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "ghm "+key, "service", secretServiceName, "key", key)
+	cmd.Stdin = strings.NewReader(value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Delete removes a credential from the keyring
+func (s *SecretServiceStore) Delete(key string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", secretServiceName, "key", key)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool clear failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// CredentialHelperStore talks to a git credential helper (get/store/erase over stdin/stdout).
+// Helper follows git's credential.helper syntax: a name such as "store" runs git credential-store,
+// an absolute path runs that program, and a value starting with "!" runs as a shell command.
+// Credentials are kept under credentialHost so that they never mix with git credentials.
+type CredentialHelperStore struct {
+	Helper string
+	Logger *logrus.Logger
+	Local  CredentialStore // keeps the plainCredentialKeys; the encrypted file when nil
+}
+
+// Name returns the display name of the store
+func (s *CredentialHelperStore) Name() string {
+	return "credential helper " + s.Helper
+}
+
+// Get asks the helper for the credential stored under the key
+func (s *CredentialHelperStore) Get(key string) (string, error) {
+	if s.isPlain(key) {
+		return s.local().Get(key)
+	}
+	return s.get(credentialHost, key)
+}
+
+// moveLegacyCredentials moves the credentials an earlier version stored under
+// legacyCredentialHost to where Set keeps them, and returns their keys
+func (s *CredentialHelperStore) moveLegacyCredentials() ([]string, error) {
+	var moved []string
+	for _, key := range credentialKeys {
+		value, err := s.get(legacyCredentialHost, key)
+		if errors.Is(err, ErrCredentialNotFound) {
+			continue
+		}
+		if err != nil {
+			return moved, err
+		}
+		if err := s.Set(key, value); err != nil {
+			return moved, err
+		}
+		if _, err := s.run("erase", s.request(legacyCredentialHost, key, "")); err != nil {
+			return moved, err
+		}
+		moved = append(moved, key)
+	}
+	return moved, nil
+}
+
+// get asks the helper for the credential stored for the host under the key
+func (s *CredentialHelperStore) get(host, key string) (string, error) {
+	output, err := s.run("get", s.request(host, key, ""))
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), "=")
+		if found && name == "password" {
+			return value, nil
+		}
+	}
+	return "", ErrCredentialNotFound
+}
+
+// Set asks the helper to store the credential
+func (s *CredentialHelperStore) Set(key, value string) error {
+	if s.isPlain(key) {
+		return s.local().Set(key, value)
+	}
+	if strings.ContainsAny(value, "\n\x00") {
+		return fmt.Errorf("credential helpers cannot store values containing newlines")
+	}
+	_, err := s.run("store", s.request(credentialHost, key, value))
+	return err
+}
+
+// Delete asks the helper to erase the credential
+func (s *CredentialHelperStore) Delete(key string) error {
+	if s.isPlain(key) {
+		return s.local().Delete(key)
+	}
+	_, err := s.run("erase", s.request(credentialHost, key, ""))
+	return err
+}
+
+// isPlain reports whether the key is kept out of the helper
+func (s *CredentialHelperStore) isPlain(key string) bool {
+	return contains(plainCredentialKeys, strings.ToLower(key))
+}
+
+// local returns the store of the plainCredentialKeys
+func (s *CredentialHelperStore) local() CredentialStore {
+	if s.Local != nil {
+		return s.Local
+	}
+	return NewEncryptedFileStore(s.Logger)
+}
+
+// request builds a credential description for the host, using the key as the username
+func (s *CredentialHelperStore) request(host, key, value string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "protocol=https\nhost=%s\nusername=%s\n", host, key)
+	if value != "" {
+		fmt.Fprintf(&b, "password=%s\n", value)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// run invokes the helper with the given action
+func (s *CredentialHelperStore) run(action, input string) ([]byte, error) {
+	var cmd *exec.Cmd
+	switch {
+	case strings.HasPrefix(s.Helper, "!"):
+		cmd = exec.Command("sh", "-c", strings.TrimPrefix(s.Helper, "!")+" "+action)
+	case filepath.IsAbs(s.Helper):
+		fields := strings.Fields(s.Helper)
+		cmd = exec.Command(fields[0], append(fields[1:], action)...)
+	default:
+		fields := strings.Fields(s.Helper)
+		cmd = exec.Command("git", append([]string{"credential-" + fields[0]}, append(fields[1:], action)...)...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s %s failed: %v: %s", s.Helper, action, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
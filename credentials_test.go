@@ -0,0 +1,173 @@
+// credentials_test.go
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEncryptedFileStore returns an EncryptedFileStore inside a temporary directory
+func newTestEncryptedFileStore(t *testing.T) *EncryptedFileStore {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	dir := t.TempDir()
+	return &EncryptedFileStore{
+		Path:    filepath.Join(dir, "credentials.enc"),
+		KeyPath: filepath.Join(dir, "credentials.key"),
+		Logger:  logger,
+	}
+}
+
+// TestEncryptedFileStore tests storing, reading and deleting credentials
+func TestEncryptedFileStore(t *testing.T) {
+	store := newTestEncryptedFileStore(t)
+
+	_, err := store.Get("github_token")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+
+	require.NoError(t, store.Set("github_token", "ghp_secret"))
+
+	value, err := store.Get("github_token")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_secret", value)
+
+	// The token must not appear in plaintext on disk
+	data, err := os.ReadFile(store.Path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ghp_secret")
+
+	info, err := os.Stat(store.Path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, store.Delete("github_token"))
+	_, err = store.Get("github_token")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+}
+
+// TestEncryptedFileStorePassphrase tests that a passphrase-encrypted file needs the passphrase
+func TestEncryptedFileStorePassphrase(t *testing.T) {
+	store := newTestEncryptedFileStore(t)
+
+	t.Setenv("GHM_CREDENTIAL_PASSPHRASE", "correct horse")
+	require.NoError(t, store.Set("github_token", "ghp_secret"))
+
+	value, err := store.Get("github_token")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_secret", value)
+
+	t.Setenv("GHM_CREDENTIAL_PASSPHRASE", "wrong horse")
+	_, err = store.Get("github_token")
+	assert.Error(t, err)
+
+	t.Setenv("GHM_CREDENTIAL_PASSPHRASE", "")
+	_, err = store.Get("github_token")
+	assert.Error(t, err)
+}
+
+// TestCredentialHelperStore tests the git credential helper protocol against a shell helper
+func TestCredentialHelperStore(t *testing.T) {
+	dir := t.TempDir()
+	stored := filepath.Join(dir, "stored")
+
+	// A minimal helper that keeps the last stored password in a file
+	script := `#!/bin/sh
+input=$(cat)
+case "$1" in
+  store) echo "$input" | sed -n 's/^password=//p' > "` + stored + `" ;;
+  get) [ -s "` + stored + `" ] && echo "password=$(cat "` + stored + `")" ;;
+  erase) rm -f "` + stored + `" ;;
+esac
+exit 0
+`
+	helperPath := filepath.Join(dir, "helper.sh")
+	require.NoError(t, os.WriteFile(helperPath, []byte(script), 0755))
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	store := &CredentialHelperStore{Helper: helperPath, Logger: logger}
+
+	require.NoError(t, store.Set("github_token", "ghp_helper"))
+
+	value, err := store.Get("github_token")
+	require.NoError(t, err)
+	assert.Equal(t, "ghp_helper", strings.TrimSpace(value))
+
+	require.NoError(t, store.Delete("github_token"))
+	_, err = store.Get("github_token")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+}
+
+// TestCredentialHelperStoreHost tests that credentials never share a host with git
+// credentials, that the expiry stays out of the helper and that legacy entries are moved
+func TestCredentialHelperStoreHost(t *testing.T) {
+	dir := t.TempDir()
+
+	// A helper that keeps each password in <dir>/<host>/<username>
+	script := `#!/bin/sh
+input=$(cat)
+host=$(echo "$input" | sed -n 's/^host=//p')
+user=$(echo "$input" | sed -n 's/^username=//p')
+file="` + dir + `/$host/$user"
+echo "$1 $host" >> "` + dir + `/calls"
+case "$1" in
+  store) mkdir -p "` + dir + `/$host"; echo "$input" | sed -n 's/^password=//p' > "$file" ;;
+  get) [ -s "$file" ] && echo "password=$(cat "$file")" ;;
+  erase) rm -f "$file" ;;
+esac
+exit 0
+`
+	helperPath := filepath.Join(dir, "helper.sh")
+	require.NoError(t, os.WriteFile(helperPath, []byte(script), 0755))
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	local := newTestEncryptedFileStore(t)
+	store := &CredentialHelperStore{Helper: helperPath, Logger: logger, Local: local}
+
+	require.NoError(t, store.Set("github_token", "ghp_helper"))
+	assert.FileExists(t, filepath.Join(dir, "ghm.invalid", "github_token"))
+	assert.NoDirExists(t, filepath.Join(dir, "github.com"))
+
+	require.NoError(t, store.Set("github_token_expiry", "2030-01-01T00:00:00Z"))
+	assert.NoFileExists(t, filepath.Join(dir, "ghm.invalid", "github_token_expiry"))
+	expiry, err := local.Get("github_token_expiry")
+	require.NoError(t, err)
+	assert.Equal(t, "2030-01-01T00:00:00Z", expiry)
+
+	// Entries of earlier versions are moved out of the github.com credentials
+	legacy := filepath.Join(dir, "github.com")
+	require.NoError(t, os.MkdirAll(legacy, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(legacy, "github_refresh_token"), []byte("ghr_old\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(legacy, "git-user"), []byte("git-password\n"), 0600))
+
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("credential_helper", helperPath)
+	require.NoError(t, migratePlaintextCredentials(logger))
+	assert.NoFileExists(t, filepath.Join(legacy, "github_refresh_token"))
+	assert.FileExists(t, filepath.Join(legacy, "git-user"), "git credentials are left alone")
+	value, err := store.Get("github_refresh_token")
+	require.NoError(t, err)
+	assert.Equal(t, "ghr_old", value)
+
+	// The migration runs once, and lookups never ask for github.com credentials
+	require.NoError(t, os.Remove(filepath.Join(dir, "calls")))
+	require.NoError(t, migratePlaintextCredentials(logger))
+	_, err = store.Get("github_token_unknown")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	require.NoError(t, err)
+	assert.Equal(t, "get ghm.invalid\n", string(calls))
+}
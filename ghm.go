@@ -9,10 +9,11 @@ import (
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,7 +22,7 @@ import (
 	"github.com/go-git/go-git/v5" // For Git operations
 	git_http "github.com/go-git/go-git/v5/plumbing/transport/http" // For HTTP transport
 	"github.com/go-git/go-git/v5/plumbing/object" // Required for object signatures
-	"github.com/google/go-github/v50/github"
+	"github.com/google/go-github/v66/github"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/nacl/box"
 	"golang.org/x/oauth2"
@@ -103,8 +104,52 @@ type GHM interface {
 	AddSecret(ctx context.Context, repo, secretName, secretValue string) error
 	AddWorkflow(ctx context.Context, repo, workflowName, content string) error
 	StoreConfig(ctx context.Context, key, value string) error
-	AddSecretsToRepo(ctx context.Context, targetRepo string, secretNames []string, reposConfig *ReposConfig) error
-	AddWorkflowsToRepo(ctx context.Context, targetRepo string, workflowNames []string, reposConfig *ReposConfig) error
+	UnsetConfig(ctx context.Context, key string) error
+	AddSecretsToRepo(ctx context.Context, targetRepo string, secretNames []string, reposConfig *ReposConfig) (OperationResults, error)
+	AddWorkflowsToRepo(ctx context.Context, targetRepo string, workflowNames []string, reposConfig *ReposConfig) (OperationResults, error)
+	RemoveSecret(ctx context.Context, repo, secretName string) error
+	RemoveWorkflow(ctx context.Context, repo, workflowName string) error
+	ListRepoSecrets(ctx context.Context, repo string) ([]string, error)
+	ListRepoWorkflows(ctx context.Context, repo string) ([]string, error)
+	ListRepos(ctx context.Context) ([]string, error)
+	WorkflowContent(ctx context.Context, repo, workflowName string) (string, bool, error)
+	ListWorkflowRuns(ctx context.Context, repo string) ([]WorkflowRun, bool, error)
+	ListRunJobs(ctx context.Context, repo string, runID int64) ([]WorkflowJob, error)
+	JobLog(ctx context.Context, repo string, jobID int64) (string, error)
+	RerunWorkflowRun(ctx context.Context, repo string, runID int64) error
+	CancelWorkflowRun(ctx context.Context, repo string, runID int64) error
+}
+
+// newGitHubClient creates a GitHub API client authenticated with the token.
+// GITHUB_API_URL points it at GitHub Enterprise Server or a test server.
+func newGitHubClient(ctx context.Context, token string) (*github.Client, error) {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	tc := oauth2.NewClient(ctx, ts)
+	client := github.NewClient(tc)
+
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		if !strings.HasSuffix(apiURL, "/") {
+			apiURL += "/"
+		}
+		baseURL, err := url.Parse(apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_API_URL: %w", err)
+		}
+		client.BaseURL = baseURL
+	}
+
+	return client, nil
+}
+
+// splitRepo splits "owner/repo" into its parts
+func splitRepo(fullName string) (string, string, error) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository format")
+	}
+	return parts[0], parts[1], nil
 }
 
 // GHMImpl is the concrete implementation of the GHM interface
@@ -112,6 +157,16 @@ type GHMImpl struct {
 	Token     string
 	Encryptor Encryptor
 	Logger    *logrus.Logger
+	Cache     *ETagCache    // conditional requests for polled workflow runs; may be nil
+	State     StateProvider // saved items, tracked repositories and the journal; the data directory when nil
+}
+
+// state returns the store of state the operations read and record
+func (g *GHMImpl) state() StateProvider {
+	if g.State != nil {
+		return g.State
+	}
+	return DataDirState(g.Logger)
 }
 
 // NewGHM creates a new instance of GHMImpl
@@ -120,6 +175,7 @@ func NewGHM(token string, logger *logrus.Logger) GHM {
 		Token:     token,
 		Encryptor: &EncryptorImpl{},
 		Logger:    logger,
+		Cache:     NewETagCache(),
 	}
 }
 
@@ -132,8 +188,32 @@ func (g *GHMImpl) AddSecret(ctx context.Context, repo, secretName, secretValue s
 		SecretValue: secretValue,
 		Encryptor:   g.Encryptor,
 		Logger:      g.Logger,
+		Ctx:         ctx,
+		State:       g.State,
+		SkipLocal:   skipsLocalSave(ctx),
 	}
-	return strategy.Execute()
+	err := strategy.Execute()
+	g.record(ctx, JournalEntry{Operation: opAddSecret, Kind: kindSecret, Name: secretName, Repo: repo,
+		Previous: &PreviousState{Exists: !strategy.Created}}, err)
+	return err
+}
+
+// skipLocalKey is the context key that keeps AddSecret from saving the value locally
+type skipLocalKey struct{}
+
+// withoutLocalSave makes AddSecret only push the value, for callers that save it themselves
+// once every push succeeded
+func withoutLocalSave(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipLocalKey{}, true)
+}
+
+// skipsLocalSave reports whether AddSecret run with ctx leaves the saved value alone
+func skipsLocalSave(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	skip, _ := ctx.Value(skipLocalKey{}).(bool)
+	return skip
 }
 
 // AddWorkflow adds a workflow file to the GitHub repository
@@ -144,8 +224,12 @@ func (g *GHMImpl) AddWorkflow(ctx context.Context, repo, workflowName, content s
 		WorkflowName: workflowName,
 		Content:      content,
 		Logger:       g.Logger,
+		Ctx:          ctx,
 	}
-	return strategy.Execute()
+	err := strategy.Execute()
+	g.record(ctx, JournalEntry{Operation: opAddWorkflow, Kind: kindWorkflow, Name: workflowName, Repo: repo, Commit: strategy.Commit,
+		FileSHA: workflowSHA(content), Previous: &PreviousState{Exists: strategy.PreviousExists, Content: strategy.PreviousContent, Commit: strategy.BaseCommit}}, err)
+	return err
 }
 
 // StoreConfig stores a configuration key-value pair
@@ -158,13 +242,29 @@ func (g *GHMImpl) StoreConfig(ctx context.Context, key, value string) error {
 	return strategy.Execute()
 }
 
+// UnsetConfig removes a configuration key
+func (g *GHMImpl) UnsetConfig(ctx context.Context, key string) error {
+	strategy := &UnsetConfigStrategy{
+		ConfigKey: key,
+		Logger:    g.Logger,
+	}
+	return strategy.Execute()
+}
+
 // AddSecretsToRepo adds multiple secrets to a target repository
-func (g *GHMImpl) AddSecretsToRepo(ctx context.Context, targetRepo string, secretNames []string, reposConfig *ReposConfig) error {
+func (g *GHMImpl) AddSecretsToRepo(ctx context.Context, targetRepo string, secretNames []string, reposConfig *ReposConfig) (OperationResults, error) {
+	// Reject bad names before the first secret is sent
+	if err := validateSecretNames(secretNames, secretNameRules()); err != nil {
+		return nil, err
+	}
+
+	results := make(OperationResults, 0, len(secretNames))
 	for _, secretName := range secretNames {
-		// Retrieve secret value from secrets.json
+		// Retrieve secret value from the state store
 		secretValue, err := g.getSecretValue(secretName)
 		if err != nil {
 			g.Logger.Errorf("Error retrieving secret '%s': %v", secretName, err)
+			results = append(results, OperationResult{Kind: kindSecret, Name: secretName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
 			continue
 		}
 
@@ -172,35 +272,37 @@ func (g *GHMImpl) AddSecretsToRepo(ctx context.Context, targetRepo string, secre
 		err = g.AddSecret(ctx, targetRepo, secretName, secretValue)
 		if err != nil {
 			g.Logger.Errorf("Error adding secret '%s' to '%s': %v", secretName, targetRepo, err)
+			results = append(results, OperationResult{Kind: kindSecret, Name: secretName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
 			continue
 		}
 
-		// Update reposConfig
-		repoConfig, exists := reposConfig.Repositories[targetRepo]
-		if !exists {
-			repoConfig = RepoConfig{
-				Secrets:    []string{},
-				Workflows:  []string{},
-				LastUpdate: time.Now().Format(time.RFC3339),
-			}
+		// Record the item right away so concurrent runs and crashes cannot lose it
+		repoConfig, err := recordRepoChange(g.state(), targetRepo, func(config *RepoConfig) {
+			config.Secrets = appendMissing(config.Secrets, secretName)
+		})
+		if err != nil {
+			g.Logger.Errorf("Error recording '%s' for '%s': %v", secretName, targetRepo, err)
+			results = append(results, OperationResult{Kind: kindSecret, Name: secretName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
+			continue
 		}
-		repoConfig.Secrets = append(repoConfig.Secrets, secretName)
-		repoConfig.LastUpdate = time.Now().Format(time.RFC3339)
 		reposConfig.Repositories[targetRepo] = repoConfig
 
 		g.Logger.Infof("Secret '%s' added to repository '%s'.", secretName, targetRepo)
+		results = append(results, OperationResult{Kind: kindSecret, Name: secretName, Repo: targetRepo, Action: actionAdded})
 	}
 
-	return nil
+	return results, nil
 }
 
 // AddWorkflowsToRepo adds multiple workflows to a target repository
-func (g *GHMImpl) AddWorkflowsToRepo(ctx context.Context, targetRepo string, workflowNames []string, reposConfig *ReposConfig) error {
+func (g *GHMImpl) AddWorkflowsToRepo(ctx context.Context, targetRepo string, workflowNames []string, reposConfig *ReposConfig) (OperationResults, error) {
+	results := make(OperationResults, 0, len(workflowNames))
 	for _, workflowName := range workflowNames {
-		// Retrieve workflow content from workflows.json
+		// Retrieve workflow content from the state store
 		workflowContent, err := g.getWorkflowContent(workflowName)
 		if err != nil {
 			g.Logger.Errorf("Error retrieving workflow '%s': %v", workflowName, err)
+			results = append(results, OperationResult{Kind: kindWorkflow, Name: workflowName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
 			continue
 		}
 
@@ -208,26 +310,171 @@ func (g *GHMImpl) AddWorkflowsToRepo(ctx context.Context, targetRepo string, wor
 		err = g.AddWorkflow(ctx, targetRepo, workflowName, workflowContent)
 		if err != nil {
 			g.Logger.Errorf("Error adding workflow '%s' to '%s': %v", workflowName, targetRepo, err)
+			results = append(results, OperationResult{Kind: kindWorkflow, Name: workflowName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
 			continue
 		}
 
-		// Update reposConfig
-		repoConfig, exists := reposConfig.Repositories[targetRepo]
-		if !exists {
-			repoConfig = RepoConfig{
-				Secrets:    []string{},
-				Workflows:  []string{},
-				LastUpdate: time.Now().Format(time.RFC3339),
-			}
+		// Record the item right away so concurrent runs and crashes cannot lose it
+		repoConfig, err := recordRepoChange(g.state(), targetRepo, func(config *RepoConfig) {
+			config.Workflows = appendMissing(config.Workflows, workflowName)
+		})
+		if err != nil {
+			g.Logger.Errorf("Error recording '%s' for '%s': %v", workflowName, targetRepo, err)
+			results = append(results, OperationResult{Kind: kindWorkflow, Name: workflowName, Repo: targetRepo, Action: actionFailed, Error: err.Error()})
+			continue
 		}
-		repoConfig.Workflows = append(repoConfig.Workflows, workflowName)
-		repoConfig.LastUpdate = time.Now().Format(time.RFC3339)
 		reposConfig.Repositories[targetRepo] = repoConfig
 
 		g.Logger.Infof("Workflow '%s' added to repository '%s'.", workflowName, targetRepo)
+		results = append(results, OperationResult{Kind: kindWorkflow, Name: workflowName, Repo: targetRepo, Action: actionAdded})
 	}
 
-	return nil
+	return results, nil
+}
+
+// RemoveWorkflow deletes a workflow file from the GitHub repository
+func (g *GHMImpl) RemoveWorkflow(ctx context.Context, repo, workflowName string) error {
+	strategy := &RemoveWorkflowStrategy{
+		Token:        g.Token,
+		Repo:         repo,
+		WorkflowName: workflowName,
+		Logger:       g.Logger,
+		Ctx:          ctx,
+		State:        g.State,
+	}
+	err := strategy.Execute()
+	g.record(ctx, JournalEntry{Operation: opRemoveWorkflow, Kind: kindWorkflow, Name: workflowName, Repo: repo, Commit: strategy.Commit,
+		Previous: &PreviousState{Exists: true, Content: strategy.PreviousContent, Commit: strategy.BaseCommit}}, err)
+	return err
+}
+
+// RemoveSecret deletes a secret from the GitHub repository
+func (g *GHMImpl) RemoveSecret(ctx context.Context, repo, secretName string) error {
+	strategy := &RemoveSecretStrategy{
+		Token:      g.Token,
+		Repo:       repo,
+		SecretName: secretName,
+		Logger:     g.Logger,
+		Ctx:        ctx,
+		State:      g.State,
+	}
+	err := strategy.Execute()
+	g.record(ctx, JournalEntry{Operation: opRemoveSecret, Kind: kindSecret, Name: secretName, Repo: repo}, err)
+	return err
+}
+
+// ListRepoSecrets lists the names of the secrets set on the GitHub repository
+func (g *GHMImpl) ListRepoSecrets(ctx context.Context, repo string) ([]string, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		secrets, resp, err := client.Actions.ListRepoSecrets(ctx, owner, name, opts)
+		if err != nil {
+			g.Logger.Errorf("Error listing secrets of '%s': %v", repo, err)
+			return nil, err
+		}
+		for _, secret := range secrets.Secrets {
+			names = append(names, secret.Name)
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListRepoWorkflows lists the workflow file names in the GitHub repository
+func (g *GHMImpl) ListRepoWorkflows(ctx context.Context, repo string) ([]string, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		workflows, resp, err := client.Actions.ListWorkflows(ctx, owner, name, opts)
+		if err != nil {
+			g.Logger.Errorf("Error listing workflows of '%s': %v", repo, err)
+			return nil, err
+		}
+		for _, workflow := range workflows.Workflows {
+			names = append(names, filepath.Base(workflow.GetPath()))
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListRepos lists the full names of the repositories the token can access
+func (g *GHMImpl) ListRepos(ctx context.Context) ([]string, error) {
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	opts := &github.RepositoryListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		repos, resp, err := client.Repositories.List(ctx, "", opts)
+		if err != nil {
+			g.Logger.Errorf("Error listing repositories: %v", err)
+			return nil, err
+		}
+		for _, repo := range repos {
+			names = append(names, repo.GetFullName())
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// WorkflowContent reads a workflow file from the default branch of the GitHub repository;
+// found is false when the file does not exist
+func (g *GHMImpl) WorkflowContent(ctx context.Context, repo, workflowName string) (content string, found bool, err error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return "", false, err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return "", false, err
+	}
+
+	file, _, resp, err := client.Repositories.GetContents(ctx, owner, name, ".github/workflows/"+workflowName, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		g.Logger.Errorf("Error reading workflow '%s' of '%s': %v", workflowName, repo, err)
+		return "", false, err
+	}
+	if file == nil {
+		return "", false, fmt.Errorf("'.github/workflows/%s' is a directory", workflowName)
+	}
+	content, err = file.GetContent()
+	if err != nil {
+		g.Logger.Errorf("Error decoding workflow '%s' of '%s': %v", workflowName, repo, err)
+		return "", false, err
+	}
+	return content, true, nil
 }
 
 // AddSecretStrategy defines the parameters for adding a secret
@@ -238,18 +485,32 @@ type AddSecretStrategy struct {
 	SecretValue string
 	Encryptor   Encryptor
 	Logger      *logrus.Logger
+	Ctx         context.Context // cancels the operation and receives its progress; may be nil
+	State       StateProvider   // where the value is saved; the data directory when nil
+	SkipLocal   bool            // push the value without saving it locally
+
+	Created bool // set by Execute when the secret did not exist in the repository before
 }
 
 // Execute adds a secret to a GitHub repository
 func (a *AddSecretStrategy) Execute() error {
-	ctx := context.Background()
+	ctx := operationContext(a.Ctx)
+
+	if err := ValidateSecretName(a.SecretName, secretNameRules()); err != nil {
+		a.Logger.Errorf("%v", err)
+		return err
+	}
+	if err := checkSecretSize(a.SecretValue); err != nil {
+		a.Logger.Errorf("Invalid value for secret '%s': %v", a.SecretName, err)
+		return err
+	}
 
 	// Initialize GitHub client with OAuth2 token
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: a.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client, err := newGitHubClient(ctx, a.Token)
+	if err != nil {
+		a.Logger.Errorf("Error creating GitHub client: %v", err)
+		return err
+	}
 
 	// Split repo into owner and repo
 	parts := strings.Split(a.Repo, "/")
@@ -260,6 +521,7 @@ func (a *AddSecretStrategy) Execute() error {
 	owner, repo := parts[0], parts[1]
 
 	// Fetch repository public key
+	reportProgress(ctx, "Fetching public key", 0)
 	publicKey, _, err := client.Actions.GetRepoPublicKey(ctx, owner, repo)
 	if err != nil {
 		a.Logger.Errorf("Error fetching repository public key: %v", err)
@@ -273,6 +535,7 @@ func (a *AddSecretStrategy) Execute() error {
 	}
 
 	// Encrypt the secret value
+	reportProgress(ctx, "Encrypting secret", 1.0/3)
 	encryptedValue, err := a.Encryptor.Encrypt(a.SecretValue, publicKey)
 	if err != nil {
 		a.Logger.Errorf("Error encrypting secret: %v", err)
@@ -287,21 +550,162 @@ func (a *AddSecretStrategy) Execute() error {
 	}
 
 	// Create or update the secret
-	_, err = client.Actions.CreateOrUpdateRepoSecret(ctx, owner, repo, encryptedSecret)
+	reportProgress(ctx, "Uploading secret", 2.0/3)
+	resp, err := client.Actions.CreateOrUpdateRepoSecret(ctx, owner, repo, encryptedSecret)
 	if err != nil {
 		a.Logger.Errorf("Error setting repository secret: %v", err)
 		return err
 	}
+	// GitHub answers 201 for a new secret and 204 for an updated one
+	a.Created = resp.StatusCode == http.StatusCreated
 
 	a.Logger.Infof("Secret '%s' added to repository '%s' successfully.", a.SecretName, a.Repo)
-	a.Logger.Infof("Secret '%s' saved locally.", a.SecretName)
+	if a.SkipLocal {
+		return nil
+	}
 
 	// Save secret locally for persistence
-	saveSecretLocally(a.SecretName, a.SecretValue, a.Logger)
+	state := a.State
+	if state == nil {
+		state = DataDirState(a.Logger)
+	}
+	saveSecretLocally(state, a.SecretName, a.SecretValue, a.Logger)
 
 	return nil
 }
 
+// RemoveWorkflowStrategy defines the parameters for removing a workflow file
+type RemoveWorkflowStrategy struct {
+	Token        string
+	Repo         string // Format: "owner/repo"
+	WorkflowName string
+	Logger       *logrus.Logger
+	Ctx          context.Context // cancels the operation; may be nil
+	State        StateProvider   // where the repository stops being tracked; the data directory when nil
+
+	// Set by Execute: the file that was removed and the commits before and after
+	PreviousContent string
+	BaseCommit      string
+	Commit          string
+}
+
+// Execute deletes a workflow file from the default branch through the contents API and stops tracking it
+func (r *RemoveWorkflowStrategy) Execute() error {
+	ctx := operationContext(r.Ctx)
+
+	owner, repo, err := splitRepo(r.Repo)
+	if err != nil {
+		r.Logger.Error("Invalid repository format. Use 'owner/repo'.")
+		return err
+	}
+	client, err := newGitHubClient(ctx, r.Token)
+	if err != nil {
+		r.Logger.Errorf("Error creating GitHub client: %v", err)
+		return err
+	}
+
+	path := ".github/workflows/" + r.WorkflowName
+	file, _, _, err := client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	if err != nil {
+		r.Logger.Errorf("Error reading workflow '%s' of '%s': %v", r.WorkflowName, r.Repo, err)
+		return err
+	}
+	if file == nil {
+		return fmt.Errorf("'%s' is a directory", path)
+	}
+	if r.PreviousContent, err = file.GetContent(); err != nil {
+		r.Logger.Errorf("Error decoding workflow '%s' of '%s': %v", r.WorkflowName, r.Repo, err)
+		return err
+	}
+
+	result, _, err := client.Repositories.DeleteFile(ctx, owner, repo, path, &github.RepositoryContentFileOptions{
+		Message: github.String("Remove GitHub Actions workflow"),
+		SHA:     file.SHA,
+	})
+	if err != nil {
+		r.Logger.Errorf("Error removing workflow '%s' from '%s': %v", r.WorkflowName, r.Repo, err)
+		return err
+	}
+	r.Commit = result.GetSHA()
+	if len(result.Parents) > 0 {
+		r.BaseCommit = result.Parents[0].GetSHA()
+	}
+
+	state := r.State
+	if state == nil {
+		state = DataDirState(r.Logger)
+	}
+	_, err = recordRepoChange(state, r.Repo, func(config *RepoConfig) {
+		config.Workflows = removeItem(config.Workflows, r.WorkflowName)
+	})
+	if err != nil {
+		r.Logger.Errorf("Error updating tracked repository: %v", err)
+		return err
+	}
+
+	r.Logger.Infof("Workflow '%s' removed from repository '%s' successfully.", r.WorkflowName, r.Repo)
+	return nil
+}
+
+// RemoveSecretStrategy defines the parameters for removing a secret
+type RemoveSecretStrategy struct {
+	Token      string
+	Repo       string // Format: "owner/repo"
+	SecretName string
+	Logger     *logrus.Logger
+	Ctx        context.Context // cancels the operation; may be nil
+	State      StateProvider   // where the repository stops being tracked; the data directory when nil
+}
+
+// Execute deletes a secret from a GitHub repository and stops tracking it
+func (r *RemoveSecretStrategy) Execute() error {
+	ctx := operationContext(r.Ctx)
+
+	owner, repo, err := splitRepo(r.Repo)
+	if err != nil {
+		r.Logger.Error("Invalid repository format. Use 'owner/repo'.")
+		return err
+	}
+
+	client, err := newGitHubClient(ctx, r.Token)
+	if err != nil {
+		r.Logger.Errorf("Error creating GitHub client: %v", err)
+		return err
+	}
+
+	_, err = client.Actions.DeleteRepoSecret(ctx, owner, repo, r.SecretName)
+	if err != nil {
+		r.Logger.Errorf("Error deleting repository secret: %v", err)
+		return err
+	}
+
+	state := r.State
+	if state == nil {
+		state = DataDirState(r.Logger)
+	}
+	_, err = recordRepoChange(state, r.Repo, func(config *RepoConfig) {
+		config.Secrets = removeItem(config.Secrets, r.SecretName)
+	})
+	if err != nil {
+		r.Logger.Errorf("Error updating tracked repository: %v", err)
+		return err
+	}
+
+	r.Logger.Infof("Secret '%s' removed from repository '%s' successfully.", r.SecretName, r.Repo)
+	return nil
+}
+
+// removeItem returns list without item
+func removeItem(list []string, item string) []string {
+	kept := []string{}
+	for _, existing := range list {
+		if existing != item {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
 // AddWorkflowStrategy defines the parameters for adding a workflow
 type AddWorkflowStrategy struct {
 	Token        string
@@ -309,10 +713,19 @@ type AddWorkflowStrategy struct {
 	WorkflowName string // e.g., "ci.yml"
 	Content      string // YAML content of the workflow
 	Logger       *logrus.Logger
+	Ctx          context.Context // cancels the operation and receives its progress; may be nil
+
+	// Set by Execute: the file that was replaced, if any, and the commits before and after
+	PreviousContent string
+	PreviousExists  bool
+	BaseCommit      string
+	Commit          string
 }
 
 // Execute adds a GitHub Actions workflow to a repository
 func (a *AddWorkflowStrategy) Execute() error {
+	ctx := operationContext(a.Ctx)
+
 	// Split repo into owner and repo
 	parts := strings.Split(a.Repo, "/")
 	if len(parts) != 2 {
@@ -339,10 +752,11 @@ func (a *AddWorkflowStrategy) Execute() error {
 	defer os.RemoveAll(tmpDir) // Clean up after cloning
 
 	a.Logger.Info("Cloning repository into temporary directory...")
+	reportProgress(ctx, "Cloning repository", -1)
 
-	repoGit, err := git.PlainClone(tmpDir, false, &git.CloneOptions{
+	repoGit, err := git.PlainCloneContext(ctx, tmpDir, false, &git.CloneOptions{
 		URL:      repoURL,
-		Progress: os.Stdout,
+		Progress: progressWriter(ctx),
 		Auth:     auth,
 	})
 	if err != nil {
@@ -364,7 +778,14 @@ func (a *AddWorkflowStrategy) Execute() error {
 		return err
 	}
 
+	// Keep what is replaced so that the change can be undone
+	if head, err := repoGit.Head(); err == nil {
+		a.BaseCommit = head.Hash().String()
+	}
 	workflowPath := filepath.Join(workflowDir, a.WorkflowName)
+	if previous, err := ioutil.ReadFile(workflowPath); err == nil {
+		a.PreviousContent, a.PreviousExists = string(previous), true
+	}
 	err = ioutil.WriteFile(workflowPath, []byte(a.Content), 0644)
 	if err != nil {
 		a.Logger.Errorf("Error writing workflow file: %v", err)
@@ -379,6 +800,7 @@ func (a *AddWorkflowStrategy) Execute() error {
 	}
 
 	// Commit the changes
+	reportProgress(ctx, "Committing workflow", -1)
 	commitMsg := "Add GitHub Actions workflow"
 	commit, err := worktree.Commit(commitMsg, &git.CommitOptions{
 		Author: &object.Signature{
@@ -402,14 +824,17 @@ func (a *AddWorkflowStrategy) Execute() error {
 
 	// Push the commit to GitHub
 	a.Logger.Info("Pushing changes to GitHub...")
-	err = repoGit.Push(&git.PushOptions{
-		Auth: auth,
+	reportProgress(ctx, "Pushing to GitHub", -1)
+	err = repoGit.PushContext(ctx, &git.PushOptions{
+		Auth:     auth,
+		Progress: progressWriter(ctx),
 	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		a.Logger.Errorf("Error pushing changes: %v", err)
 		return err
 	}
 
+	a.Commit = commit.String()
 	a.Logger.Infof("Workflow '%s' added to repository '%s' successfully.", a.WorkflowName, a.Repo)
 
 	return nil
@@ -425,12 +850,26 @@ type StoreConfigStrategy struct {
 
 // Execute stores a configuration key-value pair
 func (s *StoreConfigStrategy) Execute() error {
+	// Credentials never go to the plaintext config file
+	if isCredentialKey(s.ConfigKey) {
+		if err := storeCredential(s.ConfigKey, s.ConfigValue, s.Logger); err != nil {
+			return err
+		}
+		s.Logger.Infof("Credential '%s' saved successfully.", s.ConfigKey)
+		return nil
+	}
+
 	viper.Set(s.ConfigKey, s.ConfigValue)
 
 	err := viper.WriteConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			err = viper.SafeWriteConfig() // Attempt to create the file if it does not exist
+			// Create the file in the config directory if it does not exist
+			if err := os.MkdirAll(configDir(), 0700); err != nil {
+				s.Logger.Errorf("Error creating config directory: %v", err)
+				return err
+			}
+			err = viper.SafeWriteConfigAs(configPath(configFileName))
 			if err != nil {
 				s.Logger.Errorf("Error creating config file: %v", err)
 				return err
@@ -444,108 +883,149 @@ func (s *StoreConfigStrategy) Execute() error {
 	return nil
 }
 
+// UnsetConfigStrategy defines the parameters for removing a config key
+type UnsetConfigStrategy struct {
+	ConfigKey string
+	Logger    *logrus.Logger
+}
+
+// Execute removes a configuration key, or deletes the credential it names
+func (s *UnsetConfigStrategy) Execute() error {
+	if isCredentialKey(s.ConfigKey) {
+		if err := storeCredential(s.ConfigKey, "", s.Logger); err != nil {
+			return err
+		}
+		s.Logger.Infof("Credential '%s' removed.", s.ConfigKey)
+		return nil
+	}
+
+	// Drop values set earlier in this process so the key reads as unset
+	viper.Set(s.ConfigKey, nil)
+	if viper.ConfigFileUsed() != "" {
+		if err := removeConfigKeys(s.ConfigKey); err != nil {
+			s.Logger.Errorf("Error writing config: %v", err)
+			return err
+		}
+	}
+	s.Logger.Infof("Configuration '%s' removed.", s.ConfigKey)
+	return nil
+}
+
 // ReposConfig holds the mapping between repositories and their added secrets/workflows
 type ReposConfig struct {
+	Version      int                   `json:"version"`
 	Repositories map[string]RepoConfig `json:"repositories"`
 }
 
 // RepoConfig holds the secrets and workflows added to a repository
 type RepoConfig struct {
-	Secrets    []string `json:"secrets"`
-	Workflows  []string `json:"workflows"`
-	LastUpdate string   `json:"last_update"`
+	Secrets    []string  `json:"secrets"`
+	Workflows  []string  `json:"workflows"`
+	LastUpdate time.Time `json:"last_update"`
 }
 
-// LoadReposConfig loads the repos.json configuration file
+// LoadReposConfig loads the tracked repositories from the state store
 func LoadReposConfig(logger *logrus.Logger) (*ReposConfig, error) {
-	configFile := "repos.json"
+	return LoadReposConfigFrom(DataDirState(logger), logger)
+}
+
+// LoadReposConfigFrom loads the tracked repositories from the store of state
+func LoadReposConfigFrom(state StateProvider, logger *logrus.Logger) (*ReposConfig, error) {
 	reposConfig := &ReposConfig{
+		Version:      reposSchemaVersion,
 		Repositories: make(map[string]RepoConfig),
 	}
 
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		// Create an empty repos.json file
-		file, err := os.Create(configFile)
-		if err != nil {
-			logger.Errorf("Error creating repos.json: %v", err)
-			return nil, err
-		}
-		defer file.Close()
-
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		err = encoder.Encode(reposConfig)
-		if err != nil {
-			logger.Errorf("Error encoding repos.json: %v", err)
-			return nil, err
-		}
-		return reposConfig, nil
-	}
-
-	// Read existing repos.json
-	file, err := os.Open(configFile)
-	if err != nil {
-		logger.Errorf("Error opening repos.json: %v", err)
-		return nil, err
-	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(reposConfig)
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			repos, err := tx.Repos()
+			if err != nil {
+				return err
+			}
+			reposConfig.Repositories = repos
+			return nil
+		})
+	})
 	if err != nil {
-		logger.Errorf("Error decoding repos.json: %v", err)
+		logger.Errorf("Error loading repositories: %v", err)
 		return nil, err
 	}
 
 	return reposConfig, nil
 }
 
-// SaveReposConfig saves the repos.json configuration file
+// SaveReposConfig replaces the tracked repositories in the state store in a single transaction
 func SaveReposConfig(reposConfig *ReposConfig, logger *logrus.Logger) error {
-	configFile := "repos.json"
+	reposConfig.Version = reposSchemaVersion
 
-	file, err := os.Create(configFile)
-	if err != nil {
-		logger.Errorf("Error creating repos.json: %v", err)
-		return err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	err = encoder.Encode(reposConfig)
+	err := withStateStore(logger, func(store *StateStore) error {
+		return store.Update(func(tx *StateTx) error {
+			existing, err := tx.Repos()
+			if err != nil {
+				return err
+			}
+			for repo := range existing {
+				if _, keep := reposConfig.Repositories[repo]; !keep {
+					if err := tx.DeleteRepo(repo); err != nil {
+						return err
+					}
+				}
+			}
+			for repo, config := range reposConfig.Repositories {
+				if err := tx.PutRepo(repo, config); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		logger.Errorf("Error encoding repos.json: %v", err)
+		logger.Errorf("Error saving repositories: %v", err)
 		return err
 	}
 
 	return nil
 }
 
-// getSecretValue retrieves the secret value from secrets.json
-func (g *GHMImpl) getSecretValue(secretName string) (string, error) {
-	secretsFile := "secrets.json"
-	secrets := make(map[string]string)
+// recordRepoChange applies update to a repository record inside a transaction and returns the result
+func recordRepoChange(state StateProvider, repo string, update func(config *RepoConfig)) (RepoConfig, error) {
+	var updated RepoConfig
 
-	if _, err := os.Stat(secretsFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("secrets.json does not exist")
-	}
+	err := state.WithStore(func(store *StateStore) error {
+		return store.Update(func(tx *StateTx) error {
+			config, _, err := tx.Repo(repo)
+			if err != nil {
+				return err
+			}
+			update(&config)
+			config.LastUpdate = time.Now().UTC()
+			updated = config
+			return tx.PutRepo(repo, config)
+		})
+	})
 
-	file, err := os.Open(secretsFile)
-	if err != nil {
-		g.Logger.Errorf("Error opening secrets.json: %v", err)
-		return "", err
-	}
-	defer file.Close()
+	return updated, err
+}
+
+// getSecretValue retrieves the secret value from the state store
+func (g *GHMImpl) getSecretValue(secretName string) (string, error) {
+	return loadSecretValue(g.state(), secretName)
+}
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&secrets)
+// loadSecretValue reads a saved secret value from the state store
+func loadSecretValue(state StateProvider, secretName string) (string, error) {
+	var secretValue string
+	var exists bool
+
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			secretValue, exists = tx.Secret(secretName)
+			return nil
+		})
+	})
 	if err != nil {
-		g.Logger.Errorf("Error decoding secrets.json: %v", err)
 		return "", err
 	}
-
-	secretValue, exists := secrets[secretName]
 	if !exists {
 		return "", fmt.Errorf("secret '%s' not found", secretName)
 	}
@@ -553,30 +1033,20 @@ func (g *GHMImpl) getSecretValue(secretName string) (string, error) {
 	return secretValue, nil
 }
 
-// getWorkflowContent retrieves the workflow content from workflows.json
+// getWorkflowContent retrieves the workflow content from the state store
 func (g *GHMImpl) getWorkflowContent(workflowName string) (string, error) {
-	workflowsFile := "workflows.json"
-	workflows := make(map[string]string)
-
-	if _, err := os.Stat(workflowsFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("workflows.json does not exist")
-	}
-
-	file, err := os.Open(workflowsFile)
-	if err != nil {
-		g.Logger.Errorf("Error opening workflows.json: %v", err)
-		return "", err
-	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&workflows)
+	var workflowContent string
+	var exists bool
+
+	err := g.state().WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			workflowContent, exists = tx.Workflow(workflowName)
+			return nil
+		})
+	})
 	if err != nil {
-		g.Logger.Errorf("Error decoding workflows.json: %v", err)
 		return "", err
 	}
-
-	workflowContent, exists := workflows[workflowName]
 	if !exists {
 		return "", fmt.Errorf("workflow '%s' not found", workflowName)
 	}
@@ -584,43 +1054,18 @@ func (g *GHMImpl) getWorkflowContent(workflowName string) (string, error) {
 	return workflowContent, nil
 }
 
-// saveSecretLocally saves the secret in a local JSON file for persistence
-func saveSecretLocally(secretName, secretValue string, logger *logrus.Logger) {
-	secretsFile := "secrets.json"
-	secrets := make(map[string]string)
-
-	// Check if secrets.json exists
-	if _, err := os.Stat(secretsFile); err == nil {
-		file, err := os.Open(secretsFile)
-		if err == nil {
-			defer file.Close()
-			jsonDecoder := json.NewDecoder(file)
-			err = jsonDecoder.Decode(&secrets)
-			if err != nil {
-				logger.Errorf("Error decoding secrets file: %v", err)
-				return
-			}
-		} else {
-			logger.Errorf("Error opening secrets file: %v", err)
-			return
-		}
-	}
-
-	secrets[secretName] = secretValue
-
-	file, err := os.Create(secretsFile)
+// saveSecretLocally saves the secret in the state store for persistence
+func saveSecretLocally(state StateProvider, secretName, secretValue string, logger *logrus.Logger) {
+	err := state.WithStore(func(store *StateStore) error {
+		warnIfInGitWorktree(store.Path, logger)
+		return store.Update(func(tx *StateTx) error {
+			return tx.PutSecret(secretName, secretValue)
+		})
+	})
 	if err != nil {
 		logger.Errorf("Error saving secret locally: %v", err)
 		return
 	}
-	defer file.Close()
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	err = encoder.Encode(secrets)
-	if err != nil {
-		logger.Errorf("Error encoding secrets: %v", err)
-		return
-	}
 
 	logger.Infof("Secret '%s' saved locally.", secretName)
-}
\ No newline at end of file
+}
@@ -0,0 +1,139 @@
+// interactive.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// nonInteractive is set by the --non-interactive flag
+var nonInteractive bool
+
+// isInteractive reports whether ghm may prompt. Prompts are disabled with --non-interactive
+// and whenever stdin is not a terminal, such as in CI.
+func isInteractive() bool {
+	return !nonInteractive && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// SelectItems picks items from available by exact name, by glob pattern, or all of them.
+// The selection keeps the order of available; unknown names and patterns that match nothing are errors.
+func SelectItems(available, names []string, all bool, patterns []string) ([]string, error) {
+	if all {
+		return available, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		if !contains(available, name) {
+			return nil, fmt.Errorf("'%s' not found", name)
+		}
+		selected[name] = true
+	}
+	for _, pattern := range patterns {
+		matched := false
+		for _, item := range available {
+			ok, err := path.Match(pattern, item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+			if ok {
+				selected[item] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("pattern '%s' matches nothing", pattern)
+		}
+	}
+
+	items := []string{}
+	for _, item := range available {
+		if selected[item] {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// selectSavedItems selects saved secrets or workflows from the selection flags, showing
+// the picker only when none were given and prompts are allowed
+func selectSavedItems(kind, flag string, available, names []string, all bool, patterns []string, reposConfig *ReposConfig, logger *logrus.Logger) ([]string, error) {
+	if all || len(names) > 0 || len(patterns) > 0 {
+		selected, err := SelectItems(available, names, all, patterns)
+		if err != nil {
+			logger.Errorf("Error selecting saved %ss: %v", kind, err)
+			return nil, fmt.Errorf("selecting saved %ss: %w", kind, err)
+		}
+		return selected, nil
+	}
+
+	if !isInteractive() {
+		return nil, fmt.Errorf("no %ss selected; use --%s, --all or --match in non-interactive mode", kind, flag)
+	}
+	items, err := savedItemPreviews(DataDirState(logger), kind, available, reposConfig)
+	if err != nil {
+		return nil, err
+	}
+	return runPicker(fmt.Sprintf("Select %ss to add", kind), items, logger)
+}
+
+// savedItemPreviews describes saved items for the picker: where they are used and, for
+// workflows, what they contain. Secret values are never read.
+func savedItemPreviews(state StateProvider, kind string, names []string, reposConfig *ReposConfig) ([]PickerItem, error) {
+	var workflows map[string]string
+	if kind == kindWorkflow {
+		err := state.WithStore(func(store *StateStore) error {
+			return store.View(func(tx *StateTx) error {
+				workflows = tx.Workflows()
+				return nil
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]PickerItem, 0, len(names))
+	for _, name := range names {
+		var repos []string
+		for repo, config := range reposConfig.Repositories {
+			used := config.Secrets
+			if kind == kindWorkflow {
+				used = config.Workflows
+			}
+			if contains(used, name) {
+				repos = append(repos, repo)
+			}
+		}
+		sort.Strings(repos)
+
+		preview := []string{fmt.Sprintf("Used in %d repositories", len(repos))}
+		for _, repo := range repos {
+			preview = append(preview, "  "+repo)
+		}
+		if content, exists := workflows[name]; exists {
+			preview = append(preview, fmt.Sprintf("%d lines", strings.Count(content, "\n")+1))
+			if title := workflowTitle(content); title != "" {
+				preview = append(preview, "name: "+title)
+			}
+		}
+		items = append(items, PickerItem{Name: name, Preview: preview})
+	}
+	return items, nil
+}
+
+// workflowTitle returns the top-level name of a workflow file
+func workflowTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "name:") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "name:")), `"'`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,394 @@
+// journal.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/manifoldco/promptui"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Operations on workflow runs and the removal of a workflow by an undo; the others share
+// the names of the batch ops
+const (
+	opRerunRun       = "rerun-run"
+	opCancelRun      = "cancel-run"
+	opRemoveWorkflow = "remove-workflow"
+)
+
+// Outcomes of a journaled operation
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+)
+
+// journalLimit is the number of operations kept in the journal
+const journalLimit = 1000
+
+// ErrWorkflowChanged is returned when a workflow is undone after it was changed again on GitHub
+var ErrWorkflowChanged = errors.New("workflow changed since it was pushed")
+
+// PreviousState is what an operation replaced, as far as it can be known: GitHub never
+// returns secret values, so only the existence of a secret is recorded
+type PreviousState struct {
+	Exists  bool   `json:"exists"`
+	Content string `json:"content,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// JournalEntry records an operation ghm made on GitHub
+type JournalEntry struct {
+	ID        uint64         `json:"id"`
+	Time      time.Time      `json:"time"`
+	User      string         `json:"user"`
+	Operation string         `json:"operation"`
+	Kind      string         `json:"kind"`
+	Name      string         `json:"name"`
+	Repo      string         `json:"repo"`
+	Outcome   string         `json:"outcome"`
+	Error     string         `json:"error,omitempty"`
+	Previous  *PreviousState `json:"previous,omitempty"`
+	Commit    string         `json:"commit,omitempty"`    // workflows only: the commit that was pushed
+	FileSHA   string         `json:"file_sha,omitempty"`  // workflows only: the blob SHA of the file that was pushed
+	UndoneAt  *time.Time     `json:"undone_at,omitempty"` // set once the operation has been undone
+	Undoes    uint64         `json:"undoes,omitempty"`    // the entry this operation undid
+}
+
+// JournalEntries is the result of the history command
+type JournalEntries []JournalEntry
+
+func (r JournalEntries) tableHeader() []string {
+	return []string{"ID", "TIME", "USER", "OPERATION", "REPO", "NAME", "OUTCOME", "UNDO"}
+}
+
+func (r JournalEntries) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, entry := range r {
+		outcome := entry.Outcome
+		if entry.Error != "" {
+			outcome += ": " + entry.Error
+		}
+		rows = append(rows, []string{strconv.FormatUint(entry.ID, 10), entry.Time.Format(time.RFC3339), entry.User,
+			entry.Operation, entry.Repo, entry.Name, outcome, entry.UndoStatus()})
+	}
+	return rows
+}
+
+// Undo describes how the operation can be undone, or returns why it cannot. A workflow
+// file that replaced another is restored and a new one is removed; a secret that did not
+// exist before is removed.
+func (e JournalEntry) Undo() (string, error) {
+	switch {
+	case e.UndoneAt != nil:
+		return "", fmt.Errorf("operation %d was already undone", e.ID)
+	case e.Outcome != outcomeSucceeded:
+		return "", fmt.Errorf("operation %d did not succeed", e.ID)
+	}
+
+	switch e.Operation {
+	case opAddSecret:
+		if e.Previous == nil || e.Previous.Exists {
+			return "", fmt.Errorf("the previous value of secret '%s' is unknown", e.Name)
+		}
+		return fmt.Sprintf("remove secret '%s' from '%s'", e.Name, e.Repo), nil
+	case opAddWorkflow:
+		if e.Previous == nil {
+			return "", fmt.Errorf("the previous state of workflow '%s' is unknown", e.Name)
+		}
+		if !e.Previous.Exists {
+			return fmt.Sprintf("remove workflow '%s' from '%s'", e.Name, e.Repo), nil
+		}
+		return fmt.Sprintf("restore workflow '%s' in '%s' from commit %s", e.Name, e.Repo, shortCommit(e.Previous.Commit)), nil
+	}
+	return "", fmt.Errorf("%s cannot be undone", e.Operation)
+}
+
+// undoAction is the action of the result of undoing the operation
+func (e JournalEntry) undoAction() string {
+	if e.Operation == opAddWorkflow && e.Previous != nil && e.Previous.Exists {
+		return actionRestored
+	}
+	return actionRemoved
+}
+
+// UndoStatus summarises whether the operation can be undone, for tables and the TUI
+func (e JournalEntry) UndoStatus() string {
+	if e.UndoneAt != nil {
+		return "undone"
+	}
+	if e.Undoes != 0 {
+		return fmt.Sprintf("undoes %d", e.Undoes)
+	}
+	if _, err := e.Undo(); err != nil {
+		return ""
+	}
+	return "available"
+}
+
+// workflowSHA returns the git blob SHA of a workflow file, as GitHub reports it
+func workflowSHA(content string) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, []byte(content)).String()
+}
+
+// shortCommit abbreviates a commit SHA
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// LoadJournal returns the journal, newest first, limited to a repository when repo is set
+// and to limit entries when limit is positive
+func LoadJournal(state StateProvider, repo string, limit int) (JournalEntries, error) {
+	var all JournalEntries
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			var err error
+			all, err = tx.Journal()
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := JournalEntries{}
+	for i := len(all) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		if repo == "" || all[i].Repo == repo {
+			entries = append(entries, all[i])
+		}
+	}
+	return entries, nil
+}
+
+// LoadJournalEntry returns the journal entry with the ID
+func LoadJournalEntry(state StateProvider, id uint64) (JournalEntry, error) {
+	var entry JournalEntry
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			var found bool
+			var err error
+			entry, found, err = tx.JournalEntry(id)
+			if err == nil && !found {
+				err = fmt.Errorf("operation %d not found", id)
+			}
+			return err
+		})
+	})
+	return entry, err
+}
+
+// UndoJournalEntry undoes the operation with the ID and marks it as undone. The undoing
+// operation is journaled in turn, pointing back at the entry. A workflow that changed on
+// GitHub since it was pushed is only restored with force.
+func UndoJournalEntry(ctx context.Context, ghm GHM, state StateProvider, id uint64, force bool) (JournalEntry, error) {
+	entry, err := LoadJournalEntry(state, id)
+	if err != nil {
+		return entry, err
+	}
+	if _, err := entry.Undo(); err != nil {
+		return entry, err
+	}
+	if entry.Operation == opAddWorkflow && !force {
+		if err := checkWorkflowUnchanged(ctx, ghm, entry); err != nil {
+			return entry, err
+		}
+	}
+
+	ctx = withUndo(ctx, id)
+	switch {
+	case entry.Operation == opAddSecret:
+		err = ghm.RemoveSecret(ctx, entry.Repo, entry.Name)
+	case entry.Previous.Exists:
+		err = ghm.AddWorkflow(ctx, entry.Repo, entry.Name, entry.Previous.Content)
+	default:
+		err = ghm.RemoveWorkflow(ctx, entry.Repo, entry.Name)
+	}
+	if err != nil {
+		return entry, err
+	}
+
+	now := time.Now()
+	entry.UndoneAt = &now
+	err = state.WithStore(func(store *StateStore) error {
+		return store.Update(func(tx *StateTx) error {
+			return tx.PutJournalEntry(entry)
+		})
+	})
+	return entry, err
+}
+
+// checkWorkflowUnchanged returns ErrWorkflowChanged unless the workflow on GitHub is still
+// the file the operation pushed
+func checkWorkflowUnchanged(ctx context.Context, ghm GHM, entry JournalEntry) error {
+	if entry.FileSHA == "" {
+		return fmt.Errorf("%w: the pushed file of operation %d was not recorded", ErrWorkflowChanged, entry.ID)
+	}
+	content, found, err := ghm.WorkflowContent(ctx, entry.Repo, entry.Name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: workflow '%s' was removed from '%s'", ErrWorkflowChanged, entry.Name, entry.Repo)
+	}
+	if sha := workflowSHA(content); sha != entry.FileSHA {
+		return fmt.Errorf("%w: workflow '%s' in '%s' is %s, operation %d pushed %s",
+			ErrWorkflowChanged, entry.Name, entry.Repo, shortCommit(sha), entry.ID, shortCommit(entry.FileSHA))
+	}
+	return nil
+}
+
+// undoKey is the context key of the journal entry being undone
+type undoKey struct{}
+
+// withUndo marks the operations run with ctx as undoing the journal entry
+func withUndo(ctx context.Context, id uint64) context.Context {
+	return context.WithValue(ctx, undoKey{}, id)
+}
+
+// undoOf returns the journal entry that the operations run with ctx undo, or 0
+func undoOf(ctx context.Context) uint64 {
+	if ctx == nil {
+		return 0
+	}
+	id, _ := ctx.Value(undoKey{}).(uint64)
+	return id
+}
+
+// record journals an operation and its outcome. A journal that cannot be written must not
+// fail an operation that already happened on GitHub, so errors are only logged.
+func (g *GHMImpl) record(ctx context.Context, entry JournalEntry, err error) {
+	entry.Time = time.Now()
+	entry.User = journalUser()
+	entry.Undoes = undoOf(ctx)
+	entry.Outcome = outcomeSucceeded
+	if err != nil {
+		entry.Outcome, entry.Error = outcomeFailed, err.Error()
+		entry.Previous, entry.Commit, entry.FileSHA = nil, "", ""
+	}
+
+	err = g.state().WithStore(func(store *StateStore) error {
+		return store.Update(func(tx *StateTx) error {
+			_, err := tx.AppendJournal(entry)
+			return err
+		})
+	})
+	if err != nil {
+		g.Logger.Warnf("Could not record %s of '%s' in the journal: %v", entry.Operation, entry.Name, err)
+	}
+}
+
+// journalUser returns the name of the local user running ghm
+func journalUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Initialize History Command
+func initHistoryCmd(logger *logrus.Logger) *cobra.Command {
+	var repo string
+	var limit int
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the operations ghm made on GitHub",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := LoadJournal(DataDirState(logger), repo, limit)
+			if err != nil {
+				logger.Errorf("Error loading the journal: %v", err)
+				return err
+			}
+
+			if len(entries) == 0 {
+				logger.Info("No operations recorded.")
+			}
+			return printResult(cmd, entries)
+		},
+	}
+
+	historyCmd.Flags().StringVarP(&repo, "repo", "r", "", "Only show operations on this repository")
+	historyCmd.Flags().IntVarP(&limit, "limit", "n", 20, "Show at most this many operations; 0 shows all")
+
+	historyCmd.RegisterFlagCompletionFunc("repo", completeRepos)
+
+	historyCmd.AddCommand(initHistoryUndoCmd(logger))
+
+	return historyCmd
+}
+
+// Initialize History Undo Command
+func initHistoryUndoCmd(logger *logrus.Logger) *cobra.Command {
+	var yes, force bool
+
+	undoCmd := &cobra.Command{
+		Use:   "undo ID",
+		Short: "Undo an operation: restore the previous workflow file or remove a newly added workflow or secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				logger.Errorf("Invalid operation ID '%s'.", args[0])
+				return fmt.Errorf("invalid operation id '%s'", args[0])
+			}
+
+			state := DataDirState(logger)
+			entry, err := LoadJournalEntry(state, id)
+			if err != nil {
+				logger.Errorf("%v", err)
+				return err
+			}
+			description, err := entry.Undo()
+			if err != nil {
+				logger.Errorf("%v", err)
+				return err
+			}
+
+			if !yes {
+				if !isInteractive() {
+					logger.Error("Undoing an operation must be confirmed.")
+					return fmt.Errorf("confirmation required; use --yes")
+				}
+				prompt := promptui.Prompt{
+					Label:     "Undo: " + description,
+					IsConfirm: true,
+					Stdout:    os.Stderr,
+				}
+				if _, err := prompt.Run(); err != nil {
+					logger.Info("Nothing was undone.")
+					return nil
+				}
+			}
+
+			ghm, err := requireGHM(logger)
+			if err != nil {
+				return err
+			}
+			if _, err := UndoJournalEntry(context.Background(), ghm, state, id, force); err != nil {
+				logger.Errorf("Error undoing operation %d: %v", id, err)
+				if errors.Is(err, ErrWorkflowChanged) {
+					logger.Error("Nothing was restored; pass --force to overwrite the current file.")
+				}
+				return err
+			}
+			logger.Infof("Operation %d undone.", id)
+
+			return printResult(cmd, OperationResult{Kind: entry.Kind, Name: entry.Name, Repo: entry.Repo, Action: entry.undoAction()})
+		},
+	}
+
+	undoCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	undoCmd.Flags().BoolVar(&force, "force", false, "Restore a workflow even if it changed on GitHub since it was pushed")
+
+	return undoCmd
+}
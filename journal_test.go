@@ -0,0 +1,286 @@
+// journal_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *fakeGHM) RemoveSecret(ctx context.Context, repo, name string) error {
+	f.calls = append(f.calls, fmt.Sprintf("remove-secret %s %s", repo, name))
+	if f.fail[name] {
+		return fmt.Errorf("forbidden")
+	}
+	return nil
+}
+
+func (f *fakeGHM) RemoveWorkflow(ctx context.Context, repo, name string) error {
+	f.calls = append(f.calls, fmt.Sprintf("remove-workflow %s %s", repo, name))
+	if f.fail[name] {
+		return fmt.Errorf("forbidden")
+	}
+	return nil
+}
+
+// openJournalTestStore opens a state store with the journaled operations
+func openJournalTestStore(t *testing.T, entries ...JournalEntry) *StateStore {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	require.NoError(t, store.Update(func(tx *StateTx) error {
+		for _, entry := range entries {
+			if _, err := tx.AppendJournal(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	return store
+}
+
+// TestLoadJournal tests the order, repository filter and limit of the journal
+func TestLoadJournal(t *testing.T) {
+	store := openJournalTestStore(t,
+		JournalEntry{Operation: "add-secret", Name: "API_KEY", Repo: "acme/api"},
+		JournalEntry{Operation: "add-workflow", Name: "ci.yml", Repo: "acme/web"},
+		JournalEntry{Operation: "remove-secret", Name: "API_KEY", Repo: "acme/api"},
+	)
+
+	entries, err := LoadJournal(store, "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, uint64(3), entries[0].ID)
+	assert.Equal(t, "remove-secret", entries[0].Operation)
+	assert.Equal(t, uint64(1), entries[2].ID)
+
+	entries, err = LoadJournal(store, "acme/api", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(3), entries[0].ID)
+
+	entries, err = LoadJournal(store, "acme/gone", 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestAppendJournalPrunes tests that only the most recent 1000 operations are kept
+func TestAppendJournalPrunes(t *testing.T) {
+	store := openJournalTestStore(t)
+	require.NoError(t, store.Update(func(tx *StateTx) error {
+		for i := 0; i < 1005; i++ {
+			if _, err := tx.AppendJournal(JournalEntry{Operation: "add-secret"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	entries, err := LoadJournal(store, "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1000)
+	assert.Equal(t, uint64(1005), entries[0].ID)
+	assert.Equal(t, uint64(6), entries[999].ID)
+}
+
+// TestJournalEntryUndo tests which operations can be undone
+func TestJournalEntryUndo(t *testing.T) {
+	undone := time.Now()
+	tests := []struct {
+		name    string
+		entry   JournalEntry
+		undo    string
+		wantErr string
+	}{
+		{"new secret", JournalEntry{ID: 1, Operation: "add-secret", Name: "API_KEY", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{}}, "remove secret 'API_KEY' from 'acme/api'", ""},
+		{"updated secret", JournalEntry{ID: 2, Operation: "add-secret", Name: "API_KEY", Outcome: "succeeded",
+			Previous: &PreviousState{Exists: true}}, "", "the previous value of secret 'API_KEY' is unknown"},
+		{"replaced workflow", JournalEntry{ID: 3, Operation: "add-workflow", Name: "ci.yml", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{Exists: true, Content: "name: CI\n", Commit: "0123456789abcdef"}},
+			"restore workflow 'ci.yml' in 'acme/api' from commit 0123456", ""},
+		{"new workflow", JournalEntry{ID: 4, Operation: "add-workflow", Name: "ci.yml", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{}}, "remove workflow 'ci.yml' from 'acme/api'", ""},
+		{"failed", JournalEntry{ID: 5, Operation: "add-secret", Outcome: "failed"}, "", "operation 5 did not succeed"},
+		{"undone", JournalEntry{ID: 6, Operation: "add-secret", Outcome: "succeeded", Previous: &PreviousState{},
+			UndoneAt: &undone}, "", "operation 6 was already undone"},
+		{"removal", JournalEntry{ID: 7, Operation: "remove-secret", Outcome: "succeeded"}, "", "remove-secret cannot be undone"},
+		{"rerun", JournalEntry{ID: 8, Operation: "rerun-run", Outcome: "succeeded"}, "", "rerun-run cannot be undone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			undo, err := tt.entry.Undo()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.NotEqual(t, "available", tt.entry.UndoStatus())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.undo, undo)
+			assert.Equal(t, "available", tt.entry.UndoStatus())
+		})
+	}
+}
+
+// TestUndoJournalEntry tests removing a new secret, restoring a workflow and marking both as undone
+func TestUndoJournalEntry(t *testing.T) {
+	store := openJournalTestStore(t,
+		JournalEntry{Operation: "add-secret", Kind: "secret", Name: "API_KEY", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{}},
+		JournalEntry{Operation: "add-workflow", Kind: "workflow", Name: "ci.yml", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{Exists: true, Content: "name: CI\n"}, FileSHA: workflowSHA("name: CI v2\n")},
+		JournalEntry{Operation: "add-secret", Kind: "secret", Name: "DB_PASS", Repo: "acme/api", Outcome: "succeeded",
+			Previous: &PreviousState{}},
+	)
+	ghm := &fakeGHM{fail: map[string]bool{"DB_PASS": true}, workflows: map[string]string{"acme/api/ci.yml": "name: CI v2\n"}}
+
+	entry, err := UndoJournalEntry(context.Background(), ghm, store, 1, false)
+	require.NoError(t, err)
+	assert.NotNil(t, entry.UndoneAt)
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 2, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remove-secret acme/api API_KEY", "add-workflow acme/api ci.yml"}, ghm.calls)
+
+	// Undone operations are saved as such and cannot be undone again
+	entries, err := LoadJournal(store, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "undone", entries[2].UndoStatus())
+	assert.Equal(t, "undone", entries[1].UndoStatus())
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 1, false)
+	assert.EqualError(t, err, "operation 1 was already undone")
+
+	// A failed undo leaves the operation undoable
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 3, false)
+	assert.EqualError(t, err, "forbidden")
+	entries, err = LoadJournal(store, "", 1)
+	require.NoError(t, err)
+	assert.Nil(t, entries[0].UndoneAt)
+
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 9, false)
+	assert.EqualError(t, err, "operation 9 not found")
+}
+
+// TestUndoChangedWorkflow tests that a workflow changed or removed since it was pushed is
+// only restored with force
+func TestUndoChangedWorkflow(t *testing.T) {
+	pushed := JournalEntry{Operation: "add-workflow", Kind: "workflow", Name: "ci.yml", Repo: "acme/api", Outcome: "succeeded",
+		Previous: &PreviousState{Exists: true, Content: "name: CI\n"}, FileSHA: workflowSHA("name: CI v2\n")}
+	unrecorded := pushed
+	unrecorded.FileSHA = ""
+	store := openJournalTestStore(t, pushed, unrecorded)
+
+	ghm := &fakeGHM{workflows: map[string]string{"acme/api/ci.yml": "name: CI v3\n"}}
+	_, err := UndoJournalEntry(context.Background(), ghm, store, 1, false)
+	assert.ErrorIs(t, err, ErrWorkflowChanged)
+	assert.Empty(t, ghm.calls)
+
+	ghm.workflows = map[string]string{}
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 1, false)
+	assert.ErrorIs(t, err, ErrWorkflowChanged)
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 2, false)
+	assert.ErrorIs(t, err, ErrWorkflowChanged)
+	assert.Empty(t, ghm.calls)
+
+	// The change was refused, so the operation can still be undone with force
+	entry, err := UndoJournalEntry(context.Background(), ghm, store, 1, true)
+	require.NoError(t, err)
+	assert.NotNil(t, entry.UndoneAt)
+	assert.Equal(t, []string{"add-workflow acme/api ci.yml"}, ghm.calls)
+
+	// Without the SHA of the pushed file the undo is refused before GitHub is asked
+	ghm.fail = map[string]bool{"acme/api": true}
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 2, false)
+	assert.EqualError(t, err, "workflow changed since it was pushed: the pushed file of operation 2 was not recorded")
+}
+
+// TestUndoNewWorkflow tests that a workflow the operation added is removed, unless it was
+// changed since it was pushed and the undo is not forced
+func TestUndoNewWorkflow(t *testing.T) {
+	pushed := JournalEntry{Operation: "add-workflow", Kind: "workflow", Name: "ci.yml", Repo: "acme/api", Outcome: "succeeded",
+		Previous: &PreviousState{}, FileSHA: workflowSHA("name: CI\n")}
+	store := openJournalTestStore(t, pushed, pushed)
+
+	ghm := &fakeGHM{workflows: map[string]string{"acme/api/ci.yml": "name: CI\n"}}
+	entry, err := UndoJournalEntry(context.Background(), ghm, store, 1, false)
+	require.NoError(t, err)
+	assert.NotNil(t, entry.UndoneAt)
+	assert.Equal(t, actionRemoved, entry.undoAction())
+	assert.Equal(t, []string{"remove-workflow acme/api ci.yml"}, ghm.calls)
+
+	ghm = &fakeGHM{workflows: map[string]string{"acme/api/ci.yml": "name: CI v2\n"}}
+	_, err = UndoJournalEntry(context.Background(), ghm, store, 2, false)
+	assert.ErrorIs(t, err, ErrWorkflowChanged)
+	assert.Empty(t, ghm.calls)
+
+	entry, err = UndoJournalEntry(context.Background(), ghm, store, 2, true)
+	require.NoError(t, err)
+	assert.NotNil(t, entry.UndoneAt)
+	assert.Equal(t, []string{"remove-workflow acme/api ci.yml"}, ghm.calls)
+}
+
+// TestRemoveWorkflow tests that GHMImpl deletes the workflow file it read through the
+// contents API and journals the removed content
+func TestRemoveWorkflow(t *testing.T) {
+	store := openJournalTestStore(t)
+	require.NoError(t, store.Update(func(tx *StateTx) error {
+		return tx.PutRepo("acme/api", RepoConfig{Workflows: []string{"ci.yml", "lint.yml"}})
+	}))
+
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/api/contents/.github/workflows/ci.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q, "sha": "blob1"}`,
+				base64.StdEncoding.EncodeToString([]byte("name: CI\n")))
+		case http.MethodDelete:
+			var opts struct{ SHA string }
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&opts))
+			deleted = opts.SHA
+			fmt.Fprint(w, `{"content": null, "commit": {"sha": "commit2", "parents": [{"sha": "commit1"}]}}`)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	ghm := &GHMImpl{Token: "ghp_test", Logger: logger, State: store}
+	require.NoError(t, ghm.RemoveWorkflow(withUndo(context.Background(), 7), "acme/api", "ci.yml"))
+	assert.Equal(t, "blob1", deleted)
+
+	entries, err := LoadJournal(store, "", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, opRemoveWorkflow, entries[0].Operation)
+	assert.Equal(t, "commit2", entries[0].Commit)
+	assert.Equal(t, &PreviousState{Exists: true, Content: "name: CI\n", Commit: "commit1"}, entries[0].Previous)
+	_, err = entries[0].Undo()
+	assert.EqualError(t, err, "remove-workflow cannot be undone")
+
+	require.NoError(t, store.View(func(tx *StateTx) error {
+		config, _, err := tx.Repo("acme/api")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"lint.yml"}, config.Workflows)
+		return nil
+	}))
+}
@@ -0,0 +1,264 @@
+// jsonpath.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathNode is a piece of a --output jsonpath template: literal text, a path, or a range block
+type jsonPathNode struct {
+	text    string
+	path    []jsonPathStep
+	isPath  bool
+	isRange bool
+	body    []jsonPathNode
+}
+
+// jsonPathStep selects a field, an index or all elements of the current values
+type jsonPathStep struct {
+	field string
+	index int
+	all   bool
+	isKey bool
+}
+
+// parseJSONPathTemplate parses a kubectl-style template such as
+// '{range [*]}{.name}{"\n"}{end}'. Paths support .field, ['field'], [n] and [*].
+func parseJSONPathTemplate(template string) ([]jsonPathNode, error) {
+	root := []jsonPathNode{}
+	stack := []*[]jsonPathNode{&root}
+	current := func() *[]jsonPathNode { return stack[len(stack)-1] }
+
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			*current() = append(*current(), jsonPathNode{text: template})
+			break
+		}
+		if start > 0 {
+			*current() = append(*current(), jsonPathNode{text: template[:start]})
+		}
+
+		end := closingBrace(template, start)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed '{' in jsonpath template")
+		}
+		expr := strings.TrimSpace(template[start+1 : end])
+		template = template[end+1:]
+
+		switch {
+		case strings.HasPrefix(expr, `"`):
+			text, err := strconv.Unquote(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string literal %s in jsonpath template", expr)
+			}
+			*current() = append(*current(), jsonPathNode{text: text})
+
+		case expr == "end":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("'{end}' without '{range}' in jsonpath template")
+			}
+			stack = stack[:len(stack)-1]
+
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			nodes := current()
+			*nodes = append(*nodes, jsonPathNode{path: path, isRange: true})
+			stack = append(stack, &(*nodes)[len(*nodes)-1].body)
+
+		default:
+			path, err := parseJSONPath(expr)
+			if err != nil {
+				return nil, err
+			}
+			*current() = append(*current(), jsonPathNode{path: path, isPath: true})
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("'{range}' without '{end}' in jsonpath template")
+	}
+	return root, nil
+}
+
+// closingBrace returns the index of the '}' closing the '{' at start, skipping string literals
+func closingBrace(template string, start int) int {
+	inString := false
+	for i := start + 1; i < len(template); i++ {
+		switch template[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '}':
+			if !inString {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseJSONPath parses a path such as .repos[0].name; a leading $ or @ refers to the current value
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	i := 0
+	if strings.HasPrefix(expr, "$") || strings.HasPrefix(expr, "@") {
+		i = 1
+	}
+
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			j := i + 1
+			for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+				j++
+			}
+			if j > i+1 {
+				steps = append(steps, jsonPathStep{field: expr[i+1 : j], isKey: true})
+			}
+			i = j
+
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' in jsonpath '%s'", expr)
+			}
+			inner := strings.TrimSpace(expr[i+1 : i+end])
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{all: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{field: inner[1 : len(inner)-1], isKey: true})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index '%s' in jsonpath '%s'", inner, expr)
+				}
+				steps = append(steps, jsonPathStep{index: index})
+			}
+			i += end + 1
+
+		default:
+			return nil, fmt.Errorf("unexpected '%c' in jsonpath '%s'", expr[i], expr)
+		}
+	}
+
+	return steps, nil
+}
+
+// evalJSONPath applies the steps to value. A field that none of the current values has is an
+// error, as in kubectl, so that a mistyped path does not print nothing.
+func evalJSONPath(steps []jsonPathStep, value interface{}) ([]interface{}, error) {
+	values := []interface{}{value}
+
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range values {
+			switch {
+			case step.isKey:
+				if m, ok := v.(map[string]interface{}); ok {
+					if field, exists := m[step.field]; exists {
+						next = append(next, field)
+					}
+				}
+
+			case step.all:
+				switch collection := v.(type) {
+				case []interface{}:
+					next = append(next, collection...)
+				case map[string]interface{}:
+					keys := make([]string, 0, len(collection))
+					for key := range collection {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						next = append(next, collection[key])
+					}
+				}
+
+			default:
+				list, ok := v.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("cannot index a non-array value with [%d]", step.index)
+				}
+				index := step.index
+				if index < 0 {
+					index += len(list)
+				}
+				if index < 0 || index >= len(list) {
+					return nil, fmt.Errorf("index [%d] out of range", step.index)
+				}
+				next = append(next, list[index])
+			}
+		}
+		if step.isKey && len(values) > 0 && len(next) == 0 {
+			return nil, fmt.Errorf("%s is not found", step.field)
+		}
+		values = next
+	}
+
+	return values, nil
+}
+
+// executeJSONPathTemplate writes the template evaluated against value to w
+func executeJSONPathTemplate(w io.Writer, nodes []jsonPathNode, value interface{}) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			items, err := evalJSONPath(node.path, value)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := executeJSONPathTemplate(w, node.body, item); err != nil {
+					return err
+				}
+			}
+
+		case node.isPath:
+			results, err := evalJSONPath(node.path, value)
+			if err != nil {
+				return err
+			}
+			texts := make([]string, 0, len(results))
+			for _, result := range results {
+				texts = append(texts, jsonPathText(result))
+			}
+			if _, err := io.WriteString(w, strings.Join(texts, " ")); err != nil {
+				return err
+			}
+
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPathText prints strings and numbers as-is and everything else as JSON
+func jsonPathText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
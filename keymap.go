@@ -0,0 +1,216 @@
+// keymap.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
+)
+
+// Actions of the TUI that can be bound to keys with the keys.<action> settings.
+// Forms and the picker keep their own keys, since they take typed text.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPrevTab   = "prev_tab"
+	keyNextTab   = "next_tab"
+	keySelect    = "select"
+	keyBack      = "back"
+	keyQuit      = "quit"
+	keyCancel    = "cancel"
+	keyToggleLog = "toggle_log"
+	keyLogUp     = "log_up"
+	keyLogDown   = "log_down"
+	keyNew       = "new"
+	keyEdit      = "edit"
+	keyReveal    = "reveal"
+	keyConfirm   = "confirm"
+	keyDeny      = "deny"
+	keyFilter    = "filter"
+	keySort      = "sort"
+	keyReverse   = "reverse"
+	keyMark      = "mark"
+	keyApply     = "apply"
+	keyRefresh   = "refresh"
+	keyPush      = "push"
+	keyDelete    = "delete"
+	keyRerun     = "rerun"
+	keyCancelRun = "cancel_run"
+	keySave      = "save"
+	keyUndo      = "undo"
+)
+
+// keyAction is an action with its default keys and the help shown for it
+type keyAction struct {
+	name string
+	keys []string
+	help string
+}
+
+// keyActions lists every action in the order the Help tab shows them
+var keyActions = []keyAction{
+	{keyUp, []string{"up", "k"}, "move up"},
+	{keyDown, []string{"down", "j"}, "move down"},
+	{keyPrevTab, []string{"left", "h"}, "previous tab"},
+	{keyNextTab, []string{"right", "l"}, "next tab"},
+	{keySelect, []string{"enter"}, "open or edit"},
+	{keyBack, []string{"esc", "backspace"}, "back"},
+	{keyQuit, []string{"q"}, "quit"},
+	{keyCancel, []string{"x"}, "cancel the running operation"},
+	{keyToggleLog, []string{"L"}, "show or hide the log pane"},
+	{keyLogUp, []string{"pgup"}, "scroll the log pane up"},
+	{keyLogDown, []string{"pgdown"}, "scroll the log pane down"},
+	{keyNew, []string{"n"}, "add a secret to a repository"},
+	{keyEdit, []string{"e"}, "edit or rotate a secret"},
+	{keyReveal, []string{"v"}, "reveal a secret while held"},
+	{keyConfirm, []string{"y"}, "confirm"},
+	{keyDeny, []string{"n"}, "decline"},
+	{keyFilter, []string{"/"}, "filter repositories"},
+	{keySort, []string{"s"}, "change the sort column"},
+	{keyReverse, []string{"S"}, "reverse the sort order"},
+	{keyMark, []string{"space"}, "select a repository"},
+	{keyApply, []string{"a"}, "apply saved items to repositories"},
+	{keyRefresh, []string{"r"}, "refresh from GitHub"},
+	{keyPush, []string{"p"}, "push a saved item again"},
+	{keyDelete, []string{"d"}, "remove a secret from a repository"},
+	{keyRerun, []string{"R"}, "re-run a workflow run"},
+	{keyCancelRun, []string{"c"}, "cancel a workflow run"},
+	{keySave, []string{"w"}, "save the bulk apply report"},
+	{keyUndo, []string{"u"}, "undo an operation"},
+}
+
+// KeyMap binds the actions of the TUI to keys
+type KeyMap map[string]key.Binding
+
+// DefaultKeyMap returns the built-in bindings
+func DefaultKeyMap() KeyMap {
+	keys := make(KeyMap, len(keyActions))
+	for _, action := range keyActions {
+		keys[action.name] = newBinding(action.keys, action.help)
+	}
+	return keys
+}
+
+// LoadKeyMap applies bindings given as comma-separated key lists by action name, such as
+// the keys.<action> settings, to the built-in bindings. Invalid bindings are reported and
+// leave the built-in keys of their action in place.
+func LoadKeyMap(bindings map[string]string) (KeyMap, error) {
+	keys := DefaultKeyMap()
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		action, ok := lookupKeyAction(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown key action '%s'", name))
+			continue
+		}
+		list, err := parseKeyList(bindings[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("keys.%s: %v", name, err))
+			continue
+		}
+		keys[action.name] = newBinding(list, action.help)
+	}
+	if len(problems) > 0 {
+		return keys, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return keys, nil
+}
+
+// configuredKeyMap loads the keys.<action> settings of the active configuration
+func configuredKeyMap() (KeyMap, error) {
+	return LoadKeyMap(viper.GetStringMapString("keys"))
+}
+
+// lookupKeyAction finds an action by name
+func lookupKeyAction(name string) (keyAction, bool) {
+	for _, action := range keyActions {
+		if action.name == strings.ToLower(name) {
+			return action, true
+		}
+	}
+	return keyAction{}, false
+}
+
+// parseKeyList splits a comma-separated key list such as "down,j,ctrl+n"
+func parseKeyList(text string) ([]string, error) {
+	var keys []string
+	for _, k := range strings.Split(text, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, fmt.Errorf("empty key in '%s'", text)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// newBinding creates a binding; "space" stands for the space bar, which bubbletea reports as " "
+func newBinding(keys []string, desc string) key.Binding {
+	bound := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k == "space" {
+			k = " "
+		}
+		bound = append(bound, k)
+	}
+	return key.NewBinding(key.WithKeys(bound...), key.WithHelp(strings.Join(keys, "/"), desc))
+}
+
+// Matches reports whether msg is bound to any of the actions
+func (k KeyMap) Matches(msg tea.KeyMsg, actions ...string) bool {
+	for _, action := range actions {
+		if key.Matches(msg, k[action]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the keys of an action as shown in help, such as "down/j"
+func (k KeyMap) Label(action string) string {
+	return k[action].Help().Key
+}
+
+// Hint returns the binding of an action with help text for the current screen
+func (k KeyMap) Hint(action, desc string) key.Binding {
+	binding := k[action]
+	binding.SetHelp(binding.Help().Key, desc)
+	return binding
+}
+
+// footerView renders help for bindings, wrapped onto as many lines as width needs
+func footerView(bindings []key.Binding, width int) string {
+	footer := help.New()
+	footer.Styles.ShortKey = activeTheme.fg(activeTheme.Accent)
+	footer.Styles.ShortDesc = activeTheme.fg(activeTheme.Muted)
+	footer.Styles.ShortSeparator = activeTheme.fg(activeTheme.Muted)
+	if width <= 0 {
+		return footer.ShortHelpView(bindings)
+	}
+
+	var lines []string
+	var line []key.Binding
+	for _, binding := range bindings {
+		if len(line) > 0 && lipgloss.Width(footer.ShortHelpView(append(line, binding))) > width {
+			lines = append(lines, footer.ShortHelpView(line))
+			line = nil
+		}
+		line = append(line, binding)
+	}
+	if len(line) > 0 {
+		lines = append(lines, footer.ShortHelpView(line))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,39 @@
+// keymap_test.go
+
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoadKeyMap tests overriding bindings and reporting invalid ones
+func TestLoadKeyMap(t *testing.T) {
+	keys, err := LoadKeyMap(map[string]string{"down": "ctrl+n, J", "mark": "space"})
+	assert.NoError(t, err)
+	assert.True(t, keys.Matches(tea.KeyMsg{Type: tea.KeyCtrlN}, "down"))
+	assert.True(t, keys.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("J")}, "down"))
+	assert.False(t, keys.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")}, "down"), "overrides replace the built-in keys")
+	assert.True(t, keys.Matches(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}, "mark"))
+	assert.Equal(t, "ctrl+n/J", keys.Label("down"))
+	assert.Equal(t, "up/k", keys.Label("up"))
+
+	keys, err = LoadKeyMap(map[string]string{"jump": "g", "up": "w,,x", "quit": "Q"})
+	assert.EqualError(t, err, "unknown key action 'jump'; keys.up: empty key in 'w,,x'")
+	assert.Equal(t, "up/k", keys.Label("up"), "invalid bindings keep the built-in keys")
+	assert.Equal(t, "Q", keys.Label("quit"), "valid bindings apply despite invalid ones")
+}
+
+// TestKeySettings tests that keys.<action> settings are validated and suggested
+func TestKeySettings(t *testing.T) {
+	assert.NoError(t, ValidateSetting("keys.next_tab", "tab,l", false))
+	assert.ErrorContains(t, ValidateSetting("keys.next_tab", "tab,", false), "empty key")
+	assert.ErrorContains(t, ValidateSetting("keys.next_tba", "tab", false), "did you mean 'keys.next_tab'?")
+
+	setting, ok := LookupSetting("keys.toggle_log")
+	assert.True(t, ok)
+	assert.Equal(t, "L", setting.Default)
+	assert.True(t, setting.Global)
+}
@@ -2,13 +2,14 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 	"sync"
-	"flag"
-	"fmt"
 
 	"github.com/sirupsen/logrus"
-	//"github.com/spf13/cobra"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/manifoldco/promptui"
 )
@@ -43,15 +44,32 @@ func printASCIIHeader() {
 ╚────────────────────────────────────────────────────────────────────────────╝
 `
 	once.Do(func() {
-		HeaderColor.Println(header) // Print the header with color
+		HeaderColor.Fprintln(os.Stderr, header) // Print the header with color
 	})
 }
 
-// GetGitHubToken prompts the user for their GitHub token and saves it in the config
+// GetGitHubToken returns the configured GitHub token. When none is configured it prompts for one
+// on a terminal and saves it in the credential store.
 func GetGitHubToken(logger *logrus.Logger) (string, error) {
+    // Renew OAuth tokens before they are used
+    if err := refreshTokenIfExpired(context.Background(), logger); err != nil {
+        WarningColor.Fprintf(os.Stderr, "Could not refresh GitHub token: %v\n", err)
+        logger.Warnf("Could not refresh GitHub token: %v", err)
+    }
+
+    if token := githubToken(logger); token != "" {
+        logger.Debug("GitHub token loaded from the credential store.")
+        return token, nil
+    }
+
+    if !isInteractive() {
+        return "", fmt.Errorf("no GitHub token configured; run 'ghm auth login' or set GITHUB_TOKEN")
+    }
+
     prompt := promptui.Prompt{
         Label: "Enter your GitHub token",
         Mask: '*', // Mask input with asterisks
+        Stdout: os.Stderr, // keep stdout for the command output
     }
     
     token, err := prompt.Run()
@@ -59,74 +77,71 @@ func GetGitHubToken(logger *logrus.Logger) (string, error) {
         logger.Errorf("Error reading GitHub token: %v", err)
         return "", err
     }
+    token = strings.TrimSpace(token)
 
-    // Store the token in the config
-    storeConfig := &StoreConfigStrategy{
-        ConfigKey:   "github_token",
-        ConfigValue: token,
-        Logger:      logger,
-    }
-
-    if err := storeConfig.Execute(); err != nil {
+    // Keep the token in the credential store rather than the config file
+    if err := storeCredential("github_token", token, logger); err != nil {
         logger.Errorf("Error storing GitHub token: %v", err)
         return "", err
     }
 
+    SuccessColor.Fprintln(os.Stderr, "GitHub token saved successfully.")
+    logger.Info("GitHub token saved successfully.")
     return token, nil
 }
 
 // initConfig initializes the configuration with viper
-func initConfig() {
+func initConfig(logger *logrus.Logger) {
 	viper.SetConfigName("config")
-	viper.AddConfigPath(".")
+	viper.AddConfigPath(configDir())
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			fmt.Println("Config file not found; using defaults")
+			logger.Debug("Config file not found; using defaults")
 		} else {
-			fmt.Printf("Error reading config file: %s\n", err)
+			logger.Warnf("Error reading config file: %s", err)
 		}
 	}
+
+	// Move tokens left in the config file by earlier versions
+	if err := migratePlaintextCredentials(logger); err != nil {
+		logger.Errorf("Error migrating plaintext credentials: %v", err)
+	}
 }
 
-func main() {
-    // Parse flags to check if TUI should run
-    runTUIFlag := flag.Bool("tui", false, "Run the TUI (terminal user interface)")
-    flag.Parse()
+// applyGlobalFlags loads the configuration, from the directory given with --state-dir if set,
+// and applies the selected context
+func applyGlobalFlags(cmd *cobra.Command, logger *logrus.Logger) {
+	if cmd.Flags().Changed("state-dir") {
+		viper.Reset()
+	}
+	initConfig(logger)
 
+	if err := applyContext(); err != nil {
+		logger.Fatalf("Error applying context: %v", err)
+	}
+	if _, err := SelectTheme(viper.GetString("theme")); err != nil {
+		logger.Warnf("Ignoring the theme setting: %v", err)
+	}
+	if err := validateOutputFormat(outputFormat); err != nil {
+		logger.Fatalf("Invalid --output: %v", err)
+	}
+}
+
+func main() {
     // Initialize logger
     logger := logrus.New()
     logger.SetFormatter(&logrus.TextFormatter{
         FullTimestamp: true,
     })
-    logger.SetOutput(os.Stdout)
+    // Logs go to stderr so stdout only carries command results
+    logger.SetOutput(os.Stderr)
     logger.SetLevel(logrus.InfoLevel)
 
-    // Initialize configuration
-    initConfig()
-
-    // Get GitHub token
-    githubToken, err := GetGitHubToken(logger)
-    if err != nil {
-        logger.Fatalf("Failed to get GitHub token: %v", err)
-    }
-
-    // Print ASCII Header
-    printASCIIHeader()
-
-    // Check if TUI should be launched
-    if *runTUIFlag {
-        // Run the TUI if the flag is set
-        runTUI(logger)
-    } else {
-        // Initialize and run the default CLI command
-        rootCmd := initRootCmd(logger)
-        if err := rootCmd.Execute(); err != nil {
-            logger.Fatalf("Error executing command: %v", err)
-        }
+    // Cobra owns all argument parsing; configuration and the token are loaded by the commands that need them
+    rootCmd := initRootCmd(logger)
+    if err := rootCmd.Execute(); err != nil {
+        os.Exit(1)
     }
-
-    // Now you can use githubToken where needed
-    logger.Infof("GitHub token obtained: %s", githubToken) // Use the token as needed
-}
\ No newline at end of file
+}
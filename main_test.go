@@ -0,0 +1,50 @@
+// main_test.go
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelpWithoutConfig tests that help needs no config, token or terminal, and writes nothing
+func TestHelpWithoutConfig(t *testing.T) {
+	for _, args := range [][]string{{"--help"}, {"secret", "add", "--help"}, {"tui", "--help"}} {
+		stdout, _, err := runGHM(t, args...)
+		require.NoError(t, err, args)
+		assert.Contains(t, stdout, "Usage:")
+
+		files, err := ioutil.ReadDir(os.Getenv("GHM_STATE_DIR"))
+		require.NoError(t, err)
+		assert.Empty(t, files, args)
+	}
+}
+
+// TestLazyToken tests that only the commands that talk to GitHub ask for a token
+func TestLazyToken(t *testing.T) {
+	_, _, err := runGHM(t, "config", "store", "--key", "theme", "--value", "mono")
+	require.NoError(t, err)
+	assert.Equal(t, "mono", viper.GetString("theme"))
+
+	_, _, err = runGHM(t, "secret", "remove", "--repo", "acme/api", "--name", "API_KEY")
+	assert.EqualError(t, err, "no GitHub token configured; run 'ghm auth login' or set GITHUB_TOKEN")
+
+	// The TUI is a subcommand and refuses to start without a terminal before it asks for a token
+	_, _, err = runGHM(t, "tui")
+	assert.EqualError(t, err, "the TUI needs a terminal; use the ghm subcommands in non-interactive mode")
+}
+
+// TestFlagsAfterSubcommand tests that cobra parses global flags wherever they appear
+func TestFlagsAfterSubcommand(t *testing.T) {
+	dir := t.TempDir()
+	stdout, _, err := runGHM(t, "repo", "list", "--state-dir", dir, "-o", "json")
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", stdout)
+	assert.FileExists(t, filepath.Join(dir, "ghm.db"))
+}
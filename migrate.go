@@ -0,0 +1,206 @@
+// migrate.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// legacyConfigNames are the config files earlier versions read from the working directory
+var legacyConfigNames = []string{"config.yaml", "config.yml", "config.json"}
+
+// MigrateStateStrategy imports state files that earlier versions kept in the working directory
+type MigrateStateStrategy struct {
+	SourceDir string
+	Remove    bool // Delete legacy files after a successful import
+	Logger    *logrus.Logger
+}
+
+// Execute imports every legacy file found in SourceDir and returns the paths that were imported
+func (m *MigrateStateStrategy) Execute() ([]string, error) {
+	var imported []string
+
+	importers := map[string]func(src, dst string) error{
+		secretsFileName:    m.importIntoStore,
+		workflowsFileName:  m.importIntoStore,
+		reposFileName:      m.importIntoStore,
+		credentialsFile:    m.copyIfMissing,
+		credentialsKeyFile: m.copyIfMissing,
+	}
+
+	for _, name := range []string{secretsFileName, workflowsFileName, reposFileName, credentialsKeyFile, credentialsFile} {
+		src := filepath.Join(m.SourceDir, name)
+		dst := dataPath(name)
+		if !m.isLegacy(src, dst) {
+			continue
+		}
+		if name == secretsFileName {
+			warnIfInGitWorktree(src, m.Logger)
+		}
+		if err := ensureDataDir(); err != nil {
+			return imported, err
+		}
+		if err := importers[name](src, dst); err != nil {
+			m.Logger.Errorf("Error importing %s: %v", src, err)
+			return imported, err
+		}
+		imported = append(imported, src)
+	}
+
+	for _, name := range legacyConfigNames {
+		src := filepath.Join(m.SourceDir, name)
+		if !m.isLegacy(src, configPath(configFileName)) {
+			continue
+		}
+		if err := m.mergeConfig(src); err != nil {
+			m.Logger.Errorf("Error importing %s: %v", src, err)
+			return imported, err
+		}
+		imported = append(imported, src)
+	}
+
+	if m.Remove {
+		for _, src := range imported {
+			if err := os.Remove(src); err != nil {
+				m.Logger.Errorf("Error removing %s: %v", src, err)
+				return imported, err
+			}
+		}
+	}
+
+	return imported, nil
+}
+
+// isLegacy reports whether src exists and is not the destination itself
+func (m *MigrateStateStrategy) isLegacy(src, dst string) bool {
+	if _, err := os.Stat(src); err != nil {
+		return false
+	}
+	srcAbs, err1 := filepath.Abs(src)
+	dstAbs, err2 := filepath.Abs(dst)
+	return err1 != nil || err2 != nil || srcAbs != dstAbs
+}
+
+// importIntoStore merges a legacy JSON state file into the state store, keeping existing entries
+func (m *MigrateStateStrategy) importIntoStore(src, dst string) error {
+	return withStateStore(m.Logger, func(store *StateStore) error {
+		return store.ImportJSONFile(filepath.Base(dst), src, false)
+	})
+}
+
+// mergeConfig imports settings missing from the current config and moves credentials to the credential store
+func (m *MigrateStateStrategy) mergeConfig(src string) error {
+	legacy := viper.New()
+	legacy.SetConfigFile(src)
+	if err := legacy.ReadInConfig(); err != nil {
+		return err
+	}
+
+	for _, key := range legacy.AllKeys() {
+		if isCredentialKey(key) {
+			if err := storeCredential(key, legacy.GetString(key), m.Logger); err != nil {
+				return err
+			}
+			continue
+		}
+		if viper.InConfig(key) {
+			continue
+		}
+		store := &StoreConfigStrategy{
+			ConfigKey:   key,
+			ConfigValue: legacy.GetString(key),
+			Logger:      m.Logger,
+		}
+		if err := store.Execute(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyIfMissing copies src to dst unless dst already exists
+func (m *MigrateStateStrategy) copyIfMissing(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		m.Logger.Warnf("Skipping %s; %s already exists.", src, dst)
+		return nil
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0600)
+}
+
+// appendMissing appends the items not already present in list
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		if !contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// contains reports whether list holds item
+func contains(list []string, item string) bool {
+	for _, existing := range list {
+		if existing == item {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONFile encodes v as indented JSON with owner-only permissions
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Initialize Migrate State Command
+func initMigrateStateCmd(logger *logrus.Logger) *cobra.Command {
+	var sourceDir string
+	var remove bool
+
+	migrateStateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Import state files from the working directory into the ghm config and data directories",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			strategy := &MigrateStateStrategy{
+				SourceDir: sourceDir,
+				Remove:    remove,
+				Logger:    logger,
+			}
+
+			imported, err := strategy.Execute()
+			if err != nil {
+				return err
+			}
+			if len(imported) == 0 {
+				InfoColor.Fprintf(os.Stderr, "No legacy state files found in %s.\n", sourceDir)
+				return printResult(cmd, stateFileResults(imported, actionImported))
+			}
+
+			fmt.Fprintf(os.Stderr, "Config directory: %s\nData directory: %s\n", configDir(), dataDir())
+			if !remove {
+				WarningColor.Fprintln(os.Stderr, "Legacy files were left in place; remove them or re-run with --remove.")
+			}
+			return printResult(cmd, stateFileResults(imported, actionImported))
+		},
+	}
+
+	migrateStateCmd.Flags().StringVar(&sourceDir, "from", ".", "Directory containing legacy state files")
+	migrateStateCmd.Flags().BoolVar(&remove, "remove", false, "Delete legacy files after importing them")
+
+	return migrateStateCmd
+}
@@ -0,0 +1,60 @@
+// migrate_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestJSON writes v as JSON to path
+func writeTestJSON(t *testing.T, path string, v interface{}) {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+// TestMigrateStateStrategy tests importing legacy working-directory files into the state store
+func TestMigrateStateStrategy(t *testing.T) {
+	legacyDir := t.TempDir()
+	stateDir := t.TempDir()
+	t.Setenv("GHM_STATE_DIR", stateDir)
+
+	writeTestJSON(t, filepath.Join(legacyDir, "secrets.json"), map[string]string{"API_KEY": "legacy", "DB_PASS": "legacy"})
+	writeTestJSON(t, filepath.Join(stateDir, "secrets.json"), map[string]string{"API_KEY": "current"})
+	writeTestJSON(t, filepath.Join(legacyDir, "workflows.json"), map[string]string{"ci.yml": "on: push"})
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	strategy := &MigrateStateStrategy{
+		SourceDir: legacyDir,
+		Remove:    true,
+		Logger:    logger,
+	}
+	imported, err := strategy.Execute()
+	require.NoError(t, err)
+	assert.Len(t, imported, 2)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Existing values win over legacy ones
+	err = store.View(func(tx *StateTx) error {
+		assert.Equal(t, map[string]string{"API_KEY": "current", "DB_PASS": "legacy"}, tx.Secrets())
+		assert.Equal(t, map[string]string{"ci.yml": "on: push"}, tx.Workflows())
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(legacyDir, "secrets.json"))
+	assert.NoFileExists(t, filepath.Join(legacyDir, "workflows.json"))
+}
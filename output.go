@@ -0,0 +1,353 @@
+// output.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputJSONPath = "jsonpath"
+)
+
+// outputFormat is set by the --output flag
+var outputFormat = outputTable
+
+// tabular is implemented by results that can be printed as a table
+type tabular interface {
+	tableHeader() []string
+	tableRows() [][]string
+}
+
+// OperationResult reports a change made by a command
+type OperationResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Repo   string `json:"repo,omitempty"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// OperationResults is the result of a command that changes several items
+type OperationResults []OperationResult
+
+// ItemResult describes a secret or workflow, saved locally when Repo is empty
+type ItemResult struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Repo string `json:"repo,omitempty"`
+}
+
+// ItemResults is the result of the secret and workflow list commands
+type ItemResults []ItemResult
+
+// RepoResult describes a tracked repository
+type RepoResult struct {
+	Repo       string    `json:"repo"`
+	Secrets    []string  `json:"secrets"`
+	Workflows  []string  `json:"workflows"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// RepoResults is the result of the repo list command
+type RepoResults []RepoResult
+
+// ConfigEntry is a configuration key and its value
+type ConfigEntry struct {
+	Key         string      `json:"key"`
+	Value       interface{} `json:"value"`
+	Source      string      `json:"source,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// ConfigEntries is the result of the config get and config list commands
+type ConfigEntries []ConfigEntry
+
+// BatchResult reports the outcome of one line of a batch file
+type BatchResult struct {
+	Line int    `json:"line"`
+	Op   string `json:"op"`
+	OperationResult
+}
+
+// BatchResults is the result of the batch run command
+type BatchResults []BatchResult
+
+// StateCheckResult is the result of the state check command
+type StateCheckResult struct {
+	Path   string       `json:"path"`
+	Valid  bool         `json:"valid"`
+	Issues []StateIssue `json:"issues"`
+	Backup string       `json:"backup,omitempty"`
+}
+
+// Result kinds and mutation actions shared by all results
+const (
+	kindSecret     = "secret"
+	kindWorkflow   = "workflow"
+	kindConfig     = "config"
+	kindCredential = "credential"
+	kindStateFile  = "state_file"
+	kindRun        = "run"
+
+	actionAdded    = "added"
+	actionRemoved  = "removed"
+	actionDeleted  = "deleted"
+	actionStored   = "stored"
+	actionImported = "imported"
+	actionExported = "exported"
+	actionFailed   = "failed"
+
+	actionCreated     = "created"
+	actionOverwritten = "overwritten"
+	actionUnchanged   = "unchanged"
+	actionSkipped     = "skipped"
+	actionPlanned     = "planned" // a dry run would make the change
+
+	actionRerun     = "rerun"
+	actionCancelled = "cancelled"
+	actionRotated   = "rotated"
+	actionRestored  = "restored"
+)
+
+// validateOutputFormat checks the --output flag before a command makes any change
+func validateOutputFormat(format string) error {
+	name, template, hasTemplate := strings.Cut(format, "=")
+	switch name {
+	case outputTable, outputJSON, outputYAML:
+		if hasTemplate {
+			return fmt.Errorf("output format '%s' does not take a template", name)
+		}
+		return nil
+	case outputJSONPath:
+		if !hasTemplate || template == "" {
+			return fmt.Errorf("output format jsonpath requires a template, e.g. jsonpath='{.name}'")
+		}
+		_, err := parseJSONPathTemplate(template)
+		return err
+	}
+	return fmt.Errorf("unknown output format '%s'; use table, json, yaml or jsonpath=<template>", format)
+}
+
+// FormatOutput writes a command result to w in the given output format
+func FormatOutput(w io.Writer, format string, result interface{}) error {
+	if err := validateOutputFormat(format); err != nil {
+		return err
+	}
+	name, template, _ := strings.Cut(format, "=")
+
+	if name == outputTable {
+		table, ok := result.(tabular)
+		if !ok {
+			return fmt.Errorf("result of type %T cannot be printed as a table", result)
+		}
+		return writeTable(w, table)
+	}
+
+	// JSON, YAML and JSONPath all use the JSON field names
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if name == outputJSON {
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	if name == outputYAML {
+		out, err := yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+
+	nodes, err := parseJSONPathTemplate(template)
+	if err != nil {
+		return err
+	}
+	return executeJSONPathTemplate(w, nodes, generic)
+}
+
+// printResult writes a command result to stdout in the --output format
+func printResult(cmd *cobra.Command, result interface{}) error {
+	return FormatOutput(cmd.OutOrStdout(), outputFormat, result)
+}
+
+// completeOutputFormats completes --output
+func completeOutputFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	formats := []string{outputTable, outputJSON, outputYAML, outputJSONPath + "="}
+	return filterPrefix(formats, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// writeTable writes a tabular result with aligned columns
+func writeTable(w io.Writer, table tabular) error {
+	rows := table.tableRows()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(table.tableHeader(), "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (r OperationResult) tableHeader() []string { return OperationResults{r}.tableHeader() }
+func (r OperationResult) tableRows() [][]string { return OperationResults{r}.tableRows() }
+
+func (r OperationResults) tableHeader() []string {
+	return []string{"KIND", "NAME", "REPO", "ACTION", "ERROR"}
+}
+
+func (r OperationResults) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, result := range r {
+		action := result.Action
+		if result.DryRun {
+			action += " (dry run)"
+		}
+		rows = append(rows, []string{result.Kind, result.Name, result.Repo, action, result.Error})
+	}
+	return rows
+}
+
+// failed counts the operations that failed
+func (r OperationResults) failed() int {
+	count := 0
+	for _, result := range r {
+		if result.Action == actionFailed {
+			count++
+		}
+	}
+	return count
+}
+
+func (r BatchResults) tableHeader() []string {
+	return []string{"LINE", "OP", "NAME", "REPO", "ACTION", "ERROR"}
+}
+
+func (r BatchResults) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, result := range r {
+		row := OperationResults{result.OperationResult}.tableRows()[0]
+		rows = append(rows, append([]string{strconv.Itoa(result.Line), result.Op}, row[1:]...))
+	}
+	return rows
+}
+
+// failed counts the lines that failed
+func (r BatchResults) failed() int {
+	count := 0
+	for _, result := range r {
+		if result.Action == actionFailed {
+			count++
+		}
+	}
+	return count
+}
+
+func (r ItemResults) tableHeader() []string {
+	return []string{"KIND", "NAME", "REPO"}
+}
+
+func (r ItemResults) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, item := range r {
+		rows = append(rows, []string{item.Kind, item.Name, item.Repo})
+	}
+	return rows
+}
+
+func (r RepoResults) tableHeader() []string {
+	return []string{"REPO", "SECRETS", "WORKFLOWS", "LAST UPDATE"}
+}
+
+func (r RepoResults) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, repo := range r {
+		lastUpdate := ""
+		if !repo.LastUpdate.IsZero() {
+			lastUpdate = repo.LastUpdate.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{repo.Repo, strings.Join(repo.Secrets, ","), strings.Join(repo.Workflows, ","), lastUpdate})
+	}
+	return rows
+}
+
+func (r ConfigEntries) tableHeader() []string {
+	return []string{"KEY", "VALUE", "SOURCE", "DESCRIPTION"}
+}
+
+func (r ConfigEntries) tableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, entry := range r {
+		rows = append(rows, []string{entry.Key, fmt.Sprint(entry.Value), entry.Source, entry.Description})
+	}
+	return rows
+}
+
+func (r StateCheckResult) tableHeader() []string {
+	return []string{"SEVERITY", "LOCATION", "MESSAGE"}
+}
+
+func (r StateCheckResult) tableRows() [][]string {
+	rows := make([][]string, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		severity := "WARNING"
+		if issue.Fatal {
+			severity = "ERROR"
+		}
+		location := "state store"
+		if issue.Repo != "" {
+			location = issue.Repo
+		}
+		rows = append(rows, []string{severity, location, issue.Message})
+	}
+	return rows
+}
+
+// repoResults converts the tracked repositories into results sorted by name
+func repoResults(reposConfig *ReposConfig) RepoResults {
+	results := make(RepoResults, 0, len(reposConfig.Repositories))
+	for repo, config := range reposConfig.Repositories {
+		results = append(results, RepoResult{
+			Repo:       repo,
+			Secrets:    config.Secrets,
+			Workflows:  config.Workflows,
+			LastUpdate: config.LastUpdate,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Repo < results[j].Repo })
+	return results
+}
+
+// itemResults converts item names into results
+func itemResults(kind, repo string, names []string) ItemResults {
+	results := make(ItemResults, 0, len(names))
+	for _, name := range names {
+		results = append(results, ItemResult{Kind: kind, Name: name, Repo: repo})
+	}
+	return results
+}
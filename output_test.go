@@ -0,0 +1,57 @@
+// output_test.go
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRepoResults is a repo list result used by the output tests
+var testRepoResults = RepoResults{
+	{Repo: "owner/api", Secrets: []string{"API_KEY"}, Workflows: []string{"ci.yml"}, LastUpdate: time.Date(2024, 10, 11, 10, 0, 0, 0, time.UTC)},
+	{Repo: "owner/web", Secrets: []string{"API_KEY", "DB_PASS"}, Workflows: []string{}},
+}
+
+// TestFormatOutput tests that every format uses the same field names
+func TestFormatOutput(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"json", `"last_update": "2024-10-11T10:00:00Z"`},
+		{"yaml", "last_update: \"2024-10-11T10:00:00Z\""},
+		{"jsonpath={[*].repo}", "owner/api owner/web"},
+		{`jsonpath={range [*]}{.repo}={.secrets[-1]}{"\n"}{end}`, "owner/api=API_KEY\nowner/web=DB_PASS\n"},
+		{"table", "owner/web  API_KEY,DB_PASS"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		require.NoError(t, FormatOutput(&out, tt.format, testRepoResults), tt.format)
+		assert.Contains(t, out.String(), tt.expected, tt.format)
+	}
+}
+
+// TestFormatOutputMutation tests printing a single mutation result
+func TestFormatOutputMutation(t *testing.T) {
+	result := OperationResult{Kind: "secret", Name: "API_KEY", Repo: "owner/api", Action: "added"}
+
+	var out bytes.Buffer
+	require.NoError(t, FormatOutput(&out, "json", result))
+	assert.JSONEq(t, `{"kind": "secret", "name": "API_KEY", "repo": "owner/api", "action": "added"}`, out.String())
+}
+
+// TestFormatOutputInvalid tests rejecting unknown formats and malformed templates
+func TestFormatOutputInvalid(t *testing.T) {
+	for _, format := range []string{"xml", "jsonpath", "json=x", "jsonpath={range [*]}{.repo}", "jsonpath={.repo",
+		"jsonpath={.repo}", "jsonpath={[*].repos}"} {
+		var out bytes.Buffer
+		assert.Error(t, FormatOutput(&out, format, testRepoResults), format)
+		assert.Empty(t, out.String(), format)
+	}
+}
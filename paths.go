@@ -0,0 +1,100 @@
+// paths.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// State and config file names
+const (
+	reposFileName     = "repos.json"
+	secretsFileName   = "secrets.json"
+	workflowsFileName = "workflows.json"
+	configFileName    = "config.yaml"
+
+	// stateDirEnv overrides the state directory like --state-dir
+	stateDirEnv = "GHM_STATE_DIR"
+)
+
+// stateDirOverride is set by the --state-dir flag and holds both config and data when non-empty
+var stateDirOverride string
+
+// stateDir returns the --state-dir or GHM_STATE_DIR override, if any
+func stateDir() string {
+	if stateDirOverride != "" {
+		return stateDirOverride
+	}
+	return os.Getenv(stateDirEnv)
+}
+
+// configDir returns $XDG_CONFIG_HOME/ghm, defaulting to ~/.config/ghm
+func configDir() string {
+	if dir := stateDir(); dir != "" {
+		return dir
+	}
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "ghm")
+}
+
+// dataDir returns $XDG_DATA_HOME/ghm, defaulting to ~/.local/share/ghm
+func dataDir() string {
+	if dir := stateDir(); dir != "" {
+		return dir
+	}
+	return filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "ghm")
+}
+
+// xdgDir returns the XDG base directory from env, or the fallback below the home directory
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fallback
+	}
+	return filepath.Join(home, fallback)
+}
+
+// configPath returns the path of a file in the config directory
+func configPath(name string) string {
+	return filepath.Join(configDir(), name)
+}
+
+// dataPath returns the path of a file in the data directory
+func dataPath(name string) string {
+	return filepath.Join(dataDir(), name)
+}
+
+// ensureDataDir creates the data directory with owner-only permissions
+func ensureDataDir() error {
+	return os.MkdirAll(dataDir(), 0700)
+}
+
+// gitWorktreeRoot returns the root of the git worktree containing path, or "" if there is none
+func gitWorktreeRoot(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// warnIfInGitWorktree warns when a file holding secrets could be committed to a repository
+func warnIfInGitWorktree(path string, logger *logrus.Logger) {
+	if root := gitWorktreeRoot(path); root != "" {
+		WarningColor.Fprintf(messageOutput, "Warning: %s is inside the git worktree %s and may be committed.\n", path, root)
+		logger.Warnf("Secrets file %s is inside git worktree %s", path, root)
+	}
+}
@@ -0,0 +1,280 @@
+// picker.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+	"github.com/sirupsen/logrus"
+)
+
+// pickerHeight is the number of list rows shown at once
+const pickerHeight = 10
+
+// Picker styles, set by applyTheme
+var (
+	pickerTitleStyle    lipgloss.Style
+	pickerCursorStyle   lipgloss.Style
+	pickerSelectedStyle lipgloss.Style
+	pickerDimStyle      lipgloss.Style
+	pickerPreviewStyle  lipgloss.Style
+)
+
+// setPickerStyles derives the picker styles from a theme
+func setPickerStyles(t Theme) {
+	pickerTitleStyle = t.fg(t.Accent).Bold(true)
+	pickerCursorStyle = t.emphasis()
+	pickerSelectedStyle = t.fg(t.Success).Bold(t.Bold)
+	pickerDimStyle = t.fg(t.Muted)
+	pickerPreviewStyle = t.border(lipgloss.RoundedBorder()).Padding(0, 1)
+}
+
+// PickerItem is an entry of the multi-select picker. Preview lines describe the item
+// and must never contain secret values.
+type PickerItem struct {
+	Name    string
+	Preview []string
+}
+
+// PickerModel is a bubbletea multi-select list with fuzzy filtering
+type PickerModel struct {
+	title     string
+	items     []PickerItem
+	filter    textinput.Model
+	matches   []int // indexes into items in display order
+	cursor    int
+	offset    int
+	selected  map[int]bool
+	done      bool
+	cancelled bool
+}
+
+// NewPickerModel creates a picker over items; items with duplicate names are listed once
+func NewPickerModel(title string, items []PickerItem) PickerModel {
+	filter := textinput.New()
+	filter.Prompt = "Filter: "
+	filter.Placeholder = "type to search"
+	filter.Focus()
+
+	seen := make(map[string]bool)
+	unique := make([]PickerItem, 0, len(items))
+	for _, item := range items {
+		if seen[item.Name] {
+			continue
+		}
+		seen[item.Name] = true
+		unique = append(unique, item)
+	}
+
+	m := PickerModel{
+		title:    title,
+		items:    unique,
+		filter:   filter,
+		selected: make(map[int]bool),
+	}
+	m.applyFilter()
+	return m
+}
+
+// Init is part of the Bubble Tea interface
+func (m PickerModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update is part of the Bubble Tea interface
+func (m PickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.cancelled = true
+			return m, tea.Quit
+		case "enter":
+			m.done = true
+			return m, tea.Quit
+		case "up", "ctrl+p":
+			m.moveCursor(-1)
+			return m, nil
+		case "down", "ctrl+n":
+			m.moveCursor(1)
+			return m, nil
+		case " ", "tab":
+			if len(m.matches) > 0 {
+				index := m.matches[m.cursor]
+				m.selected[index] = !m.selected[index]
+			}
+			return m, nil
+		case "ctrl+a":
+			m.toggleAll()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	previous := m.filter.Value()
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != previous {
+		m.applyFilter()
+	}
+	return m, cmd
+}
+
+// applyFilter recomputes the visible items from the filter text
+func (m *PickerModel) applyFilter() {
+	query := m.filter.Value()
+	m.matches = nil
+	if query == "" {
+		for i := range m.items {
+			m.matches = append(m.matches, i)
+		}
+	} else {
+		names := make([]string, len(m.items))
+		for i, item := range m.items {
+			names[i] = item.Name
+		}
+		for _, match := range fuzzy.Find(query, names) {
+			m.matches = append(m.matches, match.Index)
+		}
+	}
+	m.cursor = 0
+	m.offset = 0
+}
+
+// moveCursor moves the cursor by delta and scrolls the visible window
+func (m *PickerModel) moveCursor(delta int) {
+	if len(m.matches) == 0 {
+		return
+	}
+	m.cursor = (m.cursor + delta + len(m.matches)) % len(m.matches)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+pickerHeight {
+		m.offset = m.cursor - pickerHeight + 1
+	}
+}
+
+// toggleAll selects every visible item, or clears them when all are already selected
+func (m *PickerModel) toggleAll() {
+	all := true
+	for _, index := range m.matches {
+		if !m.selected[index] {
+			all = false
+			break
+		}
+	}
+	for _, index := range m.matches {
+		m.selected[index] = !all
+	}
+}
+
+// View renders the picker
+func (m PickerModel) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(pickerTitleStyle.Render(m.title) + "\n")
+	b.WriteString(m.filter.View() + "\n\n")
+
+	if len(m.matches) == 0 {
+		b.WriteString(pickerDimStyle.Render("  No matches") + "\n")
+	}
+	end := m.offset + pickerHeight
+	if end > len(m.matches) {
+		end = len(m.matches)
+	}
+	for row := m.offset; row < end; row++ {
+		index := m.matches[row]
+		cursor := "  "
+		if row == m.cursor {
+			cursor = pickerCursorStyle.Render("> ")
+		}
+		check := "[ ] "
+		name := m.items[index].Name
+		if m.selected[index] {
+			check = pickerSelectedStyle.Render("[x] ")
+			name = pickerSelectedStyle.Render(name)
+		}
+		b.WriteString(cursor + check + name + "\n")
+	}
+
+	if len(m.matches) > 0 {
+		preview := m.items[m.matches[m.cursor]].Preview
+		if len(preview) > 0 {
+			b.WriteString("\n" + pickerPreviewStyle.Render(strings.Join(preview, "\n")) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + pickerDimStyle.Render(fmt.Sprintf(
+		"%d of %d selected • space: toggle • ctrl+a: toggle all • enter: confirm • esc: cancel",
+		len(m.Selected()), len(m.items))) + "\n")
+	return b.String()
+}
+
+// Selected returns the names of the selected items in list order
+func (m PickerModel) Selected() []string {
+	names := []string{}
+	for i, item := range m.items {
+		if m.selected[i] {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// Select marks the items with the given names as selected
+func (m *PickerModel) Select(names ...string) {
+	for i, item := range m.items {
+		if contains(names, item.Name) {
+			m.selected[i] = true
+		}
+	}
+}
+
+// AddItems appends items whose names are not listed yet, keeping the filter and selection
+func (m *PickerModel) AddItems(items []PickerItem) {
+	for _, item := range items {
+		known := false
+		for _, existing := range m.items {
+			if existing.Name == item.Name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			m.items = append(m.items, item)
+		}
+	}
+	cursor, offset := m.cursor, m.offset
+	m.applyFilter()
+	if m.filter.Value() == "" {
+		// New items come last, so the cursor still points at the same item
+		m.cursor, m.offset = cursor, offset
+	}
+}
+
+// Cancelled reports whether the picker was closed without confirming
+func (m PickerModel) Cancelled() bool {
+	return m.cancelled
+}
+
+// runPicker shows the picker on the terminal and returns the confirmed selection
+func runPicker(title string, items []PickerItem, logger *logrus.Logger) ([]string, error) {
+	final, err := tea.NewProgram(NewPickerModel(title, items), tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		logger.Errorf("Error running picker: %v", err)
+		return nil, err
+	}
+
+	picker := final.(PickerModel)
+	if picker.Cancelled() {
+		return nil, fmt.Errorf("selection cancelled")
+	}
+	return picker.Selected(), nil
+}
@@ -0,0 +1,75 @@
+// picker_test.go
+
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+// pickerKeys sends key presses to the picker and returns the resulting model
+func pickerKeys(m PickerModel, keys ...tea.KeyMsg) PickerModel {
+	for _, key := range keys {
+		model, _ := m.Update(key)
+		m = model.(PickerModel)
+	}
+	return m
+}
+
+// typeText converts text into key presses
+func typeText(text string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for _, r := range text {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+var pickerTestItems = []PickerItem{
+	{Name: "API_KEY", Preview: []string{"Used in 1 repositories"}},
+	{Name: "AWS_SECRET_ACCESS_KEY"},
+	{Name: "DB_PASS"},
+	{Name: "API_KEY"},
+}
+
+// TestPickerFilterAndSelect tests fuzzy filtering and toggling items
+func TestPickerFilterAndSelect(t *testing.T) {
+	m := NewPickerModel("Select secrets", pickerTestItems)
+	assert.Contains(t, m.View(), "0 of 3 selected")
+
+	m = pickerKeys(m, typeText("dbps")...)
+	assert.NotContains(t, m.View(), "API_KEY")
+
+	m = pickerKeys(m, tea.KeyMsg{Type: tea.KeySpace}, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"DB_PASS"}, m.Selected())
+	assert.False(t, m.Cancelled())
+}
+
+// TestPickerSelectAll tests that select-all toggles every visible item once
+func TestPickerSelectAll(t *testing.T) {
+	m := NewPickerModel("Select secrets", pickerTestItems)
+
+	m = pickerKeys(m, tea.KeyMsg{Type: tea.KeyCtrlA})
+	assert.Equal(t, []string{"API_KEY", "AWS_SECRET_ACCESS_KEY", "DB_PASS"}, m.Selected())
+	assert.Contains(t, m.View(), "Used in 1 repositories")
+
+	m = pickerKeys(m, tea.KeyMsg{Type: tea.KeyCtrlA}, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Empty(t, m.Selected())
+	assert.True(t, m.Cancelled())
+}
+
+// TestPickerSelectAndAddItems tests selecting items by name and adding items later
+func TestPickerSelectAndAddItems(t *testing.T) {
+	m := NewPickerModel("Select repositories", []PickerItem{{Name: "acme/api"}, {Name: "acme/web"}})
+	m.Select("acme/web", "unknown/repo")
+	assert.Equal(t, []string{"acme/web"}, m.Selected())
+
+	m = pickerKeys(m, tea.KeyMsg{Type: tea.KeyDown})
+	m.AddItems([]PickerItem{{Name: "acme/web"}, {Name: "other/worker"}})
+	assert.Contains(t, m.View(), "1 of 3 selected")
+
+	m = pickerKeys(m, tea.KeyMsg{Type: tea.KeySpace}, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeySpace})
+	assert.Equal(t, []string{"other/worker"}, m.Selected(), "the cursor stays on its item")
+}
@@ -0,0 +1,96 @@
+// progress.go
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProgressEvent reports how far a running operation has come
+type ProgressEvent struct {
+	Step    string  // e.g. "Receiving objects" or "Encrypting secret"
+	Percent float64 // between 0 and 1, or -1 when unknown
+}
+
+// progressKey is the context key of the progress reporter
+type progressKey struct{}
+
+// sidebandProgress matches git progress lines such as "Receiving objects:  45% (9/20)"
+var sidebandProgress = regexp.MustCompile(`^\s*([^:]+):\s+(\d{1,3})% \((\d+)/(\d+)\)`)
+
+// withProgress returns a context whose operations report their progress to report.
+// report must not block; it is called from the goroutine running the operation.
+func withProgress(ctx context.Context, report func(ProgressEvent)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// reportProgress reports a step of the operation running with ctx, if anyone listens
+func reportProgress(ctx context.Context, step string, percent float64) {
+	if report, ok := ctx.Value(progressKey{}).(func(ProgressEvent)); ok {
+		report(ProgressEvent{Step: step, Percent: percent})
+	}
+}
+
+// operationContext returns ctx, or the background context for strategies created without one
+func operationContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// progressWriter returns the writer for git sideband progress: stderr on the command line,
+// or a parser turning the progress lines into events when a reporter is set
+func progressWriter(ctx context.Context) io.Writer {
+	report, ok := ctx.Value(progressKey{}).(func(ProgressEvent))
+	if !ok {
+		return os.Stderr
+	}
+	return &sidebandWriter{report: report}
+}
+
+// ParseSidebandProgress parses a git progress line; ok is false for other messages
+func ParseSidebandProgress(line string) (event ProgressEvent, ok bool) {
+	match := sidebandProgress.FindStringSubmatch(line)
+	if match == nil {
+		return ProgressEvent{}, false
+	}
+	done, _ := strconv.Atoi(match[3])
+	total, _ := strconv.Atoi(match[4])
+	if total == 0 {
+		return ProgressEvent{Step: strings.TrimSpace(match[1]), Percent: -1}, true
+	}
+	return ProgressEvent{Step: strings.TrimSpace(match[1]), Percent: float64(done) / float64(total)}, true
+}
+
+// sidebandWriter splits git sideband output into lines, which end in \r while a step is
+// running and in \n when it is done, and reports the progress lines
+type sidebandWriter struct {
+	mu      sync.Mutex
+	report  func(ProgressEvent)
+	pending string
+}
+
+// Write implements io.Writer
+func (w *sidebandWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending += string(p)
+	for {
+		end := strings.IndexAny(w.pending, "\r\n")
+		if end < 0 {
+			break
+		}
+		if event, ok := ParseSidebandProgress(w.pending[:end]); ok {
+			w.report(event)
+		}
+		w.pending = w.pending[end+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,24 @@
+// progress_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSidebandProgress tests parsing git progress lines into events
+func TestParseSidebandProgress(t *testing.T) {
+	event, ok := ParseSidebandProgress("Receiving objects:  45% (9/20)")
+	assert.True(t, ok)
+	assert.Equal(t, "Receiving objects", event.Step)
+	assert.InDelta(t, 0.45, event.Percent, 0.001)
+
+	event, ok = ParseSidebandProgress("Counting objects: 100% (5/5), done.")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, event.Percent)
+
+	_, ok = ParseSidebandProgress("Enumerating objects: 5, done.")
+	assert.False(t, ok)
+}
@@ -0,0 +1,408 @@
+// runs.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"golang.org/x/oauth2"
+)
+
+// Workflow run limits
+const (
+	runsPerRepo   = 10      // most recent runs listed per repository
+	maxJobLogSize = 1 << 20 // bytes kept from the end of a job log
+)
+
+// cacheHeader marks responses served from the ETag cache. go-github skips its rate
+// limit bookkeeping for responses carrying it.
+const cacheHeader = "X-From-Cache"
+
+// WorkflowRun summarizes a run of a GitHub Actions workflow
+type WorkflowRun struct {
+	Repo       string
+	ID         int64
+	Number     int
+	Workflow   string
+	Status     string // queued, in_progress, completed, ...
+	Conclusion string // success, failure, cancelled, ...; empty until completed
+	Branch     string
+	Actor      string
+	Event      string
+	URL        string
+	StartedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// State returns the conclusion of a completed run and the status of any other
+func (r WorkflowRun) State() string {
+	if r.Status == "completed" && r.Conclusion != "" {
+		return r.Conclusion
+	}
+	return r.Status
+}
+
+// Duration returns how long the run took, or has been running at now
+func (r WorkflowRun) Duration(now time.Time) time.Duration {
+	return runDuration(r.Status, r.StartedAt, r.UpdatedAt, now)
+}
+
+// WorkflowJob is a job of a workflow run with its steps
+type WorkflowJob struct {
+	ID          int64
+	Name        string
+	Status      string
+	Conclusion  string
+	StartedAt   time.Time
+	CompletedAt time.Time
+	Steps       []WorkflowStep
+}
+
+// State returns the conclusion of a completed job and the status of any other
+func (j WorkflowJob) State() string {
+	if j.Status == "completed" && j.Conclusion != "" {
+		return j.Conclusion
+	}
+	return j.Status
+}
+
+// Duration returns how long the job took, or has been running at now
+func (j WorkflowJob) Duration(now time.Time) time.Duration {
+	return runDuration(j.Status, j.StartedAt, j.CompletedAt, now)
+}
+
+// WorkflowStep is a step of a workflow job
+type WorkflowStep struct {
+	Number     int64
+	Name       string
+	Status     string
+	Conclusion string
+}
+
+// State returns the conclusion of a completed step and the status of any other
+func (s WorkflowStep) State() string {
+	if s.Status == "completed" && s.Conclusion != "" {
+		return s.Conclusion
+	}
+	return s.Status
+}
+
+// runDuration returns the time between start and end, or between start and now while running
+func runDuration(status string, start, end, now time.Time) time.Duration {
+	switch {
+	case start.IsZero():
+		return 0
+	case status != "completed":
+		return now.Sub(start)
+	case end.Before(start):
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// ETagCache remembers GitHub API responses by URL so that polling can send conditional
+// requests; unchanged resources then cost no rate limit
+type ETagCache struct {
+	mu      sync.Mutex
+	entries map[string]etagEntry
+}
+
+// etagEntry is a cached response
+type etagEntry struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// NewETagCache creates an empty cache
+func NewETagCache() *ETagCache {
+	return &ETagCache{entries: make(map[string]etagEntry)}
+}
+
+// Transport returns a round tripper sending GET requests through the cache
+func (c *ETagCache) Transport(base http.RoundTripper) http.RoundTripper {
+	return &etagTransport{cache: c, base: base}
+}
+
+// etagTransport adds If-None-Match to GET requests with a cached response and replays
+// the cached body, marked with cacheHeader, when GitHub answers 304 Not Modified
+type etagTransport struct {
+	cache *ETagCache
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	t.cache.mu.Lock()
+	entry, cached := t.cache.entries[key]
+	t.cache.mu.Unlock()
+	if cached {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		resp.Body.Close()
+		header := entry.header.Clone()
+		header.Set(cacheHeader, "1")
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(entry.body)),
+			ContentLength: int64(len(entry.body)),
+			Request:       req,
+		}, nil
+
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.cache.mu.Lock()
+		t.cache.entries[key] = etagEntry{etag: resp.Header.Get("ETag"), header: resp.Header.Clone(), body: body}
+		t.cache.mu.Unlock()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+// actionsClient creates a GitHub client whose GET requests go through the ETag cache, if any
+func (g *GHMImpl) actionsClient(ctx context.Context) (*github.Client, error) {
+	if g.Cache != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: g.Cache.Transport(http.DefaultTransport)})
+	}
+	return newGitHubClient(ctx, g.Token)
+}
+
+// ListWorkflowRuns lists the most recent workflow runs of the GitHub repository, newest
+// first; changed is false when GitHub reported them unchanged since the last call
+func (g *GHMImpl) ListWorkflowRuns(ctx context.Context, repo string) (runs []WorkflowRun, changed bool, err error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, false, err
+	}
+	client, err := g.actionsClient(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	list, resp, err := client.Actions.ListRepositoryWorkflowRuns(ctx, owner, name, &github.ListWorkflowRunsOptions{ListOptions: github.ListOptions{PerPage: runsPerRepo}})
+	if err != nil {
+		// Polling calls this every few seconds, so leave reporting the error to the caller
+		g.Logger.Debugf("Error listing workflow runs of '%s': %v", repo, err)
+		return nil, false, err
+	}
+
+	for _, run := range list.WorkflowRuns {
+		startedAt := run.GetRunStartedAt().Time
+		if startedAt.IsZero() {
+			startedAt = run.GetCreatedAt().Time
+		}
+		runs = append(runs, WorkflowRun{
+			Repo:       repo,
+			ID:         run.GetID(),
+			Number:     run.GetRunNumber(),
+			Workflow:   run.GetName(),
+			Status:     run.GetStatus(),
+			Conclusion: run.GetConclusion(),
+			Branch:     run.GetHeadBranch(),
+			Actor:      run.GetActor().GetLogin(),
+			Event:      run.GetEvent(),
+			URL:        run.GetHTMLURL(),
+			StartedAt:  startedAt,
+			UpdatedAt:  run.GetUpdatedAt().Time,
+		})
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs, resp.Header.Get(cacheHeader) == "", nil
+}
+
+// ListRunJobs lists the jobs of a workflow run with their steps
+func (g *GHMImpl) ListRunJobs(ctx context.Context, repo string, runID int64) ([]WorkflowJob, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	client, err := g.actionsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []WorkflowJob
+	opts := &github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := client.Actions.ListWorkflowJobs(ctx, owner, name, runID, opts)
+		if err != nil {
+			g.Logger.Debugf("Error listing jobs of run %d of '%s': %v", runID, repo, err)
+			return nil, err
+		}
+		for _, job := range list.Jobs {
+			steps := make([]WorkflowStep, 0, len(job.Steps))
+			for _, step := range job.Steps {
+				steps = append(steps, WorkflowStep{
+					Number:     step.GetNumber(),
+					Name:       step.GetName(),
+					Status:     step.GetStatus(),
+					Conclusion: step.GetConclusion(),
+				})
+			}
+			jobs = append(jobs, WorkflowJob{
+				ID:          job.GetID(),
+				Name:        job.GetName(),
+				Status:      job.GetStatus(),
+				Conclusion:  job.GetConclusion(),
+				StartedAt:   job.GetStartedAt().Time,
+				CompletedAt: job.GetCompletedAt().Time,
+				Steps:       steps,
+			})
+		}
+		if resp.NextPage == 0 {
+			return jobs, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// JobLog downloads the log of a workflow job. Only the last maxJobLogSize bytes are kept.
+func (g *GHMImpl) JobLog(ctx context.Context, repo string, jobID int64) (string, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return "", err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return "", err
+	}
+
+	logURL, _, err := client.Actions.GetWorkflowJobLogs(ctx, owner, name, jobID, 1)
+	if err != nil {
+		g.Logger.Errorf("Error getting the log of job %d of '%s': %v", jobID, repo, err)
+		return "", err
+	}
+
+	// The log is served from a signed URL that must not receive the token
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		g.Logger.Errorf("Error downloading the log of job %d of '%s': %v", jobID, repo, err)
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading job log: %s", resp.Status)
+	}
+
+	tail := &tailBuffer{max: maxJobLogSize}
+	if _, err := io.Copy(tail, resp.Body); err != nil {
+		return "", fmt.Errorf("downloading job log: %w", err)
+	}
+	return tail.String(), nil
+}
+
+// RerunWorkflowRun starts a new attempt of a workflow run
+func (g *GHMImpl) RerunWorkflowRun(ctx context.Context, repo string, runID int64) error {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Actions.RerunWorkflowByID(ctx, owner, name, runID)
+	g.record(ctx, JournalEntry{Operation: opRerunRun, Kind: kindRun, Name: strconv.FormatInt(runID, 10), Repo: repo}, err)
+	if err != nil {
+		g.Logger.Errorf("Error re-running run %d of '%s': %v", runID, repo, err)
+		return err
+	}
+	g.Logger.Infof("Re-run of run %d of '%s' requested.", runID, repo)
+	return nil
+}
+
+// CancelWorkflowRun cancels a queued or running workflow run
+func (g *GHMImpl) CancelWorkflowRun(ctx context.Context, repo string, runID int64) error {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+	client, err := newGitHubClient(ctx, g.Token)
+	if err != nil {
+		return err
+	}
+
+	// GitHub accepts the cancellation with 202 and carries it out in the background
+	_, err = client.Actions.CancelWorkflowRunByID(ctx, owner, name, runID)
+	if _, accepted := err.(*github.AcceptedError); accepted {
+		err = nil
+	}
+	g.record(ctx, JournalEntry{Operation: opCancelRun, Kind: kindRun, Name: strconv.FormatInt(runID, 10), Repo: repo}, err)
+	if err != nil {
+		g.Logger.Errorf("Error cancelling run %d of '%s': %v", runID, repo, err)
+		return err
+	}
+	g.Logger.Infof("Cancellation of run %d of '%s' requested.", runID, repo)
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+// Write implements io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	// Trim only once the buffer is twice as large to avoid copying on every write
+	if len(t.buf) > 2*t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the kept bytes, starting at a line boundary when the start was dropped
+func (t *tailBuffer) String() string {
+	data := t.buf
+	if len(data) > t.max {
+		data = data[len(data)-t.max:]
+		t.truncated = true
+	}
+	if !t.truncated {
+		return string(data)
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return "[earlier output truncated]\n" + string(data)
+}
@@ -0,0 +1,182 @@
+// runs_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runsServer is a stand-in for the GitHub Actions API of the repository acme/api
+type runsServer struct {
+	*httptest.Server
+	listCalls    int32
+	notModified  int32
+	rerunCalls   int32
+	cancelCalls  int32
+	logAuthorize string
+}
+
+// newRunsServer starts the stand-in and points the GitHub client at it
+func newRunsServer(t *testing.T) *runsServer {
+	s := &runsServer{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/repos/acme/api/actions/runs", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.listCalls, 1)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == `"runs-v1"` {
+			atomic.AddInt32(&s.notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"runs-v1"`)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"total_count": 2,
+			"workflow_runs": []map[string]interface{}{
+				{
+					"id": 7, "run_number": 12, "name": "CI", "status": "completed", "conclusion": "failure",
+					"head_branch": "main", "event": "push", "actor": map[string]string{"login": "octocat"},
+					"run_started_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:03:30Z",
+				},
+				{
+					"id": 8, "run_number": 13, "name": "CI", "status": "in_progress",
+					"head_branch": "feature", "event": "pull_request", "actor": map[string]string{"login": "hubot"},
+					"run_started_at": "2024-05-01T11:00:00Z", "updated_at": "2024-05-01T11:01:00Z",
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/repos/acme/api/actions/runs/7/jobs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"total_count": 1,
+			"jobs": []map[string]interface{}{{
+				"id": 70, "name": "test", "status": "completed", "conclusion": "failure",
+				"started_at": "2024-05-01T10:00:10Z", "completed_at": "2024-05-01T10:03:00Z",
+				"steps": []map[string]interface{}{
+					{"number": 1, "name": "Checkout", "status": "completed", "conclusion": "success"},
+					{"number": 2, "name": "Run tests", "status": "completed", "conclusion": "failure"},
+				},
+			}},
+		})
+	})
+	mux.HandleFunc("/repos/acme/api/actions/jobs/70/logs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, s.URL+"/blob/job-70.txt?sig=abc", http.StatusFound)
+	})
+	mux.HandleFunc("/blob/job-70.txt", func(w http.ResponseWriter, r *http.Request) {
+		s.logAuthorize = r.Header.Get("Authorization")
+		fmt.Fprint(w, "Run tests\nFAIL: TestSomething\n")
+	})
+	mux.HandleFunc("/repos/acme/api/actions/runs/7/rerun", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		atomic.AddInt32(&s.rerunCalls, 1)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/repos/acme/api/actions/runs/8/cancel", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		atomic.AddInt32(&s.cancelCalls, 1)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "{}")
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	t.Setenv("GITHUB_API_URL", s.URL)
+	return s
+}
+
+// newRunsGHM creates a GHM talking to the stand-in
+func newRunsGHM() GHM {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	return NewGHM("test-token", logger)
+}
+
+// TestListWorkflowRunsETag tests that polling sends the ETag and reuses unchanged runs
+func TestListWorkflowRunsETag(t *testing.T) {
+	server := newRunsServer(t)
+	ghm := newRunsGHM()
+
+	runs, changed, err := ghm.ListWorkflowRuns(context.Background(), "acme/api")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.Len(t, runs, 2)
+
+	// Newest first
+	assert.Equal(t, int64(8), runs[0].ID)
+	assert.Equal(t, "in_progress", runs[0].State())
+	failed := runs[1]
+	assert.Equal(t, WorkflowRun{
+		Repo: "acme/api", ID: 7, Number: 12, Workflow: "CI", Status: "completed", Conclusion: "failure",
+		Branch: "main", Actor: "octocat", Event: "push",
+		StartedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2024, 5, 1, 10, 3, 30, 0, time.UTC),
+	}, failed)
+	assert.Equal(t, "failure", failed.State())
+	assert.Equal(t, 210*time.Second, failed.Duration(time.Now()))
+	assert.Equal(t, 5*time.Minute, runs[0].Duration(time.Date(2024, 5, 1, 11, 5, 0, 0, time.UTC)))
+
+	again, changed, err := ghm.ListWorkflowRuns(context.Background(), "acme/api")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, runs, again)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.listCalls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.notModified))
+}
+
+// TestListWorkflowRunsError tests that API errors are returned
+func TestListWorkflowRunsError(t *testing.T) {
+	newRunsServer(t)
+	_, _, err := newRunsGHM().ListWorkflowRuns(context.Background(), "acme/missing")
+	assert.Error(t, err)
+}
+
+// TestListRunJobs tests listing the jobs of a run with their steps
+func TestListRunJobs(t *testing.T) {
+	newRunsServer(t)
+
+	jobs, err := newRunsGHM().ListRunJobs(context.Background(), "acme/api", 7)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "test", jobs[0].Name)
+	assert.Equal(t, "failure", jobs[0].State())
+	assert.Equal(t, 170*time.Second, jobs[0].Duration(time.Now()))
+	assert.Equal(t, []WorkflowStep{
+		{Number: 1, Name: "Checkout", Status: "completed", Conclusion: "success"},
+		{Number: 2, Name: "Run tests", Status: "completed", Conclusion: "failure"},
+	}, jobs[0].Steps)
+}
+
+// TestJobLog tests that the log is downloaded from the redirect without the token
+func TestJobLog(t *testing.T) {
+	server := newRunsServer(t)
+
+	log, err := newRunsGHM().JobLog(context.Background(), "acme/api", 70)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(log, "FAIL: TestSomething"))
+	assert.Empty(t, server.logAuthorize)
+}
+
+// TestRerunAndCancelWorkflowRun tests the re-run and cancel requests
+func TestRerunAndCancelWorkflowRun(t *testing.T) {
+	server := newRunsServer(t)
+	ghm := newRunsGHM()
+
+	require.NoError(t, ghm.RerunWorkflowRun(context.Background(), "acme/api", 7))
+	require.NoError(t, ghm.CancelWorkflowRun(context.Background(), "acme/api", 8))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.rerunCalls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.cancelCalls))
+
+	assert.Error(t, ghm.CancelWorkflowRun(context.Background(), "acme/api", 9))
+}
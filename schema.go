@@ -0,0 +1,490 @@
+// schema.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// reposSchemaVersion is the repos.json schema written by this version of ghm.
+// Files without a version field are treated as version 1.
+const reposSchemaVersion = 2
+
+// ReposMigration upgrades a raw repos.json document from one schema version to the next
+type ReposMigration struct {
+	From        int
+	Description string
+	Migrate     func(doc map[string]interface{}, logger *logrus.Logger) error
+}
+
+// reposMigrations lists the migrations in order; each one upgrades From to From+1
+var reposMigrations = []ReposMigration{
+	{
+		From:        1,
+		Description: "normalize last_update to RFC 3339 and remove duplicate entries",
+		Migrate:     migrateReposV1ToV2,
+	},
+}
+
+// lastUpdateLayouts are the timestamp formats accepted when normalizing free-form last_update values
+var lastUpdateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+// schemaVersion returns the version field of a raw document, defaulting to 1
+func schemaVersion(doc map[string]interface{}) (int, error) {
+	raw, exists := doc["version"]
+	if !exists {
+		return 1, nil
+	}
+	version, ok := raw.(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	return int(version), nil
+}
+
+// UpgradeReposDocument decodes repos.json content, applying migrations up to the current schema.
+// It returns the upgraded config and the version the document started at.
+func UpgradeReposDocument(data []byte, logger *logrus.Logger) (*ReposConfig, int, error) {
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse repos.json: %w", err)
+	}
+
+	from, err := schemaVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > reposSchemaVersion {
+		return nil, from, fmt.Errorf("repos.json schema version %d is newer than supported version %d", from, reposSchemaVersion)
+	}
+
+	for version := from; version < reposSchemaVersion; version++ {
+		migration, err := findReposMigration(version)
+		if err != nil {
+			return nil, from, err
+		}
+		logger.Infof("Migrating repos.json from schema version %d: %s", version, migration.Description)
+		if err := migration.Migrate(doc, logger); err != nil {
+			return nil, from, fmt.Errorf("migration from version %d failed: %w", version, err)
+		}
+		doc["version"] = version + 1
+	}
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, from, err
+	}
+	reposConfig := &ReposConfig{}
+	if err := json.Unmarshal(upgraded, reposConfig); err != nil {
+		return nil, from, fmt.Errorf("failed to decode repos.json: %w", err)
+	}
+	if reposConfig.Repositories == nil {
+		reposConfig.Repositories = make(map[string]RepoConfig)
+	}
+
+	return reposConfig, from, nil
+}
+
+// findReposMigration returns the migration that upgrades the given version
+func findReposMigration(version int) (ReposMigration, error) {
+	for _, migration := range reposMigrations {
+		if migration.From == version {
+			return migration, nil
+		}
+	}
+	return ReposMigration{}, fmt.Errorf("no migration from repos.json schema version %d", version)
+}
+
+// backupReposFile copies repos.json next to itself before it is rewritten by a migration
+func backupReposFile(configFile string, version int) (string, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.v%d.%s.bak", configFile, version, time.Now().UTC().Format("20060102T150405Z"))
+	if err := ioutil.WriteFile(backup, data, 0600); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// migrateReposV1ToV2 turns free-form last_update strings into RFC 3339 timestamps and deduplicates lists
+func migrateReposV1ToV2(doc map[string]interface{}, logger *logrus.Logger) error {
+	repositories, _ := doc["repositories"].(map[string]interface{})
+	if repositories == nil {
+		doc["repositories"] = map[string]interface{}{}
+		return nil
+	}
+
+	for repo, raw := range repositories {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("repository '%s' is not an object", repo)
+		}
+
+		for _, field := range []string{"secrets", "workflows"} {
+			items, err := uniqueStrings(entry[field])
+			if err != nil {
+				return fmt.Errorf("repository '%s' %s: %w", repo, field, err)
+			}
+			entry[field] = items
+		}
+
+		lastUpdate, _ := entry["last_update"].(string)
+		parsed, err := parseLastUpdate(lastUpdate)
+		if err != nil {
+			logger.Warnf("Clearing unparseable last_update '%s' for '%s'.", lastUpdate, repo)
+		}
+		entry["last_update"] = parsed.Format(time.RFC3339)
+	}
+	return nil
+}
+
+// uniqueStrings converts a raw JSON array to a list of strings without duplicates
+func uniqueStrings(raw interface{}) ([]string, error) {
+	items := []string{}
+	if raw == nil {
+		return items, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list")
+	}
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		items = appendMissing(items, name)
+	}
+	return items, nil
+}
+
+// parseLastUpdate parses a legacy last_update value, returning the zero time when it is empty or unknown
+func parseLastUpdate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range lastUpdateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp '%s'", value)
+}
+
+// StateIssue is a problem found by CheckReposConfig
+type StateIssue struct {
+	Repo    string `json:"repo,omitempty"`
+	Message string `json:"message"`
+	Fatal   bool   `json:"fatal"` // Fatal issues mean the file cannot be used as-is
+
+	duplicate bool // the issue is a duplicate entry that --fix removes
+}
+
+// CheckReposConfig validates repos.json content and reports corruption and inconsistencies.
+// savedSecrets and savedWorkflows are the names in the local stores, or nil to skip those checks.
+func CheckReposConfig(data []byte, savedSecrets, savedWorkflows []string, logger *logrus.Logger) ([]StateIssue, *ReposConfig) {
+	var issues []StateIssue
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return append(issues, StateIssue{Message: fmt.Sprintf("file is not valid JSON: %v", err), Fatal: true}), nil
+	}
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return append(issues, StateIssue{Message: err.Error(), Fatal: true}), nil
+	}
+	if version < reposSchemaVersion {
+		issues = append(issues, StateIssue{Message: fmt.Sprintf("schema version %d is outdated; it will be migrated to %d on next load", version, reposSchemaVersion)})
+	}
+
+	// Duplicates are removed by migrations, so count them on the raw document
+	if repositories, ok := doc["repositories"].(map[string]interface{}); ok {
+		for repo, raw := range repositories {
+			entry, _ := raw.(map[string]interface{})
+			for _, field := range []string{"secrets", "workflows"} {
+				list, _ := entry[field].([]interface{})
+				seen := make(map[interface{}]int)
+				for _, item := range list {
+					seen[item]++
+				}
+				for item, count := range seen {
+					if count > 1 {
+						issues = append(issues, StateIssue{Repo: repo, Message: fmt.Sprintf("%s entry '%v' appears %d times", strings.TrimSuffix(field, "s"), item, count), duplicate: true})
+					}
+				}
+			}
+		}
+	}
+
+	reposConfig, _, err := UpgradeReposDocument(data, logger)
+	if err != nil {
+		return append(issues, StateIssue{Message: err.Error(), Fatal: true}), nil
+	}
+
+	for repo, config := range reposConfig.Repositories {
+		if parts := strings.Split(repo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			issues = append(issues, StateIssue{Repo: repo, Message: "repository name is not in 'owner/repo' format", Fatal: true})
+		}
+		if config.LastUpdate.After(time.Now().Add(time.Hour)) {
+			issues = append(issues, StateIssue{Repo: repo, Message: fmt.Sprintf("last update %s is in the future", config.LastUpdate.Format(time.RFC3339))})
+		}
+		if savedSecrets != nil {
+			for _, secret := range config.Secrets {
+				if !contains(savedSecrets, secret) {
+					issues = append(issues, StateIssue{Repo: repo, Message: fmt.Sprintf("secret '%s' is not in the local secret store", secret)})
+				}
+			}
+		}
+		if savedWorkflows != nil {
+			for _, workflow := range config.Workflows {
+				if !contains(savedWorkflows, workflow) {
+					issues = append(issues, StateIssue{Repo: repo, Message: fmt.Sprintf("workflow '%s' is not in the local workflow store", workflow)})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Repo < issues[j].Repo
+	})
+	return issues, reposConfig
+}
+
+// exportReposDocument returns the tracked repositories in the repos.json layout
+func exportReposDocument(store *StateStore) ([]byte, error) {
+	var data []byte
+	err := store.View(func(tx *StateTx) error {
+		repos, err := tx.Repos()
+		if err != nil {
+			return err
+		}
+		data, err = json.Marshal(&ReposConfig{Version: reposSchemaVersion, Repositories: repos})
+		return err
+	})
+	return data, err
+}
+
+// stateFileResults reports the state files handled by a command
+func stateFileResults(paths []string, action string) OperationResults {
+	results := make(OperationResults, 0, len(paths))
+	for _, path := range paths {
+		results = append(results, OperationResult{Kind: kindStateFile, Name: path, Action: action})
+	}
+	return results
+}
+
+// Initialize State Command
+func initStateCmd(logger *logrus.Logger) *cobra.Command {
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and repair local state files",
+	}
+
+	stateCmd.AddCommand(initStateCheckCmd(logger))
+	stateCmd.AddCommand(initMigrateStateCmd(logger))
+	stateCmd.AddCommand(initStateExportCmd(logger))
+	stateCmd.AddCommand(initStateImportCmd(logger))
+
+	return stateCmd
+}
+
+// Initialize State Check Command
+func initStateCheckCmd(logger *logrus.Logger) *cobra.Command {
+	var fix bool
+
+	stateCheckCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate the state store and report corruption",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Missing stores only skip the cross-reference checks
+			savedSecrets, err := loadSavedSecrets(logger)
+			if err != nil {
+				savedSecrets = nil
+			}
+			savedWorkflows, err := loadSavedWorkflows(logger)
+			if err != nil {
+				savedWorkflows = nil
+			}
+
+			store, err := OpenStateStore(logger)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			var issues []StateIssue
+			for _, err := range store.Check() {
+				issues = append(issues, StateIssue{Message: fmt.Sprintf("store corruption: %v", err), Fatal: true})
+			}
+
+			data, err := exportReposDocument(store)
+			if err != nil {
+				issues = append(issues, StateIssue{Message: err.Error(), Fatal: true})
+			}
+
+			var reposConfig *ReposConfig
+			if data != nil {
+				var repoIssues []StateIssue
+				repoIssues, reposConfig = CheckReposConfig(data, savedSecrets, savedWorkflows, logger)
+				issues = append(issues, repoIssues...)
+			}
+
+			result := StateCheckResult{Path: store.Path, Valid: true, Issues: []StateIssue{}}
+			if len(issues) == 0 {
+				SuccessColor.Fprintf(os.Stderr, "State store %s is valid.\n", store.Path)
+				if fix {
+					InfoColor.Fprintln(os.Stderr, "Nothing to fix: no duplicate entries.")
+				}
+				return printResult(cmd, result)
+			}
+
+			fatal, duplicates := 0, 0
+			for _, issue := range issues {
+				if issue.Fatal {
+					fatal++
+				}
+				if issue.duplicate {
+					duplicates++
+				}
+			}
+			result.Issues = issues
+			result.Valid = fatal == 0
+
+			switch {
+			case !fix:
+			case duplicates == 0 || reposConfig == nil:
+				InfoColor.Fprintln(os.Stderr, "Nothing to fix: no duplicate entries.")
+			default:
+				backup := fmt.Sprintf("%s.%s.bak", store.Path, time.Now().UTC().Format("20060102T150405Z"))
+				if err := store.Backup(backup); err != nil {
+					logger.Errorf("Error backing up state store: %v", err)
+					return err
+				}
+				err := store.Update(func(tx *StateTx) error {
+					for repo, config := range reposConfig.Repositories {
+						config.Secrets = appendMissing([]string{}, config.Secrets...)
+						config.Workflows = appendMissing([]string{}, config.Workflows...)
+						if err := tx.PutRepo(repo, config); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					logger.Errorf("Error repairing state store: %v", err)
+					return err
+				}
+				result.Backup = backup
+				SuccessColor.Fprintf(os.Stderr, "Removed duplicate entries (backup: %s).\n", backup)
+			}
+
+			if err := printResult(cmd, result); err != nil {
+				return err
+			}
+			if fatal > 0 {
+				return fmt.Errorf("state store has %d error(s)", fatal)
+			}
+			return nil
+		},
+	}
+
+	stateCheckCmd.Flags().BoolVar(&fix, "fix", false, "Remove duplicate entries after backing up the store")
+
+	return stateCheckCmd
+}
+
+// Initialize State Export Command
+func initStateExportCmd(logger *logrus.Logger) *cobra.Command {
+	var dir string
+
+	stateExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the state store as repos.json, secrets.json and workflows.json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				logger.Error("Export directory must be specified.")
+				return fmt.Errorf("export directory not specified")
+			}
+			err := withStateStore(logger, func(store *StateStore) error {
+				return store.ExportJSON(dir)
+			})
+			if err != nil {
+				logger.Errorf("Error exporting state: %v", err)
+				return err
+			}
+			warnIfInGitWorktree(filepath.Join(dir, secretsFileName), logger)
+			SuccessColor.Fprintf(os.Stderr, "Exported state to %s.\n", dir)
+
+			results := make(OperationResults, 0, 3)
+			for _, name := range []string{reposFileName, secretsFileName, workflowsFileName} {
+				results = append(results, OperationResult{Kind: kindStateFile, Name: filepath.Join(dir, name), Action: actionExported})
+			}
+			return printResult(cmd, results)
+		},
+	}
+
+	stateExportCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory to write the JSON files to")
+
+	return stateExportCmd
+}
+
+// Initialize State Import Command
+func initStateImportCmd(logger *logrus.Logger) *cobra.Command {
+	var dir string
+	var overwrite bool
+
+	stateImportCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import repos.json, secrets.json and workflows.json into the state store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				logger.Error("Import directory must be specified.")
+				return fmt.Errorf("import directory not specified")
+			}
+			var imported []string
+			err := withStateStore(logger, func(store *StateStore) error {
+				var err error
+				imported, err = store.ImportJSON(dir, overwrite)
+				return err
+			})
+			if err != nil {
+				logger.Errorf("Error importing state: %v", err)
+				return err
+			}
+			if len(imported) == 0 {
+				InfoColor.Fprintf(os.Stderr, "No state files found in %s.\n", dir)
+			}
+			return printResult(cmd, stateFileResults(imported, actionImported))
+		},
+	}
+
+	stateImportCmd.Flags().StringVarP(&dir, "dir", "d", "", "Directory containing the JSON files")
+	stateImportCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace secrets and workflows that already exist in the store")
+
+	return stateImportCmd
+}
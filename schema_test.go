@@ -0,0 +1,158 @@
+// schema_test.go
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyReposJSON is a repos.json written before schema versions existed
+const legacyReposJSON = `{
+  "repositories": {
+    "owner/repo": {
+      "secrets": ["API_KEY", "API_KEY", "DB_PASS"],
+      "workflows": ["ci.yml"],
+      "last_update": "2024-10-11 10:00:00"
+    },
+    "owner/other": {
+      "secrets": null,
+      "workflows": ["ci.yml", "ci.yml"],
+      "last_update": "sometime last week"
+    }
+  }
+}`
+
+// TestUpgradeReposDocument tests migrating a version 1 document to the current schema
+func TestUpgradeReposDocument(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	reposConfig, from, err := UpgradeReposDocument([]byte(legacyReposJSON), logger)
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, 2, reposConfig.Version)
+
+	repo := reposConfig.Repositories["owner/repo"]
+	assert.Equal(t, []string{"API_KEY", "DB_PASS"}, repo.Secrets)
+	assert.Equal(t, time.Date(2024, 10, 11, 10, 0, 0, 0, time.UTC), repo.LastUpdate)
+
+	other := reposConfig.Repositories["owner/other"]
+	assert.Equal(t, []string{}, other.Secrets)
+	assert.Equal(t, []string{"ci.yml"}, other.Workflows)
+	assert.True(t, other.LastUpdate.IsZero())
+}
+
+// TestUpgradeReposDocumentNewerVersion tests that files from newer versions are rejected
+func TestUpgradeReposDocumentNewerVersion(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	_, _, err := UpgradeReposDocument([]byte(`{"version": 99, "repositories": {}}`), logger)
+	assert.Error(t, err)
+}
+
+// TestLoadReposConfigImportsLegacyFile tests that the state store imports and upgrades an old repos.json
+func TestLoadReposConfigImportsLegacyFile(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("GHM_STATE_DIR", stateDir)
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, "repos.json"), []byte(legacyReposJSON), 0600))
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	reposConfig, err := LoadReposConfig(logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_KEY", "DB_PASS"}, reposConfig.Repositories["owner/repo"].Secrets)
+
+	// The original file is kept next to the store
+	backups, err := filepath.Glob(filepath.Join(stateDir, "repos.json.imported.*.bak"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	backup, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, legacyReposJSON, string(backup))
+	assert.NoFileExists(t, filepath.Join(stateDir, "repos.json"))
+
+	// The version 1 document is also backed up before it is upgraded
+	backups, err = filepath.Glob(filepath.Join(stateDir, "repos.json.v1.*.bak"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	backup, err = os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, legacyReposJSON, string(backup))
+}
+
+// TestCheckReposConfig tests reporting duplicates and malformed repository names
+func TestCheckReposConfig(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	data := []byte(`{"version": 2, "repositories": {
+		"not-a-repo": {"secrets": [], "workflows": [], "last_update": "2024-10-11T10:00:00Z"},
+		"owner/repo": {"secrets": ["API_KEY", "API_KEY"], "workflows": [], "last_update": "2024-10-11T10:00:00Z"}
+	}}`)
+
+	issues, _ := CheckReposConfig(data, []string{"API_KEY"}, nil, logger)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "not-a-repo", issues[0].Repo)
+	assert.True(t, issues[0].Fatal)
+	assert.Equal(t, "owner/repo", issues[1].Repo)
+	assert.Contains(t, issues[1].Message, "appears 2 times")
+}
+
+// TestStateCheckFix tests that --fix only backs up and repairs a store with duplicate entries
+func TestStateCheckFix(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GHM_STATE_DIR", dir)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	putRepo := func(config RepoConfig) {
+		require.NoError(t, withStateStore(logger, func(store *StateStore) error {
+			return store.Update(func(tx *StateTx) error {
+				return tx.PutRepo("owner/repo", config)
+			})
+		}))
+	}
+	checkFix := func() {
+		rootCmd := initRootCmd(logger)
+		rootCmd.SetOut(ioutil.Discard)
+		rootCmd.SetArgs([]string{"state", "check", "--fix"})
+		require.NoError(t, rootCmd.Execute())
+	}
+	backups := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.bak"))
+		require.NoError(t, err)
+		return matches
+	}
+
+	// An issue that is not a duplicate is reported but leaves nothing to fix
+	future := time.Now().Add(48 * time.Hour)
+	putRepo(RepoConfig{Secrets: []string{"API_KEY"}, Workflows: []string{"ci.yml"}, LastUpdate: future})
+	checkFix()
+	assert.Empty(t, backups())
+
+	putRepo(RepoConfig{Secrets: []string{"API_KEY", "API_KEY"}, Workflows: []string{"ci.yml"}, LastUpdate: future})
+	checkFix()
+	assert.Len(t, backups(), 1)
+	require.NoError(t, withStateStore(logger, func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			config, _, err := tx.Repo("owner/repo")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"API_KEY"}, config.Secrets)
+			return nil
+		})
+	}))
+}
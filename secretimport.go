@@ -0,0 +1,281 @@
+// secretimport.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret file formats accepted by secret import
+const (
+	formatDotenv = "dotenv"
+	formatJSON   = "json"
+	formatYAML   = "yaml"
+)
+
+// SecretNameMapping turns the keys of an imported file into secret names.
+// Rename entries are applied first; Prefix and Uppercase then apply to every name.
+type SecretNameMapping struct {
+	Prefix    string
+	Uppercase bool
+	Rename    map[string]string
+	FixNames  bool // replace invalid names with their suggested fix
+}
+
+// Apply returns the secret name for a key of an imported file
+func (m SecretNameMapping) Apply(key string) string {
+	name := key
+	if renamed, exists := m.Rename[key]; exists {
+		name = renamed
+	}
+	name = m.Prefix + name
+	if m.Uppercase {
+		name = strings.ToUpper(name)
+	}
+	return name
+}
+
+// SecretImportEntry is a secret that an import creates, overwrites or leaves unchanged
+type SecretImportEntry struct {
+	Source string // key in the imported file
+	Name   string
+	Value  string
+	Action string
+}
+
+// secretsFileFormat returns format, or guesses it from the file extension; .env files
+// such as .env.production have no useful extension, so dotenv is the default
+func secretsFileFormat(path, format string) (string, error) {
+	switch format {
+	case formatDotenv, formatJSON, formatYAML:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown format '%s'; use dotenv, json or yaml", format)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON, nil
+	case ".yaml", ".yml":
+		return formatYAML, nil
+	}
+	return formatDotenv, nil
+}
+
+// ParseSecretsFile parses a dotenv, JSON or YAML file into secret values by key.
+// JSON and YAML files must hold a flat object of strings, numbers or booleans.
+func ParseSecretsFile(data []byte, format string) (map[string]string, error) {
+	var raw map[string]interface{}
+	switch format {
+	case formatDotenv:
+		return ParseDotenv(string(data))
+	case formatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parsing YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format '%s'; use dotenv, json or yaml", format)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case json.Number, int, float64, bool:
+			values[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("value of '%s' must be a string, number or boolean", key)
+		}
+	}
+	return values, nil
+}
+
+// ParseDotenv parses KEY=VALUE lines. Blank lines, comments and an 'export' prefix are
+// ignored. Double-quoted values may span lines and understand \n, \r, \t, \", \\ and \$;
+// single-quoted values may span lines and are taken literally; unquoted values are trimmed
+// and end at ' #'.
+func ParseDotenv(data string) (map[string]string, error) {
+	values := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+
+		key, rest, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote := rest[0]
+			body := rest[1:]
+			for {
+				end := closingQuote(body, quote)
+				if end >= 0 {
+					trailing := strings.TrimSpace(body[end+1:])
+					if trailing != "" && !strings.HasPrefix(trailing, "#") {
+						return nil, fmt.Errorf("line %d: unexpected text after closing quote", i+1)
+					}
+					value = body[:end]
+					break
+				}
+				if i+1 >= len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+				}
+				i++
+				body += "\n" + lines[i]
+			}
+			if quote == '"' {
+				value = unescapeDotenv(value)
+			}
+		} else {
+			value = rest
+			if index := strings.Index(value, " #"); index >= 0 {
+				value = value[:index]
+			}
+			if index := strings.Index(value, "\t#"); index >= 0 {
+				value = value[:index]
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// closingQuote returns the index of the quote ending a quoted value, or -1;
+// backslashes escape characters only in double-quoted values
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\' && quote == '"':
+			i++
+		case body[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenv resolves the escapes of a double-quoted dotenv value; unknown escapes are kept
+func unescapeDotenv(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// PlanSecretImport maps the imported keys to secret names and decides for each whether it is
+// created, overwritten or unchanged compared to the saved secrets. Invalid names, keys that map
+// to the same name and values GitHub would reject are errors, so nothing is written for a bad file.
+func PlanSecretImport(values map[string]string, mapping SecretNameMapping, rules SecretNameRules, existing map[string]string) ([]SecretImportEntry, error) {
+	sources := make(map[string]string, len(values))
+	entries := make([]SecretImportEntry, 0, len(values))
+	var names []string
+
+	for _, key := range sortedKeys(values) {
+		name := mapping.Apply(key)
+		if mapping.FixNames {
+			name = NormalizeSecretName(name, rules)
+		}
+		names = append(names, name)
+		if other, exists := sources[name]; exists {
+			return nil, fmt.Errorf("'%s' and '%s' both map to secret '%s'", other, key, name)
+		}
+		sources[name] = key
+
+		value := values[key]
+		if err := checkSecretSize(value); err != nil {
+			return nil, fmt.Errorf("'%s': %w", key, err)
+		}
+
+		action := actionCreated
+		if saved, exists := existing[name]; exists {
+			action = actionOverwritten
+			if saved == value {
+				action = actionUnchanged
+			}
+		}
+		entries = append(entries, SecretImportEntry{Source: key, Name: name, Value: value, Action: action})
+	}
+	if err := validateSecretNames(names, rules); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// SecretImportResults reports every planned secret with its action and, for a dry run, the
+// repositories it would be added to
+func SecretImportResults(entries []SecretImportEntry, repos []string, dryRun bool) OperationResults {
+	results := make(OperationResults, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, OperationResult{Kind: kindSecret, Name: entry.Name, Action: entry.Action, DryRun: dryRun})
+	}
+	if dryRun {
+		for _, repo := range repos {
+			for _, entry := range entries {
+				results = append(results, OperationResult{Kind: kindSecret, Name: entry.Name, Repo: repo, Action: actionPlanned, DryRun: true})
+			}
+		}
+	}
+	return results
+}
+
+// CountSecretImport counts the planned secrets that are created or overwritten, and those
+// already saved with the same value
+func CountSecretImport(entries []SecretImportEntry) (changed, unchanged int) {
+	for _, entry := range entries {
+		if entry.Action == actionUnchanged {
+			unchanged++
+		} else {
+			changed++
+		}
+	}
+	return changed, unchanged
+}
@@ -0,0 +1,126 @@
+// secretimport_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseDotenv tests quoting, multi-line values, comments and export prefixes
+func TestParseDotenv(t *testing.T) {
+	data := "# production settings\r\n" +
+		"export API_KEY=abc123 # inline comment\n" +
+		"\n" +
+		"DB_URL = postgres://db:5432/app\n" +
+		"GREETING=\"hello\\n\\\"world\\\"\"\n" +
+		"LITERAL='no $expansion \\n here'\n" +
+		"PRIVATE_KEY=\"-----BEGIN KEY-----\n  line two  \n-----END KEY-----\"\n" +
+		"HASH=\"a # b\"\n"
+
+	values, err := ParseDotenv(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"API_KEY":     "abc123",
+		"DB_URL":      "postgres://db:5432/app",
+		"GREETING":    "hello\n\"world\"",
+		"LITERAL":     `no $expansion \n here`,
+		"PRIVATE_KEY": "-----BEGIN KEY-----\n  line two  \n-----END KEY-----",
+		"HASH":        "a # b",
+	}, values)
+
+	_, err = ParseDotenv("NO_EQUALS_SIGN\n")
+	assert.ErrorContains(t, err, "line 1: expected KEY=VALUE")
+
+	_, err = ParseDotenv("OK=1\nOPEN=\"never closed\nstill open\n")
+	assert.ErrorContains(t, err, "line 2: unterminated quoted value")
+}
+
+// TestParseSecretsFileFormats tests JSON and YAML files and their value types
+func TestParseSecretsFileFormats(t *testing.T) {
+	values, err := ParseSecretsFile([]byte(`{"API_KEY": "abc", "PORT": 8080, "DEBUG": true, "BIG": 12345678901}`), "json")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "abc", "PORT": "8080", "DEBUG": "true", "BIG": "12345678901"}, values)
+
+	values, err = ParseSecretsFile([]byte("API_KEY: abc\nPORT: 8080\nCERT: |\n  line one\n  line two\n"), "yaml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "abc", "PORT": "8080", "CERT": "line one\nline two\n"}, values)
+
+	_, err = ParseSecretsFile([]byte(`{"NESTED": {"a": 1}}`), "json")
+	assert.ErrorContains(t, err, "must be a string, number or boolean")
+}
+
+// TestPlanSecretImport tests name mapping and the created, overwritten and unchanged actions
+func TestPlanSecretImport(t *testing.T) {
+	mapping := SecretNameMapping{Prefix: "prod_", Uppercase: true, Rename: map[string]string{"db_url": "database_url"}}
+	existing := map[string]string{"PROD_API_KEY": "old", "PROD_DATABASE_URL": "postgres://db"}
+
+	entries, err := PlanSecretImport(map[string]string{
+		"api_key": "new",
+		"db_url":  "postgres://db",
+		"token":   "t",
+	}, mapping, SecretNameRules{}, existing)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "PROD_API_KEY", entries[0].Name)
+	assert.Equal(t, "overwritten", entries[0].Action)
+	assert.Equal(t, "PROD_DATABASE_URL", entries[1].Name)
+	assert.Equal(t, "db_url", entries[1].Source)
+	assert.Equal(t, "unchanged", entries[1].Action)
+	assert.Equal(t, "PROD_TOKEN", entries[2].Name)
+	assert.Equal(t, "created", entries[2].Action)
+
+	_, err = PlanSecretImport(map[string]string{"api_key": "a", "API_KEY": "b"}, mapping, SecretNameRules{}, nil)
+	assert.ErrorContains(t, err, "both map to secret 'PROD_API_KEY'")
+
+	_, err = PlanSecretImport(map[string]string{"EMPTY": ""}, SecretNameMapping{}, SecretNameRules{}, nil)
+	assert.ErrorContains(t, err, "'EMPTY': secret value is empty")
+}
+
+// TestSecretImportResults tests the reported actions of an import and of its dry run
+func TestSecretImportResults(t *testing.T) {
+	entries := []SecretImportEntry{
+		{Name: "API_KEY", Action: "overwritten"},
+		{Name: "DB_URL", Action: "unchanged"},
+		{Name: "TOKEN", Action: "created"},
+	}
+
+	changed, unchanged := CountSecretImport(entries)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, 1, unchanged)
+
+	results := SecretImportResults(entries, []string{"acme/api"}, false)
+	require.Len(t, results, 3)
+	assert.Equal(t, "unchanged", results[1].Action)
+	assert.False(t, results[1].DryRun)
+
+	// A dry run lists the repositories as planned, not as added
+	results = SecretImportResults(entries, []string{"acme/api"}, true)
+	require.Len(t, results, 6)
+	assert.Equal(t, "unchanged", results[1].Action)
+	for _, result := range results[3:] {
+		assert.Equal(t, "acme/api", result.Repo)
+		assert.Equal(t, "planned", result.Action)
+		assert.True(t, result.DryRun)
+	}
+}
+
+// TestPlanSecretImportNames tests that invalid names are rejected or fixed
+func TestPlanSecretImportNames(t *testing.T) {
+	values := map[string]string{"db-pass": "secret", "API_KEY": "key"}
+	rules := SecretNameRules{Prefix: "PROD_"}
+
+	_, err := PlanSecretImport(values, SecretNameMapping{}, rules, nil)
+	assert.ErrorContains(t, err, "2 invalid secret names")
+	assert.ErrorContains(t, err, "did you mean 'PROD_DB_PASS'?")
+
+	entries, err := PlanSecretImport(values, SecretNameMapping{FixNames: true}, rules, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "PROD_API_KEY", entries[0].Name)
+	assert.Equal(t, "PROD_DB_PASS", entries[1].Name)
+	assert.Equal(t, "db-pass", entries[1].Source)
+}
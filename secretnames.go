@@ -0,0 +1,137 @@
+// secretnames.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretNameRules are team conventions checked on top of GitHub's naming rules.
+// Set them per environment with the secret_name_prefix and secret_name_pattern keys of a context.
+type SecretNameRules struct {
+	Prefix  string // required prefix, e.g. PROD_
+	Pattern string // regular expression every name must match
+}
+
+// SecretNameError describes why a secret name is invalid and how it could be fixed
+type SecretNameError struct {
+	Name       string
+	Problems   []string
+	Suggestion string // empty when no valid name could be derived
+}
+
+// Error implements the error interface
+func (e *SecretNameError) Error() string {
+	msg := fmt.Sprintf("invalid secret name '%s': %s", e.Name, strings.Join(e.Problems, "; "))
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean '%s'?)", e.Suggestion)
+	}
+	return msg
+}
+
+// secretNameRules returns the naming conventions of the active configuration and context
+func secretNameRules() SecretNameRules {
+	return SecretNameRules{
+		Prefix:  viper.GetString("secret_name_prefix"),
+		Pattern: viper.GetString("secret_name_pattern"),
+	}
+}
+
+// ValidateSecretName checks a name against GitHub's rules and the team conventions.
+// Invalid names yield a *SecretNameError with a suggested fix when one exists.
+func ValidateSecretName(name string, rules SecretNameRules) error {
+	problems, err := secretNameProblems(name, rules)
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+
+	nameErr := &SecretNameError{Name: name, Problems: problems}
+	if fixed := NormalizeSecretName(name, rules); fixed != name {
+		if fixedProblems, _ := secretNameProblems(fixed, rules); len(fixedProblems) == 0 {
+			nameErr.Suggestion = fixed
+		}
+	}
+	return nameErr
+}
+
+// validateSecretNames checks all names before a batch starts, so a bad name cannot stop it halfway
+func validateSecretNames(names []string, rules SecretNameRules) error {
+	var invalid []string
+	for _, name := range names {
+		if err := ValidateSecretName(name, rules); err != nil {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d invalid secret names:\n  %s", len(invalid), strings.Join(invalid, "\n  "))
+	}
+	return nil
+}
+
+// secretNameProblems lists the rules a name breaks; an error means the rules themselves are invalid
+func secretNameProblems(name string, rules SecretNameRules) ([]string, error) {
+	if name == "" {
+		return []string{"name is empty"}, nil
+	}
+
+	var problems []string
+	if strings.IndexFunc(name, func(r rune) bool { return !isSecretNameRune(r) }) >= 0 {
+		problems = append(problems, "only letters, digits and underscores are allowed")
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		problems = append(problems, "must not start with a digit")
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		problems = append(problems, "the GITHUB_ prefix is reserved")
+	}
+	if name != strings.ToUpper(name) {
+		problems = append(problems, "must be upper case, as GitHub stores it")
+	}
+	if rules.Prefix != "" && !strings.HasPrefix(name, rules.Prefix) {
+		problems = append(problems, fmt.Sprintf("must start with '%s'", rules.Prefix))
+	}
+	if rules.Pattern != "" {
+		pattern, err := regexp.Compile(rules.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret_name_pattern: %w", err)
+		}
+		if !pattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("must match '%s'", rules.Pattern))
+		}
+	}
+	return problems, nil
+}
+
+// NormalizeSecretName derives a name that follows the rules where possible: it upper-cases the
+// name, replaces invalid characters with underscores, turns GITHUB_ into GH_, adds the required
+// prefix and puts an underscore before a leading digit. Pattern violations are not fixed.
+func NormalizeSecretName(name string, rules SecretNameRules) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(strings.TrimSpace(name)) {
+		if isSecretNameRune(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+
+	fixed := b.String()
+	if strings.HasPrefix(fixed, "GITHUB_") {
+		fixed = "GH_" + strings.TrimPrefix(fixed, "GITHUB_")
+	}
+	if rules.Prefix != "" && !strings.HasPrefix(fixed, rules.Prefix) {
+		fixed = rules.Prefix + fixed
+	}
+	if fixed != "" && fixed[0] >= '0' && fixed[0] <= '9' {
+		fixed = "_" + fixed
+	}
+	return fixed
+}
+
+// isSecretNameRune reports whether r may appear in a secret name
+func isSecretNameRune(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_'
+}
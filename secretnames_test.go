@@ -0,0 +1,60 @@
+// secretnames_test.go
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateSecretName tests GitHub's naming rules and the suggested fixes
+func TestValidateSecretName(t *testing.T) {
+	tests := []struct {
+		name       string
+		problem    string
+		suggestion string
+	}{
+		{"db pass", "only letters, digits and underscores", "DB_PASS"},
+		{"api-key", "only letters, digits and underscores", "API_KEY"},
+		{"1PASSWORD_TOKEN", "must not start with a digit", "_1PASSWORD_TOKEN"},
+		{"GITHUB_TOKEN", "GITHUB_ prefix is reserved", "GH_TOKEN"},
+		{"github_token", "GITHUB_ prefix is reserved", "GH_TOKEN"},
+		{"api_key", "must be upper case", "API_KEY"},
+		{"", "name is empty", ""},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSecretName(tt.name, SecretNameRules{})
+		var nameErr *SecretNameError
+		require.True(t, errors.As(err, &nameErr), tt.name)
+		assert.Contains(t, err.Error(), tt.problem, tt.name)
+		assert.Equal(t, tt.suggestion, nameErr.Suggestion, tt.name)
+	}
+
+	assert.NoError(t, ValidateSecretName("API_KEY", SecretNameRules{}))
+	assert.NoError(t, ValidateSecretName("_1PASSWORD", SecretNameRules{}))
+}
+
+// TestValidateSecretNameRules tests the configurable team conventions
+func TestValidateSecretNameRules(t *testing.T) {
+	rules := SecretNameRules{Prefix: "PROD_", Pattern: `^[A-Z]+_[A-Z0-9_]+$`}
+
+	assert.NoError(t, ValidateSecretName("PROD_API_KEY", rules))
+
+	err := ValidateSecretName("api_key", rules)
+	assert.ErrorContains(t, err, "must start with 'PROD_'")
+	assert.ErrorContains(t, err, "did you mean 'PROD_API_KEY'?")
+
+	// Pattern violations cannot be fixed automatically
+	var nameErr *SecretNameError
+	err = ValidateSecretName("PROD_", rules)
+	require.True(t, errors.As(err, &nameErr))
+	assert.Contains(t, nameErr.Error(), "must match")
+	assert.Empty(t, nameErr.Suggestion)
+
+	err = ValidateSecretName("API_KEY", SecretNameRules{Pattern: "[unclosed"})
+	assert.ErrorContains(t, err, "invalid secret_name_pattern")
+}
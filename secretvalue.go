@@ -0,0 +1,129 @@
+// secretvalue.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// maxSecretSize is the largest secret value GitHub accepts
+const maxSecretSize = 48 * 1024
+
+// SecretValueSource describes where a secret value is read from; at most one source may be set.
+// File, stdin and environment values are used byte for byte.
+type SecretValueSource struct {
+	Value    string
+	HasValue bool // set when --value was given, even if empty
+	File     string
+	Stdin    bool
+	Env      string
+	Command  string
+}
+
+// IsSet reports whether any source was given
+func (s SecretValueSource) IsSet() bool {
+	return s.HasValue || s.File != "" || s.Stdin || s.Env != "" || s.Command != ""
+}
+
+// checkSingle rejects more than one source
+func (s SecretValueSource) checkSingle() error {
+	count := 0
+	for _, set := range []bool{s.HasValue, s.File != "", s.Stdin, s.Env != "", s.Command != ""} {
+		if set {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("use only one of --value, --value-file, --value-stdin, --value-env and --value-cmd")
+	}
+	return nil
+}
+
+// Read returns the secret value from the configured source
+func (s SecretValueSource) Read(stdin io.Reader) (string, error) {
+	if err := s.checkSingle(); err != nil {
+		return "", err
+	}
+
+	var value string
+	switch {
+	case s.HasValue:
+		value = s.Value
+
+	case s.File != "":
+		data, err := ioutil.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("reading secret value file: %w", err)
+		}
+		value = string(data)
+
+	case s.Stdin:
+		data, err := ioutil.ReadAll(io.LimitReader(stdin, maxSecretSize+1))
+		if err != nil {
+			return "", fmt.Errorf("reading secret value from stdin: %w", err)
+		}
+		value = string(data)
+
+	case s.Env != "":
+		envValue, exists := os.LookupEnv(s.Env)
+		if !exists {
+			return "", fmt.Errorf("environment variable '%s' is not set", s.Env)
+		}
+		value = envValue
+
+	case s.Command != "":
+		output, err := runValueCommand(s.Command)
+		if err != nil {
+			return "", err
+		}
+		value = output
+
+	default:
+		return "", fmt.Errorf("no secret value source given")
+	}
+
+	if err := checkSecretSize(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// runValueCommand runs command in the shell and returns its output. Like shell command
+// substitution, a single trailing newline is dropped; everything else is kept.
+func runValueCommand(command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("secret value command failed: %w", err)
+	}
+
+	output := stdout.String()
+	if strings.HasSuffix(output, "\r\n") {
+		return strings.TrimSuffix(output, "\r\n"), nil
+	}
+	return strings.TrimSuffix(output, "\n"), nil
+}
+
+// checkSecretSize rejects values GitHub would refuse
+func checkSecretSize(value string) error {
+	if value == "" {
+		return fmt.Errorf("secret value is empty")
+	}
+	if len(value) > maxSecretSize {
+		return fmt.Errorf("secret value is %d bytes; GitHub allows at most %d bytes (48 KB)", len(value), maxSecretSize)
+	}
+	return nil
+}
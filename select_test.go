@@ -0,0 +1,37 @@
+// select_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSelectItems tests selecting saved items by name, glob pattern and --all
+func TestSelectItems(t *testing.T) {
+	available := []string{"API_KEY", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "DB_PASS"}
+
+	selected, err := SelectItems(available, nil, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, available, selected)
+
+	selected, err = SelectItems(available, []string{"DB_PASS", "API_KEY"}, false, []string{"AWS_*", "API_*"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_KEY", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "DB_PASS"}, selected)
+}
+
+// TestSelectItemsErrors tests that missing inputs fail instead of selecting nothing
+func TestSelectItemsErrors(t *testing.T) {
+	available := []string{"API_KEY", "DB_PASS"}
+
+	_, err := SelectItems(available, []string{"MISSING"}, false, nil)
+	assert.ErrorContains(t, err, "'MISSING' not found")
+
+	_, err = SelectItems(available, nil, false, []string{"AWS_*"})
+	assert.ErrorContains(t, err, "matches nothing")
+
+	_, err = SelectItems(available, nil, false, []string{"[API"})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
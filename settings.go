@@ -0,0 +1,282 @@
+// settings.go
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Types of setting values
+const (
+	SettingString  = "string"
+	SettingSecret  = "secret"  // kept in the credential store, never printed
+	SettingChoice  = "choice"  // one of Choices
+	SettingURL     = "url"     // absolute http(s) URL
+	SettingList    = "list"    // comma-separated values
+	SettingPattern = "pattern" // regular expression
+	SettingKeys    = "keys"    // comma-separated key names
+)
+
+// Sources of setting values reported by config get and config list
+const (
+	sourceConfig     = "config"
+	sourceCredential = "credential store"
+	sourceDefault    = "default"
+)
+
+// Setting describes a configuration key ghm knows about
+type Setting struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default,omitempty"`
+	Choices     []string `json:"choices,omitempty"`
+	Description string   `json:"description"`
+	Global      bool     `json:"global,omitempty"` // cannot be set per context
+}
+
+// settingsRegistry lists the known settings in the order they are shown
+var settingsRegistry = []Setting{
+	{Key: "github_token", Type: SettingSecret, Global: true,
+		Description: "GitHub token used when GITHUB_TOKEN is not set"},
+	{Key: "credential_store", Type: SettingChoice, Choices: []string{"keyring", "file"},
+		Description: "Where tokens are kept when no credential helper is set; detected when empty. The file is only as safe as its key file unless GHM_CREDENTIAL_PASSPHRASE is set"},
+	{Key: "credential_helper", Type: SettingString,
+		Description: "git credential helper that keeps tokens, as in git's credential.helper"},
+	{Key: "oauth_client_id", Type: SettingString,
+		Description: "Client ID of the OAuth app used by auth login"},
+	{Key: "oauth_scopes", Type: SettingList, Default: defaultOAuthScopes,
+		Description: "Comma-separated scopes requested by auth login"},
+	{Key: "oauth_device_code_url", Type: SettingURL, Default: defaultDeviceCodeURL,
+		Description: "Device code endpoint of the OAuth device flow"},
+	{Key: "oauth_token_url", Type: SettingURL, Default: defaultTokenURL,
+		Description: "Token endpoint of the OAuth device flow"},
+	{Key: "secret_name_prefix", Type: SettingString,
+		Description: "Prefix every new secret name must start with, e.g. PROD_"},
+	{Key: "secret_name_pattern", Type: SettingPattern,
+		Description: "Regular expression every new secret name must match"},
+	{Key: "current_context", Type: SettingString, Global: true,
+		Description: "Context applied when --context is not given"},
+	{Key: "theme", Type: SettingChoice, Choices: themeNames(), Default: "default",
+		Description: "Colors of the TUI and of CLI messages; NO_COLOR turns colors off"},
+}
+
+// secretNamePrefixPattern matches the characters GitHub allows in secret names
+var secretNamePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
+// UnknownSettingError reports a key that is not in the settings registry
+type UnknownSettingError struct {
+	Key        string
+	Suggestion string // closest known key, empty when none is close
+}
+
+// Error implements the error interface
+func (e *UnknownSettingError) Error() string {
+	msg := fmt.Sprintf("unknown setting '%s'", e.Key)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean '%s'?)", e.Suggestion)
+	}
+	return msg
+}
+
+// Settings returns the known settings in display order
+func Settings() []Setting {
+	return append([]Setting(nil), settingsRegistry...)
+}
+
+// LookupSetting finds the setting for a key. Keys of the form contexts.<name>.<key>
+// resolve to the setting they override, and keys.<action> to a TUI key binding.
+func LookupSetting(key string) (Setting, bool) {
+	name := strings.ToLower(key)
+	if action, ok := strings.CutPrefix(name, "keys."); ok {
+		binding, ok := lookupKeyAction(action)
+		if !ok {
+			return Setting{}, false
+		}
+		return Setting{Key: name, Type: SettingKeys, Default: strings.Join(binding.keys, ","), Global: true,
+			Description: "TUI keys to " + binding.help}, true
+	}
+	if parts := strings.SplitN(name, ".", 3); len(parts) == 3 && parts[0] == "contexts" {
+		setting, ok := LookupSetting(parts[2])
+		if !ok || setting.Global {
+			return Setting{}, false
+		}
+		return setting, true
+	}
+	for _, setting := range settingsRegistry {
+		if setting.Key == name {
+			return setting, true
+		}
+	}
+	return Setting{}, false
+}
+
+// ValidateSetting checks a key and value before they are stored. Unknown keys yield an
+// *UnknownSettingError unless force is set; values of known keys are always checked.
+func ValidateSetting(key, value string, force bool) error {
+	setting, ok := LookupSetting(key)
+	if !ok {
+		if force {
+			return nil
+		}
+		return &UnknownSettingError{Key: key, Suggestion: closestSetting(key)}
+	}
+	if err := setting.Validate(value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return nil
+}
+
+// Validate checks that value is acceptable for the setting; an empty value means unset
+func (s Setting) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	switch s.Type {
+	case SettingChoice:
+		for _, choice := range s.Choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(s.Choices, ", "))
+	case SettingURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("must be an http or https URL")
+		}
+	case SettingList:
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) == "" {
+				return fmt.Errorf("must not contain empty items")
+			}
+		}
+	case SettingPattern:
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("must be a valid regular expression: %v", err)
+		}
+	case SettingKeys:
+		if _, err := parseKeyList(value); err != nil {
+			return err
+		}
+	}
+
+	switch s.Key {
+	case "secret_name_prefix":
+		if !secretNamePrefixPattern.MatchString(value) {
+			return fmt.Errorf("must contain only letters, digits and underscores")
+		}
+	case "current_context":
+		for _, name := range contextNames() {
+			if name == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("context '%s' is not configured", value)
+	}
+	return nil
+}
+
+// settingValue returns the effective value of a key and where it comes from.
+// Credentials are masked; unset keys without a default have no source.
+func settingValue(key string, logger *logrus.Logger) (value, source string) {
+	if isCredentialKey(key) {
+		if getCredential(key, logger) != "" {
+			return "********", sourceCredential
+		}
+		return "", ""
+	}
+	if v := viper.Get(key); v != nil && viper.IsSet(key) {
+		return fmt.Sprint(v), sourceConfig
+	}
+	if setting, ok := LookupSetting(key); ok && setting.Default != "" {
+		return setting.Default, sourceDefault
+	}
+	return "", ""
+}
+
+// configEntries lists every known setting followed by the other keys set in the configuration
+func configEntries(logger *logrus.Logger) ConfigEntries {
+	entries := make(ConfigEntries, 0, len(settingsRegistry))
+	seen := make(map[string]bool)
+	for _, setting := range settingsRegistry {
+		entries = append(entries, configEntry(setting.Key, logger))
+		seen[setting.Key] = true
+	}
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Credentials live in the credential store and are never printed
+		if seen[key] || isCredentialKey(key) || viper.Get(key) == nil {
+			continue
+		}
+		entries = append(entries, configEntry(key, logger))
+	}
+	return entries
+}
+
+// configEntry describes one key for config get and config list
+func configEntry(key string, logger *logrus.Logger) ConfigEntry {
+	key = strings.ToLower(key)
+	value, source := settingValue(key, logger)
+	entry := ConfigEntry{Key: key, Value: value, Source: source}
+	if setting, ok := LookupSetting(key); ok {
+		entry.Description = setting.Description
+	}
+	return entry
+}
+
+// closestSetting returns the known key nearest to key, or "" when none is close enough to be a typo
+func closestSetting(key string) string {
+	key = strings.ToLower(key)
+	if parts := strings.SplitN(key, ".", 3); len(parts) == 3 && parts[0] == "contexts" {
+		if suggestion := closestSetting(parts[2]); suggestion != "" {
+			return parts[0] + "." + parts[1] + "." + suggestion
+		}
+		return ""
+	}
+
+	candidates := make([]string, 0, len(settingsRegistry)+len(keyActions))
+	for _, setting := range settingsRegistry {
+		candidates = append(candidates, setting.Key)
+	}
+	for _, action := range keyActions {
+		candidates = append(candidates, "keys."+action.name)
+	}
+
+	best, bestDistance := "", 4
+	for _, candidate := range candidates {
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
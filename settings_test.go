@@ -0,0 +1,89 @@
+// settings_test.go
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateSetting tests unknown keys, their suggestions and --force
+func TestValidateSetting(t *testing.T) {
+	var unknown *UnknownSettingError
+	err := ValidateSetting("githb_token", "x", false)
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, "github_token", unknown.Suggestion)
+	assert.EqualError(t, err, "unknown setting 'githb_token' (did you mean 'github_token'?)")
+
+	err = ValidateSetting("something_else_entirely", "x", false)
+	require.True(t, errors.As(err, &unknown))
+	assert.Empty(t, unknown.Suggestion)
+
+	assert.NoError(t, ValidateSetting("something_else_entirely", "x", true))
+	assert.NoError(t, ValidateSetting("OAUTH_CLIENT_ID", "Iv1.abc", false))
+
+	// Context keys resolve to the setting they override; global settings cannot be overridden
+	assert.NoError(t, ValidateSetting("contexts.work.secret_name_prefix", "PROD_", false))
+	assert.ErrorContains(t, ValidateSetting("contexts.work.secret_name_prefx", "PROD_", false),
+		"did you mean 'contexts.work.secret_name_prefix'?")
+	assert.Error(t, ValidateSetting("contexts.work.github_token", "x", false))
+
+	// Values of known keys are checked even with --force
+	assert.ErrorContains(t, ValidateSetting("credential_store", "vault", true), "must be one of keyring, file")
+}
+
+// TestSettingValidate tests the value checks of each setting type
+func TestSettingValidate(t *testing.T) {
+	tests := []struct {
+		key, value, problem string
+	}{
+		{"credential_store", "keyring", ""},
+		{"credential_store", "vault", "must be one of"},
+		{"oauth_token_url", "https://github.example.com/login/oauth/access_token", ""},
+		{"oauth_token_url", "github.com/login", "must be an http or https URL"},
+		{"oauth_scopes", "repo,workflow", ""},
+		{"oauth_scopes", "repo,,workflow", "must not contain empty items"},
+		{"secret_name_pattern", "^[A-Z_]+$", ""},
+		{"secret_name_pattern", "[unclosed", "must be a valid regular expression"},
+		{"secret_name_prefix", "PROD_", ""},
+		{"secret_name_prefix", "PROD-", "only letters, digits and underscores"},
+		{"current_context", "missing", "context 'missing' is not configured"},
+		{"credential_store", "", ""},
+	}
+
+	for _, tt := range tests {
+		setting, ok := LookupSetting(tt.key)
+		require.True(t, ok, tt.key)
+		err := setting.Validate(tt.value)
+		if tt.problem == "" {
+			assert.NoError(t, err, tt.key+"="+tt.value)
+		} else {
+			assert.ErrorContains(t, err, tt.problem, tt.key+"="+tt.value)
+		}
+	}
+}
+
+// TestSettingChanges tests which edits of the Settings form are stored or unset
+func TestSettingChanges(t *testing.T) {
+	current := map[string]string{"credential_store": "file", "oauth_client_id": "Iv1.old"}
+	edited := map[string]string{
+		"credential_store": "file",
+		"oauth_client_id":  "",
+		"oauth_scopes":     " repo ",
+		"github_token":     "",
+	}
+
+	changes, err := SettingChanges(current, edited)
+	require.NoError(t, err)
+	assert.Equal(t, []SettingChange{
+		{Key: "oauth_client_id", Value: ""},
+		{Key: "oauth_scopes", Value: "repo"},
+	}, changes)
+
+	edited["credential_store"] = "vault"
+	_, err = SettingChanges(current, edited)
+	assert.ErrorContains(t, err, "invalid value for credential_store")
+}
@@ -0,0 +1,574 @@
+// store.go
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// State store file and buckets
+const (
+	stateStoreFileName = "ghm.db"
+
+	// stateLockTimeout bounds how long a process waits for another ghm process to release the store
+	stateLockTimeout = 10 * time.Second
+)
+
+var (
+	reposBucket      = []byte("repos")
+	secretsBucket    = []byte("secrets")
+	workflowsBucket  = []byte("workflows")
+	metaBucket       = []byte("meta")
+	secretMetaBucket = []byte("secret_meta")
+	journalBucket    = []byte("journal")
+
+	schemaVersionKey = []byte("schema_version")
+	// helperMigratedKey holds the credential helper whose legacy credentials were moved
+	helperMigratedKey = []byte("credential_helper_migrated")
+)
+
+// StateStore is the embedded transactional store holding repositories, secrets and workflows.
+// The underlying file is locked while the store is open, so keep it open only for the duration of an operation.
+type StateStore struct {
+	db     *bolt.DB
+	Path   string
+	Logger *logrus.Logger
+}
+
+// SecretMeta holds the tags of a saved secret and when its value was created and last changed
+type SecretMeta struct {
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+// StateTx is a read or read-write transaction on the state store
+type StateTx struct {
+	tx *bolt.Tx
+}
+
+// OpenStateStore opens the state store in the data directory, importing legacy JSON files on first use
+func OpenStateStore(logger *logrus.Logger) (*StateStore, error) {
+	if err := ensureDataDir(); err != nil {
+		logger.Errorf("Error creating data directory: %v", err)
+		return nil, err
+	}
+
+	path := dataPath(stateStoreFileName)
+	_, statErr := os.Stat(path)
+	isNew := os.IsNotExist(statErr)
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: stateLockTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("state store %s is locked by another ghm process", path)
+	}
+	if err != nil {
+		logger.Errorf("Error opening state store: %v", err)
+		return nil, err
+	}
+
+	store := &StateStore{db: db, Path: path, Logger: logger}
+	if err := store.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if isNew {
+		if err := store.importLegacyDataFiles(); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// init creates the buckets and records the schema version
+func (s *StateStore) init() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{reposBucket, secretsBucket, workflowsBucket, metaBucket, secretMetaBucket, journalBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		meta := tx.Bucket(metaBucket)
+		if raw := meta.Get(schemaVersionKey); raw != nil {
+			version, err := strconv.Atoi(string(raw))
+			if err != nil {
+				return fmt.Errorf("invalid state store schema version %q", raw)
+			}
+			if version > reposSchemaVersion {
+				return fmt.Errorf("state store schema version %d is newer than supported version %d", version, reposSchemaVersion)
+			}
+		}
+		return meta.Put(schemaVersionKey, []byte(strconv.Itoa(reposSchemaVersion)))
+	})
+}
+
+// importLegacyDataFiles imports JSON state files from the data directory and renames them afterwards
+func (s *StateStore) importLegacyDataFiles() error {
+	imported, err := s.ImportJSON(dataDir(), false)
+	if err != nil {
+		s.Logger.Errorf("Error importing JSON state files: %v", err)
+		return err
+	}
+
+	for _, path := range imported {
+		backup := fmt.Sprintf("%s.imported.%s.bak", path, time.Now().UTC().Format("20060102T150405Z"))
+		if err := os.Rename(path, backup); err != nil {
+			return err
+		}
+		s.Logger.Infof("Imported %s into the state store; original kept as %s.", path, backup)
+	}
+	return nil
+}
+
+// Close releases the store and its file lock
+func (s *StateStore) Close() error {
+	return s.db.Close()
+}
+
+// View runs fn in a read-only transaction
+func (s *StateStore) View(fn func(tx *StateTx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(&StateTx{tx: tx})
+	})
+}
+
+// Update runs fn in a read-write transaction that is committed only if fn returns nil
+func (s *StateStore) Update(fn func(tx *StateTx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&StateTx{tx: tx})
+	})
+}
+
+// Check verifies the consistency of the store file
+func (s *StateStore) Check() []error {
+	var errs []error
+	s.db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	return errs
+}
+
+// Backup writes a consistent copy of the store to path
+func (s *StateStore) Backup(path string) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	})
+}
+
+// withStateStore opens the store, runs fn and closes it again
+func withStateStore(logger *logrus.Logger, fn func(store *StateStore) error) error {
+	store, err := OpenStateStore(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// StateProvider gives access to the state store for the duration of fn. Long-running code
+// such as the TUI takes one instead of opening the store itself, so that tests can run it
+// against a store of their own.
+type StateProvider interface {
+	WithStore(fn func(store *StateStore) error) error
+}
+
+// dataDirState opens the store in the data directory for each call, leaving the file
+// unlocked for other ghm processes in between
+type dataDirState struct {
+	logger *logrus.Logger
+}
+
+// DataDirState returns the provider of the state store in the data directory
+func DataDirState(logger *logrus.Logger) StateProvider {
+	return dataDirState{logger: logger}
+}
+
+// WithStore implements StateProvider
+func (s dataDirState) WithStore(fn func(store *StateStore) error) error {
+	return withStateStore(s.logger, fn)
+}
+
+// WithStore implements StateProvider for a store that is already open, such as in tests
+func (s *StateStore) WithStore(fn func(store *StateStore) error) error {
+	return fn(s)
+}
+
+// Repo returns the record of a repository
+func (t *StateTx) Repo(name string) (RepoConfig, bool, error) {
+	var config RepoConfig
+	raw := t.tx.Bucket(reposBucket).Get([]byte(name))
+	if raw == nil {
+		return config, false, nil
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return config, false, fmt.Errorf("corrupt record for repository '%s': %w", name, err)
+	}
+	return config, true, nil
+}
+
+// PutRepo stores the record of a repository
+func (t *StateTx) PutRepo(name string, config RepoConfig) error {
+	if config.Secrets == nil {
+		config.Secrets = []string{}
+	}
+	if config.Workflows == nil {
+		config.Workflows = []string{}
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(reposBucket).Put([]byte(name), raw)
+}
+
+// DeleteRepo removes the record of a repository
+func (t *StateTx) DeleteRepo(name string) error {
+	return t.tx.Bucket(reposBucket).Delete([]byte(name))
+}
+
+// Repos returns all repository records
+func (t *StateTx) Repos() (map[string]RepoConfig, error) {
+	repos := make(map[string]RepoConfig)
+	err := t.tx.Bucket(reposBucket).ForEach(func(k, v []byte) error {
+		var config RepoConfig
+		if err := json.Unmarshal(v, &config); err != nil {
+			return fmt.Errorf("corrupt record for repository '%s': %w", k, err)
+		}
+		repos[string(k)] = config
+		return nil
+	})
+	return repos, err
+}
+
+// Secret returns the value of a saved secret
+func (t *StateTx) Secret(name string) (string, bool) {
+	return getString(t.tx.Bucket(secretsBucket), name)
+}
+
+// PutSecret saves a secret value and records when it was created or last changed
+func (t *StateTx) PutSecret(name, value string) error {
+	previous, exists := t.Secret(name)
+	if exists && previous == value {
+		return nil
+	}
+
+	meta, err := t.SecretMeta(name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		meta.RotatedAt = time.Now().UTC()
+	} else {
+		meta.CreatedAt = time.Now().UTC()
+	}
+	if err := t.PutSecretMeta(name, meta); err != nil {
+		return err
+	}
+	return t.tx.Bucket(secretsBucket).Put([]byte(name), []byte(value))
+}
+
+// DeleteSecret removes a saved secret with its tags and dates
+func (t *StateTx) DeleteSecret(name string) error {
+	if err := t.tx.Bucket(secretMetaBucket).Delete([]byte(name)); err != nil {
+		return err
+	}
+	return t.tx.Bucket(secretsBucket).Delete([]byte(name))
+}
+
+// SecretMeta returns the tags and dates of a saved secret; secrets saved before they were
+// recorded have none
+func (t *StateTx) SecretMeta(name string) (SecretMeta, error) {
+	var meta SecretMeta
+	raw := t.tx.Bucket(secretMetaBucket).Get([]byte(name))
+	if raw == nil {
+		return meta, nil
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return meta, fmt.Errorf("corrupt metadata for secret '%s': %w", name, err)
+	}
+	return meta, nil
+}
+
+// PutSecretMeta stores the tags and dates of a saved secret
+func (t *StateTx) PutSecretMeta(name string, meta SecretMeta) error {
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(secretMetaBucket).Put([]byte(name), raw)
+}
+
+// SecretMetas returns the tags and dates of all saved secrets that have them
+func (t *StateTx) SecretMetas() (map[string]SecretMeta, error) {
+	metas := make(map[string]SecretMeta)
+	err := t.tx.Bucket(secretMetaBucket).ForEach(func(k, v []byte) error {
+		var meta SecretMeta
+		if err := json.Unmarshal(v, &meta); err != nil {
+			return fmt.Errorf("corrupt metadata for secret '%s': %w", k, err)
+		}
+		metas[string(k)] = meta
+		return nil
+	})
+	return metas, err
+}
+
+// Secrets returns all saved secrets
+func (t *StateTx) Secrets() map[string]string {
+	return getAll(t.tx.Bucket(secretsBucket))
+}
+
+// Workflow returns the content of a saved workflow
+func (t *StateTx) Workflow(name string) (string, bool) {
+	return getString(t.tx.Bucket(workflowsBucket), name)
+}
+
+// PutWorkflow saves a workflow
+func (t *StateTx) PutWorkflow(name, content string) error {
+	return t.tx.Bucket(workflowsBucket).Put([]byte(name), []byte(content))
+}
+
+// DeleteWorkflow removes a saved workflow
+func (t *StateTx) DeleteWorkflow(name string) error {
+	return t.tx.Bucket(workflowsBucket).Delete([]byte(name))
+}
+
+// Workflows returns all saved workflows
+func (t *StateTx) Workflows() map[string]string {
+	return getAll(t.tx.Bucket(workflowsBucket))
+}
+
+// AppendJournal records an operation under the next ID and drops the oldest entries beyond
+// journalLimit. It returns the entry with its ID.
+func (t *StateTx) AppendJournal(entry JournalEntry) (JournalEntry, error) {
+	bucket := t.tx.Bucket(journalBucket)
+	id, err := bucket.NextSequence()
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = id
+	if err := t.PutJournalEntry(entry); err != nil {
+		return entry, err
+	}
+
+	cursor := bucket.Cursor()
+	for k, _ := cursor.First(); k != nil && binary.BigEndian.Uint64(k)+journalLimit <= id; k, _ = cursor.Next() {
+		if err := cursor.Delete(); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// PutJournalEntry stores a journal entry under its ID
+func (t *StateTx) PutJournalEntry(entry JournalEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return t.tx.Bucket(journalBucket).Put(journalKey(entry.ID), raw)
+}
+
+// JournalEntry returns the journal entry with the ID
+func (t *StateTx) JournalEntry(id uint64) (JournalEntry, bool, error) {
+	var entry JournalEntry
+	raw := t.tx.Bucket(journalBucket).Get(journalKey(id))
+	if raw == nil {
+		return entry, false, nil
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return entry, false, fmt.Errorf("corrupt journal entry %d: %w", id, err)
+	}
+	return entry, true, nil
+}
+
+// Journal returns the journal entries, oldest first
+func (t *StateTx) Journal() (JournalEntries, error) {
+	var entries JournalEntries
+	err := t.tx.Bucket(journalBucket).ForEach(func(k, v []byte) error {
+		var entry JournalEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return fmt.Errorf("corrupt journal entry %d: %w", binary.BigEndian.Uint64(k), err)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// journalKey encodes a journal ID so that keys sort in ID order
+func journalKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// Meta returns a value of the meta bucket
+func (t *StateTx) Meta(key []byte) (string, bool) {
+	return getString(t.tx.Bucket(metaBucket), string(key))
+}
+
+// PutMeta stores a value in the meta bucket
+func (t *StateTx) PutMeta(key []byte, value string) error {
+	return t.tx.Bucket(metaBucket).Put(key, []byte(value))
+}
+
+// getString reads a string value from a bucket
+func getString(bucket *bolt.Bucket, key string) (string, bool) {
+	raw := bucket.Get([]byte(key))
+	if raw == nil {
+		return "", false
+	}
+	return string(raw), true
+}
+
+// getAll copies every key-value pair of a bucket; values are only valid inside the transaction
+func getAll(bucket *bolt.Bucket) map[string]string {
+	values := make(map[string]string)
+	bucket.ForEach(func(k, v []byte) error {
+		values[string(k)] = string(v)
+		return nil
+	})
+	return values
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ImportJSON merges repos.json, secrets.json and workflows.json from dir into the store.
+// Existing secrets and workflows are kept unless overwrite is set; repository records are merged.
+// It returns the paths of the files that were imported.
+func (s *StateStore) ImportJSON(dir string, overwrite bool) ([]string, error) {
+	var imported []string
+	for _, name := range []string{secretsFileName, workflowsFileName, reposFileName} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := s.ImportJSONFile(name, path, overwrite); err != nil {
+			return imported, fmt.Errorf("importing %s: %w", path, err)
+		}
+		imported = append(imported, path)
+	}
+	return imported, nil
+}
+
+// ImportJSONFile merges a single file in the JSON layout into the store; name selects the file kind
+func (s *StateStore) ImportJSONFile(name, path string, overwrite bool) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case secretsFileName, workflowsFileName:
+		values := make(map[string]string)
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		return s.Update(func(tx *StateTx) error {
+			get, put := tx.Secret, tx.PutSecret
+			if name == workflowsFileName {
+				get, put = tx.Workflow, tx.PutWorkflow
+			}
+			for key, value := range values {
+				if existing, exists := get(key); exists && !overwrite {
+					if existing != value {
+						s.Logger.Warnf("Keeping existing '%s'; %s has a different value.", key, path)
+					}
+					continue
+				}
+				if err := put(key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case reposFileName:
+		// Older repos.json layouts are upgraded before they are merged, keeping a copy of the original
+		reposConfig, from, err := UpgradeReposDocument(data, s.Logger)
+		if err != nil {
+			return err
+		}
+		if from < reposSchemaVersion {
+			backup, err := backupReposFile(path, from)
+			if err != nil {
+				s.Logger.Errorf("Error backing up %s: %v", path, err)
+				return err
+			}
+			s.Logger.Infof("Schema version %d of %s backed up as %s.", from, path, backup)
+		}
+		return s.Update(func(tx *StateTx) error {
+			for repo, imported := range reposConfig.Repositories {
+				config, exists, err := tx.Repo(repo)
+				if err != nil {
+					return err
+				}
+				if exists {
+					imported.Secrets = appendMissing(config.Secrets, imported.Secrets...)
+					imported.Workflows = appendMissing(config.Workflows, imported.Workflows...)
+					if config.LastUpdate.After(imported.LastUpdate) {
+						imported.LastUpdate = config.LastUpdate
+					}
+				}
+				if err := tx.PutRepo(repo, imported); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return fmt.Errorf("unknown state file '%s'", name)
+}
+
+// ExportJSON writes the store to dir as repos.json, secrets.json and workflows.json
+func (s *StateStore) ExportJSON(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	return s.View(func(tx *StateTx) error {
+		repos, err := tx.Repos()
+		if err != nil {
+			return err
+		}
+		reposConfig := &ReposConfig{Version: reposSchemaVersion, Repositories: repos}
+
+		files := map[string]interface{}{
+			reposFileName:     reposConfig,
+			secretsFileName:   tx.Secrets(),
+			workflowsFileName: tx.Workflows(),
+		}
+		for name, v := range files {
+			if err := writeJSONFile(filepath.Join(dir, name), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,175 @@
+// store_test.go
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStateStoreExportImport tests round-tripping the store through the JSON layout
+func TestStateStoreExportImport(t *testing.T) {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	err = store.Update(func(tx *StateTx) error {
+		require.NoError(t, tx.PutSecret("API_KEY", "value\nwith newline "))
+		require.NoError(t, tx.PutWorkflow("ci.yml", "on: push\n"))
+		return tx.PutRepo("owner/repo", RepoConfig{Secrets: []string{"API_KEY"}})
+	})
+	require.NoError(t, err)
+
+	exportDir := t.TempDir()
+	require.NoError(t, store.ExportJSON(exportDir))
+	require.NoError(t, store.Close())
+
+	// Import into a fresh store
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	store, err = OpenStateStore(logger)
+	require.NoError(t, err)
+	defer store.Close()
+
+	imported, err := store.ImportJSON(exportDir, false)
+	require.NoError(t, err)
+	assert.Len(t, imported, 3)
+
+	err = store.View(func(tx *StateTx) error {
+		value, exists := tx.Secret("API_KEY")
+		assert.True(t, exists)
+		assert.Equal(t, "value\nwith newline ", value)
+
+		repo, exists, err := tx.Repo("owner/repo")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, []string{"API_KEY"}, repo.Secrets)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestStateStoreConcurrentUpdates tests that concurrent writers do not lose updates
+func TestStateStoreConcurrentUpdates(t *testing.T) {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"A", "B", "C", "D"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			store, err := OpenStateStore(logger)
+			require.NoError(t, err)
+			defer store.Close()
+			require.NoError(t, store.Update(func(tx *StateTx) error {
+				current, _, err := tx.Repo("owner/repo")
+				if err != nil {
+					return err
+				}
+				current.Secrets = append(current.Secrets, name)
+				return tx.PutRepo("owner/repo", current)
+			}))
+		}(name)
+	}
+	wg.Wait()
+
+	reposConfig, err := LoadReposConfig(logger)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A", "B", "C", "D"}, reposConfig.Repositories["owner/repo"].Secrets)
+}
+
+// TestStateStoreSecretMeta tests that saving a secret records when it was created and changed
+func TestStateStoreSecretMeta(t *testing.T) {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	defer store.Close()
+
+	var created SecretMeta
+	err = store.Update(func(tx *StateTx) error {
+		require.NoError(t, tx.PutSecret("API_KEY", "one"))
+		created, err = tx.SecretMeta("API_KEY")
+		return err
+	})
+	require.NoError(t, err)
+	assert.False(t, created.CreatedAt.IsZero())
+	assert.True(t, created.RotatedAt.IsZero())
+
+	err = store.Update(func(tx *StateTx) error {
+		meta := created
+		meta.Tags = []string{"prod"}
+		require.NoError(t, tx.PutSecretMeta("API_KEY", meta))
+
+		// Saving the same value again is not a rotation
+		require.NoError(t, tx.PutSecret("API_KEY", "one"))
+		meta, err := tx.SecretMeta("API_KEY")
+		require.NoError(t, err)
+		assert.True(t, meta.RotatedAt.IsZero())
+
+		require.NoError(t, tx.PutSecret("API_KEY", "two"))
+		meta, err = tx.SecretMeta("API_KEY")
+		require.NoError(t, err)
+		assert.Equal(t, created.CreatedAt, meta.CreatedAt)
+		assert.False(t, meta.RotatedAt.IsZero())
+		assert.Equal(t, []string{"prod"}, meta.Tags)
+
+		require.NoError(t, tx.DeleteSecret("API_KEY"))
+		metas, err := tx.SecretMetas()
+		require.NoError(t, err)
+		assert.Empty(t, metas)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+// TestGHMUsesInjectedState tests that GHMImpl reads saved secrets from its State and journals
+// there, not in the data directory
+func TestGHMUsesInjectedState(t *testing.T) {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Update(func(tx *StateTx) error {
+		return tx.PutSecret("API_KEY", "key")
+	}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Forbidden"}`, http.StatusForbidden)
+	}))
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+
+	ghm := &GHMImpl{Token: "ghp_test", Encryptor: &EncryptorImpl{}, Logger: logger, State: store}
+	results, err := ghm.AddSecretsToRepo(context.Background(), "acme/api", []string{"API_KEY"},
+		&ReposConfig{Repositories: map[string]RepoConfig{}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].Error, "403")
+
+	entries, err := LoadJournal(store, "", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "add-secret", entries[0].Operation)
+	assert.Equal(t, "failed", entries[0].Outcome)
+}
@@ -1,5 +1,11 @@
 // tests/main_test.go
 
+//go:build e2e
+
+// These end-to-end tests build ghm and run the flat commands and JSON files it had before
+// the command groups and the state store, so they only compile with -tags e2e. The unit
+// tests live next to the code in package main.
+
 package main_test
 
 import (
@@ -21,7 +27,7 @@ import (
     // Replace "github.com/Cdaprod/secret-workflow-companion-go" with your actual module path
     mainpkg "github.com/Cdaprod/secret-workflow-companion-go"
 
-    "github.com/google/go-github/v50/github"
+    "github.com/google/go-github/v66/github"
     "github.com/sirupsen/logrus"
     "github.com/spf13/viper"
     "github.com/stretchr/testify/assert"
@@ -369,7 +375,7 @@ func TestLoadAndSaveReposConfig(t *testing.T) {
             "owner/repo": {
                 Secrets:    []string{"SECRET_KEY_1"},
                 Workflows:  []string{"ci.yml"},
-                LastUpdate: "2024-10-11T10:00:00Z",
+                LastUpdate: time.Date(2024, 10, 11, 10, 0, 0, 0, time.UTC),
             },
         },
     }
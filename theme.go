@@ -0,0 +1,134 @@
+// theme.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+)
+
+// Theme is the palette of the TUI and of the colored messages of the CLI. Colors are
+// ANSI 256-color numbers; an empty color keeps the terminal's default.
+type Theme struct {
+	Name    string
+	Accent  string // titles, the active tab and cursors
+	Muted   string // labels, hints and inactive tabs
+	Success string
+	Warning string
+	Error   string
+	Info    string // tags and informational messages
+	Bold    bool   // also set emphasized text in bold
+}
+
+// Built-in themes; the first one is the default
+var themes = []Theme{
+	{Name: "default", Accent: "205", Muted: "240", Success: "42", Warning: "214", Error: "196", Info: "39"},
+	{Name: "high-contrast", Accent: "51", Muted: "252", Success: "46", Warning: "226", Error: "203", Info: "45", Bold: true},
+	{Name: "mono", Bold: true},
+}
+
+// activeTheme is the theme applied last
+var activeTheme Theme
+
+func init() {
+	applyTheme(themes[0])
+}
+
+// themeNames returns the names of the built-in themes
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for _, theme := range themes {
+		names = append(names, theme.Name)
+	}
+	return names
+}
+
+// LookupTheme finds a built-in theme by name
+func LookupTheme(name string) (Theme, error) {
+	for _, theme := range themes {
+		if theme.Name == name {
+			return theme, nil
+		}
+	}
+	return Theme{}, fmt.Errorf("unknown theme '%s'", name)
+}
+
+// SelectTheme applies the theme of the theme setting and returns it. NO_COLOR turns
+// colors off whatever the setting.
+func SelectTheme(name string) (Theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		name = "mono"
+	}
+	if name == "" {
+		name = themes[0].Name
+	}
+	theme, err := LookupTheme(name)
+	if err != nil {
+		return activeTheme, err
+	}
+	applyTheme(theme)
+	return theme, nil
+}
+
+// applyTheme derives the TUI styles and the CLI message colors from a theme
+func applyTheme(t Theme) {
+	activeTheme = t
+	setStatusStyles(t)
+	setFormStyles(t)
+	setLogStyles(t)
+	setPickerStyles(t)
+	setRepoStyles(t)
+	setRunStyles(t)
+	setSecretStyles(t)
+	setWizardStyles(t)
+
+	HeaderColor = t.color(t.Info, color.Bold)
+	SuccessColor = t.color(t.Success)
+	ErrorColor = t.color(t.Error, color.Bold)
+	WarningColor = t.color(t.Warning)
+	InfoColor = t.color(t.Info)
+	PromptColor = t.color(t.Accent)
+}
+
+// fg returns a style with a foreground color, or a plain style for the default color
+func (t Theme) fg(c string) lipgloss.Style {
+	if c == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+}
+
+// emphasis returns the accent style, in bold where the theme asks for it
+func (t Theme) emphasis() lipgloss.Style {
+	return t.fg(t.Accent).Bold(t.Bold)
+}
+
+// border returns a style with a border in the muted color
+func (t Theme) border(b lipgloss.Border, sides ...bool) lipgloss.Style {
+	style := lipgloss.NewStyle().Border(b, sides...)
+	if t.Muted != "" {
+		style = style.BorderForeground(lipgloss.Color(t.Muted))
+	}
+	return style
+}
+
+// progressBar returns a progress bar in the theme's colors
+func (t Theme) progressBar() progress.Model {
+	fill := progress.WithDefaultGradient()
+	if t.Name != themes[0].Name {
+		fill = progress.WithSolidFill(t.Accent)
+	}
+	return progress.New(fill, progress.WithWidth(progressBarWidth))
+}
+
+// color returns a CLI message color; colors stay off when NO_COLOR is set or output is no terminal
+func (t Theme) color(c string, attrs ...color.Attribute) *color.Color {
+	if n, err := strconv.Atoi(c); err == nil {
+		attrs = append([]color.Attribute{38, 5, color.Attribute(n)}, attrs...)
+	}
+	return color.New(attrs...)
+}
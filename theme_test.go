@@ -0,0 +1,45 @@
+// theme_test.go
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSelectTheme tests choosing a theme by name and turning colors off with NO_COLOR
+func TestSelectTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Cleanup(func() { SelectTheme("") })
+
+	theme, err := SelectTheme("high-contrast")
+	require.NoError(t, err)
+	assert.Equal(t, "high-contrast", theme.Name)
+	assert.True(t, theme.Bold)
+
+	theme, err = SelectTheme("")
+	require.NoError(t, err)
+	assert.Equal(t, "default", theme.Name)
+
+	theme, err = SelectTheme("neon")
+	assert.EqualError(t, err, "unknown theme 'neon'")
+	assert.Equal(t, "default", theme.Name, "an unknown theme keeps the active one")
+
+	t.Setenv("NO_COLOR", "1")
+	theme, err = SelectTheme("high-contrast")
+	require.NoError(t, err)
+	assert.Equal(t, "mono", theme.Name)
+	assert.Empty(t, theme.Accent)
+}
+
+// TestThemeSetting tests that the theme setting accepts the built-in themes only
+func TestThemeSetting(t *testing.T) {
+	for _, name := range []string{"default", "high-contrast", "mono"} {
+		_, err := LookupTheme(name)
+		assert.NoError(t, err, name)
+		assert.NoError(t, ValidateSetting("theme", name, false), name)
+	}
+	assert.ErrorContains(t, ValidateSetting("theme", "neon", false), "must be one of default, high-contrast, mono")
+}
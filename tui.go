@@ -2,367 +2,891 @@
 package main
 
 import (
-    "context"
-    "fmt"
-    //"os"
-    "strings"
-
-    tea "github.com/charmbracelet/bubbletea"
-    "github.com/charmbracelet/lipgloss"
-    "github.com/manifoldco/promptui"
-    "github.com/sirupsen/logrus"
-    "github.com/spf13/viper"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // Define TUI tabs
-var tabs = []string{"Secrets", "Workflows", "Repositories", "Settings", "Help"}
+var tabs = []string{"Secrets", "Workflows", "Repositories", "Runs", "History", "Settings", "Help"}
+
+// Layout sizes
+const (
+	minContentHeight = 3  // lines kept for the active tab on very small terminals
+	progressBarWidth = 40 // at most; narrower terminals get a narrower bar
+)
+
+// Tab indexes
+const (
+	tabSecrets = iota
+	tabWorkflows
+	tabRepositories
+	tabRuns
+	tabHistory
+	tabSettings
+	tabHelp
+)
+
+// Status and tab bar styles, set by applyTheme
+var (
+	statusSuccessStyle lipgloss.Style
+	statusErrorStyle   lipgloss.Style
+	statusBusyStyle    lipgloss.Style
+	tabActiveStyle     lipgloss.Style
+	tabInactiveStyle   lipgloss.Style
+)
+
+// setStatusStyles derives the status and tab bar styles from a theme
+func setStatusStyles(t Theme) {
+	statusSuccessStyle = t.fg(t.Success)
+	statusErrorStyle = t.fg(t.Error).Bold(t.Bold)
+	statusBusyStyle = t.fg(t.Muted)
+	tabActiveStyle = t.fg(t.Accent).Bold(true).Underline(t.Accent == "")
+	tabInactiveStyle = t.fg(t.Muted)
+}
 
 // Define the TUI model
 type model struct {
-    tabs        []string
-    activeTab   int
-    logger      *logrus.Logger
-    reposConfig *ReposConfig
-    // Additional state can be added here for each tab
+	tabs        []string
+	activeTab   int
+	logger      *logrus.Logger
+	ghm         GHM
+	state       StateProvider
+	reposConfig *ReposConfig
+
+	// Forms of the Secrets and Workflows tabs
+	secretForm   formModel
+	workflowForm formModel
+	settings     settingsView
+	editing      bool // the form of the active tab has the keyboard
+
+	// Saved secrets listed on the Secrets tab
+	secrets secretsView
+
+	// Table, detail and bulk apply wizard of the Repositories tab
+	repos reposView
+
+	// Workflow runs of the tracked repositories, polled while the Runs tab is shown
+	runs runsView
+
+	// Journal of the operations made on GitHub, with undo
+	history historyView
+
+	// Running operation and the result of the last one
+	busy        bool
+	cancel      context.CancelFunc
+	progress    ProgressEvent
+	spinner     spinner.Model
+	progressBar progress.Model
+	status      string
+	statusError bool
+
+	// Log entries and progress events from running operations
+	events chan tea.Msg
+	logs   logPane
+
+	// Key bindings, terminal size (zero until the first WindowSizeMsg) and the Help tab scroll
+	keys       KeyMap
+	width      int
+	height     int
+	helpOffset int
+}
+
+// operationDoneMsg reports the end of an operation started from a form
+type operationDoneMsg struct {
+	result      OperationResult
+	err         error
+	reposConfig *ReposConfig // reloaded after the operation; nil if that failed
+}
+
+// resultsDoneMsg reports the end of an operation on several items, such as a bulk apply
+type resultsDoneMsg struct {
+	results     OperationResults
+	err         error
+	reposConfig *ReposConfig // reloaded after the operation; nil if that failed
+}
+
+// NewTUIModel creates the TUI for the repositories tracked in state. GitHub is only reached
+// through ghm and the state store only through state, so tests can supply their own.
+func NewTUIModel(logger *logrus.Logger, ghm GHM, state StateProvider) (tea.Model, error) {
+	reposConfig, err := LoadReposConfigFrom(state, logger)
+	if err != nil {
+		return nil, err
+	}
+	return newModel(logger, ghm, state, reposConfig), nil
 }
 
 // Initialize the TUI model
-func newModel(logger *logrus.Logger, reposConfig *ReposConfig) model {
-    return model{
-        tabs:        tabs,
-        activeTab:   0,
-        logger:      logger,
-        reposConfig: reposConfig,
-    }
+func newModel(logger *logrus.Logger, ghm GHM, state StateProvider, reposConfig *ReposConfig) model {
+	// Operations read saved items and record their changes in the store the TUI shows
+	if impl, ok := ghm.(*GHMImpl); ok {
+		impl.State = state
+	}
+	operationSpinner := spinner.New()
+	operationSpinner.Spinner = spinner.Dot
+	operationSpinner.Style = activeTheme.fg(activeTheme.Accent)
+	settings := newSettingsView()
+	settings.load(logger)
+	keys, err := configuredKeyMap()
+	if err != nil {
+		logger.Warnf("Ignoring invalid key bindings: %v", err)
+	}
+
+	return model{
+		tabs:        tabs,
+		activeTab:   0,
+		logger:      logger,
+		ghm:         ghm,
+		state:       state,
+		reposConfig: reposConfig,
+		secretForm: newForm("Add a secret",
+			textField("Repository", "owner/repo"),
+			textField("Secret name", "API_KEY"),
+			maskedField("Secret value"),
+		),
+		workflowForm: newForm("Add a workflow",
+			textField("Repository", "owner/repo"),
+			textField("Workflow file name", "ci.yml"),
+			textAreaField("Workflow YAML", "name: CI\non: [push]\n..."),
+		),
+		settings:     settings,
+		repos:        newReposView(),
+		runs:         newRunsView(),
+		spinner:      operationSpinner,
+		progressBar:  activeTheme.progressBar(),
+		events:       make(chan tea.Msg, tuiEventBuffer),
+		logs:         newLogPane(),
+		keys:         keys,
+	}
 }
 
 // Init is part of the Bubble Tea interface
 func (m model) Init() tea.Cmd {
-    return nil
+	return tea.Batch(listenForEvents(m.events), pollRuns(), loadSecrets(m.state, m.reposConfig))
+}
+
+// activeForm returns the form of the active tab, or nil if the tab has none
+func (m *model) activeForm() *formModel {
+	switch m.activeTab {
+	case tabSecrets:
+		switch m.secrets.mode {
+		case secretsAdd:
+			return &m.secretForm
+		case secretsEdit:
+			return &m.secrets.editForm
+		}
+	case tabWorkflows:
+		return &m.workflowForm
+	case tabSettings:
+		return &m.settings.form
+	}
+	return nil
+}
+
+// formFor returns the form that starts operations on items of kind
+func (m *model) formFor(kind string) *formModel {
+	switch kind {
+	case kindSecret:
+		return &m.secretForm
+	case kindWorkflow:
+		return &m.workflowForm
+	}
+	return nil
 }
 
 // Update is part of the Bubble Tea interface
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-    switch msg := msg.(type) {
-    case tea.KeyMsg:
-        switch msg.String() {
-        case "ctrl+c", "q":
-            return m, tea.Quit
-        case "left", "h":
-            m.activeTab = max(m.activeTab-1, 0)
-        case "right", "l":
-            m.activeTab = min(m.activeTab+1, len(m.tabs)-1)
-        case "enter":
-            // Handle actions based on the active tab
-            switch m.activeTab {
-            case 0:
-                // Secrets Tab
-                m.logger.Info("Secrets tab selected")
-                // Implement secrets management interaction
-                runAddSecretInteraction(m)
-            case 1:
-                // Workflows Tab
-                m.logger.Info("Workflows tab selected")
-                // Implement workflows management interaction
-                runAddWorkflowInteraction(m)
-            case 2:
-                // Repositories Tab
-                m.logger.Info("Repositories tab selected")
-                // Implement repository overview interaction
-                // For simplicity, no action
-            case 3:
-                // Settings Tab
-                m.logger.Info("Settings tab selected")
-                // Implement settings management interaction
-                runStoreConfigInteraction(m)
-            case 4:
-                // Help Tab
-                m.logger.Info("Help tab selected")
-                // No action, help is already displayed
-            }
-        }
-    }
-    return m, nil
-}
-
-// View renders the TUI
+	if result, cmd, handled := m.updateRunsMsg(msg); handled {
+		return result, cmd
+	}
+	if result, cmd, handled := m.updateSecretsMsg(msg); handled {
+		return result, cmd
+	}
+	if result, cmd, handled := m.updateWizardMsg(msg); handled {
+		return result, cmd
+	}
+	if result, cmd, handled := m.updateHistoryMsg(msg); handled {
+		return result, cmd
+	}
+
+	switch msg := msg.(type) {
+	case logEntryMsg:
+		m.logs.Append(msg)
+		return m, listenForEvents(m.events)
+
+	case progressMsg:
+		if m.busy {
+			m.progress = ProgressEvent(msg)
+		}
+		return m, listenForEvents(m.events)
+
+	case spinner.TickMsg:
+		// The spinner stops ticking once the operation is done
+		if !m.busy {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.logs.SetSize(msg.Width, msg.Height)
+		m.runs.log.Width = msg.Width
+		m.runs.log.Height = max(msg.Height-runLogChrome, 3)
+		m.progressBar.Width = max(min(progressBarWidth, msg.Width-4), 10)
+		return m, nil
+
+	case operationDoneMsg:
+		m.finishOperation()
+		if msg.reposConfig != nil {
+			m.reposConfig = msg.reposConfig
+		}
+		m.status, m.statusError = resultMessage(msg.result), msg.err != nil
+		m.repos.applyRemoteResults(OperationResults{msg.result})
+		if form := m.formFor(msg.result.Kind); form != nil && msg.err == nil {
+			form.Reset()
+			if msg.result.Kind == kindSecret && m.secrets.mode == secretsAdd {
+				m.secrets.mode = secretsList
+			}
+		}
+		return m, tea.Batch(loadSecrets(m.state, m.reposConfig), loadHistory(m.state))
+
+	case resultsDoneMsg:
+		m.finishOperation()
+		if msg.reposConfig != nil {
+			m.reposConfig = msg.reposConfig
+		}
+		m.repos.applyRemoteResults(msg.results)
+		m.status = resultsMessage(msg.results, msg.err)
+		m.statusError = msg.err != nil || msg.results.failed() > 0
+		if hasConfigResults(msg.results) {
+			m.settings.load(m.logger)
+			m.applySettings()
+		}
+		cmds := []tea.Cmd{loadSecrets(m.state, m.reposConfig), loadHistory(m.state)}
+		if hasRunResults(msg.results) {
+			cmds = append(cmds, m.fetchRuns())
+		}
+		return m, tea.Batch(cmds...)
+
+	case remoteItemsMsg:
+		m.finishOperation()
+		if msg.err != nil {
+			m.status, m.statusError = fmt.Sprintf("Failed: refreshing %s: %v", msg.repo, msg.err), true
+			return m, nil
+		}
+		if msg.repo == m.repos.detailRepo {
+			m.repos.remoteSecrets, m.repos.remoteWorkflows = msg.secrets, msg.workflows
+		}
+		m.status, m.statusError = fmt.Sprintf("Refreshed %s from GitHub.", msg.repo), false
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		if m.activeTab == tabSecrets {
+			if result, cmd, handled := m.updateSecrets(msg); handled {
+				return result, cmd
+			}
+		}
+		if m.activeTab == tabRepositories {
+			if result, cmd, handled := m.updateRepos(msg); handled {
+				return result, cmd
+			}
+		}
+		if m.activeTab == tabRuns {
+			if result, cmd, handled := m.updateRuns(msg); handled {
+				return result, cmd
+			}
+		}
+		if m.activeTab == tabHistory {
+			if result, cmd, handled := m.updateHistory(msg); handled {
+				return result, cmd
+			}
+		}
+
+		// While a form is edited, keys go to the form
+		if m.editing {
+			form := m.activeForm()
+			if msg.String() == "esc" {
+				form.Blur()
+				m.editing = false
+				if m.activeTab == tabSecrets {
+					m.secrets.mode = secretsList
+				}
+				return m, nil
+			}
+			updated, cmd, submitted := form.Update(msg)
+			*form = updated
+			if submitted {
+				return m.submit()
+			}
+			return m, cmd
+		}
+
+		switch {
+		case m.keys.Matches(msg, keyQuit):
+			return m, tea.Quit
+		case m.keys.Matches(msg, keyCancel):
+			if m.busy && m.cancel != nil {
+				m.cancel()
+				m.status = "Cancelling..."
+			}
+		case m.keys.Matches(msg, keyToggleLog):
+			m.logs.visible = !m.logs.visible
+		case m.keys.Matches(msg, keyLogUp, keyLogDown):
+			m.logs.Scroll(m.keys.Matches(msg, keyLogUp))
+		case m.keys.Matches(msg, keyPrevTab):
+			m.activeTab = max(m.activeTab-1, 0)
+		case m.keys.Matches(msg, keyNextTab):
+			m.activeTab = min(m.activeTab+1, len(m.tabs)-1)
+		case m.activeTab == tabHelp && m.keys.Matches(msg, keyUp):
+			m.helpOffset = max(m.helpOffset-1, 0)
+		case m.activeTab == tabHelp && m.keys.Matches(msg, keyDown):
+			m.helpOffset = min(m.helpOffset+1, max(len(m.helpLines())-1, 0))
+		case m.keys.Matches(msg, keySelect):
+			if form := m.activeForm(); form != nil {
+				m.editing = true
+				cmd := form.Focus()
+				return m, cmd
+			}
+		}
+
+		// Show runs right away instead of waiting for the next poll
+		if m.activeTab == tabRuns && !m.runs.loaded {
+			cmd := m.fetchRuns()
+			return m, cmd
+		}
+		if m.activeTab == tabHistory && !m.history.loaded {
+			return m, loadHistory(m.state)
+		}
+	}
+	return m, nil
+}
+
+// submit validates the form of the active tab and starts its operation in the background
+func (m model) submit() (tea.Model, tea.Cmd) {
+	form := m.activeForm()
+	if m.busy {
+		form.SetError(fmt.Errorf("another operation is still running"))
+		return m, nil
+	}
+
+	var result OperationResult
+	var run func(ctx context.Context) error
+	switch m.activeTab {
+	case tabSecrets:
+		if m.secrets.mode == secretsEdit {
+			return m.submitSecretEdit()
+		}
+		repo := strings.TrimSpace(form.Value(0))
+		name := strings.TrimSpace(form.Value(1))
+		value := form.Value(2) // used exactly as typed
+		if err := validateFormRepo(repo); err != nil {
+			form.SetError(err)
+			return m, nil
+		}
+		if err := ValidateSecretName(name, secretNameRules()); err != nil {
+			form.SetError(err)
+			return m, nil
+		}
+		if err := checkSecretSize(value); err != nil {
+			form.SetError(err)
+			return m, nil
+		}
+		result = OperationResult{Kind: kindSecret, Name: name, Repo: repo, Action: actionAdded}
+		run = func(ctx context.Context) error { return m.ghm.AddSecret(ctx, repo, name, value) }
+
+	case tabWorkflows:
+		repo := strings.TrimSpace(form.Value(0))
+		name := strings.TrimSpace(form.Value(1))
+		content := form.Value(2)
+		if err := validateFormRepo(repo); err != nil {
+			form.SetError(err)
+			return m, nil
+		}
+		if name == "" {
+			form.SetError(fmt.Errorf("workflow file name is required"))
+			return m, nil
+		}
+		if strings.TrimSpace(content) == "" {
+			form.SetError(fmt.Errorf("workflow YAML is required"))
+			return m, nil
+		}
+		result = OperationResult{Kind: kindWorkflow, Name: name, Repo: repo, Action: actionAdded}
+		run = func(ctx context.Context) error { return m.ghm.AddWorkflow(ctx, repo, name, content) }
+
+	case tabSettings:
+		return m.submitSettings()
+	}
+
+	// Keep the values on screen until the operation succeeds
+	form.SetError(nil)
+	form.Blur()
+	m.editing = false
+	state, logger := m.state, m.logger
+	return m.start(fmt.Sprintf("%s '%s'", result.Kind, result.Name), func(ctx context.Context) tea.Msg {
+		return runOperation(ctx, state, result, logger, run)
+	})
+}
+
+// start runs op in the background with a cancellable context that reports its progress
+// to the status line; only one operation runs at a time
+func (m model) start(description string, op func(ctx context.Context) tea.Msg) (tea.Model, tea.Cmd) {
+	if m.busy {
+		m.status, m.statusError = "Another operation is still running.", true
+		return m, nil
+	}
+	m.busy = true
+	m.status, m.statusError = "Running: "+description, false
+	m.progress = ProgressEvent{Percent: -1}
+
+	// Progress goes through the event channel; a full channel drops events rather than blocking
+	events := m.events
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = withProgress(ctx, func(event ProgressEvent) {
+		select {
+		case events <- progressMsg(event):
+		default:
+		}
+	})
+	m.cancel = cancel
+	return m, tea.Batch(func() tea.Msg { return op(ctx) }, m.spinner.Tick)
+}
+
+// finishOperation clears the state of the operation that just ended
+func (m *model) finishOperation() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.busy = false
+	m.cancel = nil
+	m.progress = ProgressEvent{}
+}
+
+// applySettings applies a changed theme and key bindings to the running TUI
+func (m *model) applySettings() {
+	if _, err := SelectTheme(viper.GetString("theme")); err != nil {
+		m.logger.Warnf("Ignoring the theme setting: %v", err)
+	}
+	m.spinner.Style = activeTheme.fg(activeTheme.Accent)
+	width := m.progressBar.Width
+	m.progressBar = activeTheme.progressBar()
+	m.progressBar.Width = width
+
+	keys, err := configuredKeyMap()
+	if err != nil {
+		m.logger.Warnf("Ignoring invalid key bindings: %v", err)
+	}
+	m.keys = keys
+}
+
+// validateFormRepo checks a repository entered in a form
+func validateFormRepo(repo string) error {
+	if repo == "" {
+		return fmt.Errorf("repository is required")
+	}
+	if _, _, err := splitRepo(repo); err != nil {
+		return fmt.Errorf("invalid repository format; use 'owner/repo'")
+	}
+	return nil
+}
+
+// runOperation runs an operation and reloads the tracked repositories afterwards
+func runOperation(ctx context.Context, state StateProvider, result OperationResult, logger *logrus.Logger, run func(ctx context.Context) error) tea.Msg {
+	err := run(ctx)
+	if err != nil {
+		result.Action = actionFailed
+		result.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			result.Error = "cancelled"
+		}
+	}
+
+	reposConfig, loadErr := LoadReposConfigFrom(state, logger)
+	if loadErr != nil {
+		logger.Errorf("Error reloading repos config: %v", loadErr)
+		reposConfig = nil
+	}
+	return operationDoneMsg{result: result, err: err, reposConfig: reposConfig}
+}
+
+// resultsDone reloads the tracked repositories and reports the results of an operation
+func resultsDone(state StateProvider, results OperationResults, err error, logger *logrus.Logger) tea.Msg {
+	reposConfig, loadErr := LoadReposConfigFrom(state, logger)
+	if loadErr != nil {
+		logger.Errorf("Error reloading repos config: %v", loadErr)
+		reposConfig = nil
+	}
+	return resultsDoneMsg{results: results, err: err, reposConfig: reposConfig}
+}
+
+// resultsMessage summarizes the results of an operation on several items for the status line
+func resultsMessage(results OperationResults, err error) string {
+	failed := results.failed()
+	switch {
+	case len(results) == 1 && (err == nil || results[0].Action == actionFailed):
+		return resultMessage(results[0])
+	case errors.Is(err, context.Canceled):
+		return fmt.Sprintf("Cancelled after %d items.", len(results))
+	case err != nil && len(results) == 0:
+		return "Failed: " + err.Error()
+	case err != nil:
+		return fmt.Sprintf("Failed after %d items: %v", len(results), err)
+	case failed > 0:
+		for _, result := range results {
+			if result.Action == actionFailed {
+				return fmt.Sprintf("Failed: %d of %d items; %s '%s' on %s: %s", failed, len(results), result.Kind, result.Name, result.Repo, result.Error)
+			}
+		}
+	case len(results) > 0 && results[len(results)-1].Action == actionRotated:
+		return fmt.Sprintf("Done: secret '%s' rotated and pushed to %d repositories.", results[0].Name, len(results)-1)
+	}
+	return fmt.Sprintf("Done: %d items.", len(results))
+}
+
+// resultMessage describes the result of an operation for the status line
+func resultMessage(result OperationResult) string {
+	if result.Action == actionFailed {
+		return fmt.Sprintf("Failed: %s '%s': %s", result.Kind, result.Name, result.Error)
+	}
+	target := ""
+	switch {
+	case result.Repo == "":
+	case result.Action == actionRemoved:
+		target = " from " + result.Repo
+	case result.Kind == kindRun:
+		target = " in " + result.Repo
+	default:
+		target = " to " + result.Repo
+	}
+	return fmt.Sprintf("Done: %s '%s' %s%s.", result.Kind, result.Name, result.Action, target)
+}
+
+// View renders the TUI to fit the terminal. The tab bar and status line wrap, and the
+// active tab gets the lines left over, cut to the terminal width.
 func (m model) View() string {
-    var tabUI string
-    var separator = " | "
-
-    for i, t := range m.tabs {
-        if i == m.activeTab {
-            tabUI += lipgloss.NewStyle().
-                Bold(true).
-                Foreground(lipgloss.Color("205")).
-                Render(t) + separator
-        } else {
-            tabUI += lipgloss.NewStyle().
-                Foreground(lipgloss.Color("240")).
-                Render(t) + separator
-        }
-    }
-
-    // Remove the trailing separator
-    tabUI = strings.TrimSuffix(tabUI, separator)
-
-    // Render content based on activeTab
-    var content string
-    switch m.activeTab {
-    case 0:
-        content = renderSecretsTab(m)
-    case 1:
-        content = renderWorkflowsTab(m)
-    case 2:
-        content = renderRepositoriesTab(m)
-    case 3:
-        content = renderSettingsTab(m)
-    case 4:
-        content = renderHelpTab(m)
-    default:
-        content = "Unknown Tab"
-    }
-
-    // Combine tabs and content
-    return lipgloss.JoinVertical(lipgloss.Left, tabUI, content)
-}
-
-// Render functions for each tab
-func renderSecretsTab(m model) string {
-    return "Secrets Management Interface:\n\nPress 'Enter' to add a secret."
-}
-
-func renderWorkflowsTab(m model) string {
-    return "Workflows Management Interface:\n\nPress 'Enter' to add a workflow."
-}
-
-func renderRepositoriesTab(m model) string {
-    // Example: List repositories
-    if len(m.reposConfig.Repositories) == 0 {
-        return "No repositories configured."
-    }
-
-    var repoList string
-    for repo, config := range m.reposConfig.Repositories {
-        repoList += fmt.Sprintf("Repository: %s\n  Secrets: %s\n  Workflows: %s\n\n",
-            repo,
-            strings.Join(config.Secrets, ", "),
-            strings.Join(config.Workflows, ", "))
-    }
-    return repoList
-}
-
-func renderSettingsTab(m model) string {
-    return "Settings Interface:\n\nPress 'Enter' to store a configuration key-value pair."
-}
-
-func renderHelpTab(m model) string {
-    helpText := `
-GitHub Management CLI Help
-
-- Secrets Tab:
-  Manage your GitHub secrets across repositories.
-  - Press 'Enter' to add a new secret.
-
-- Workflows Tab:
-  Manage your GitHub Actions workflows across repositories.
-  - Press 'Enter' to add a new workflow.
-
-- Repositories Tab:
-  View all repositories and their associated secrets and workflows.
-
-- Settings Tab:
-  Configure your GitHub tokens and default settings.
-  - Press 'Enter' to store a new configuration.
-
-- Help Tab:
-  Display this help information.
-
-Navigation:
-- Use Left/Right arrows or 'h'/'l' to switch between tabs.
-- Press 'Enter' to interact with the active tab.
-- Press 'q' or 'Ctrl+C' to quit the application.
-`
-    return helpText
-}
-
-// Interaction functions
-func runAddSecretInteraction(m model) {
-    // Prompt for repository
-    repo, err := promptInput("Enter repository (owner/repo): ")
-    if err != nil {
-        m.logger.Errorf("Error reading repository: %v", err)
-        return
-    }
-
-    // Prompt for secret name
-    secretName, err := promptInput("Enter secret name: ")
-    if err != nil {
-        m.logger.Errorf("Error reading secret name: %v", err)
-        return
-    }
-
-    // Prompt for secret value
-    secretValue, err := promptPassword("Enter secret value: ")
-    if err != nil {
-        m.logger.Errorf("Error reading secret value: %v", err)
-        return
-    }
-
-    // Execute the AddSecret command
-    ghm := NewGHM(viper.GetString("github_token"), m.logger)
-    err = ghm.AddSecret(context.Background(), repo, secretName, secretValue)
-    if err != nil {
-        m.logger.Errorf("Error adding secret: %v", err)
-        fmt.Println("Failed to add secret.")
-    } else {
-        SuccessColor.Println("Secret added successfully.")
-    }
-
-    // Reload reposConfig
-    updatedReposConfig, err := LoadReposConfig(m.logger)
-    if err != nil {
-        m.logger.Errorf("Error reloading repos config: %v", err)
-        return
-    }
-    m.reposConfig = updatedReposConfig
-}
-
-func runAddWorkflowInteraction(m model) {
-    // Prompt for repository
-    repo, err := promptInput("Enter repository (owner/repo): ")
-    if err != nil {
-        m.logger.Errorf("Error reading repository: %v", err)
-        return
-    }
-
-    // Prompt for workflow name
-    workflowName, err := promptInput("Enter workflow file name (e.g., ci.yml): ")
-    if err != nil {
-        m.logger.Errorf("Error reading workflow name: %v", err)
-        return
-    }
-
-    // Prompt for workflow content
-    workflowContent, err := promptMultiLineInput("Enter workflow YAML content (end with an empty line):")
-    if err != nil {
-        m.logger.Errorf("Error reading workflow content: %v", err)
-        return
-    }
-
-    // Execute the AddWorkflow command
-    ghm := NewGHM(viper.GetString("github_token"), m.logger)
-    err = ghm.AddWorkflow(context.Background(), repo, workflowName, workflowContent)
-    if err != nil {
-        m.logger.Errorf("Error adding workflow: %v", err)
-        fmt.Println("Failed to add workflow.")
-    } else {
-        SuccessColor.Println("Workflow added successfully.")
-    }
-
-    // Reload reposConfig
-    updatedReposConfig, err := LoadReposConfig(m.logger)
-    if err != nil {
-        m.logger.Errorf("Error reloading repos config: %v", err)
-        return
-    }
-    m.reposConfig = updatedReposConfig
-}
-
-func runStoreConfigInteraction(m model) {
-    // Prompt for config key
-    configKey, err := promptInput("Enter configuration key: ")
-    if err != nil {
-        m.logger.Errorf("Error reading config key: %v", err)
-        return
-    }
-
-    // Prompt for config value
-    configValue, err := promptInput("Enter configuration value: ")
-    if err != nil {
-        m.logger.Errorf("Error reading config value: %v", err)
-        return
-    }
-
-    // Execute the StoreConfig command
-    ghm := NewGHM(viper.GetString("github_token"), m.logger)
-    err = ghm.StoreConfig(context.Background(), configKey, configValue)
-    if err != nil {
-        m.logger.Errorf("Error storing config: %v", err)
-        fmt.Println("Failed to store configuration.")
-    } else {
-        SuccessColor.Println("Configuration stored successfully.")
-    }
-}
-
-// Prompt helper functions
-func promptInput(label string) (string, error) {
-    prompt := promptui.Prompt{
-        Label: label,
-    }
-    return prompt.Run()
-}
-
-func promptPassword(label string) (string, error) {
-    prompt := promptui.Prompt{
-        Label:    label,
-        Mask:     '*',
-        Validate: func(input string) error {
-            if len(input) == 0 {
-                return fmt.Errorf("value cannot be empty")
-            }
-            return nil
-        },
-    }
-    return prompt.Run()
-}
-
-func promptMultiLineInput(label string) (string, error) {
-    fmt.Println(label)
-    var lines []string
-    for {
-        var input string
-        _, err := fmt.Scanln(&input)
-        if err != nil {
-            return "", err
-        }
-        if input == "" {
-            break
-        }
-        lines = append(lines, input)
-    }
-    return strings.Join(lines, "\n"), nil
+	var tabUI string
+	var separator = " | "
+
+	for i, t := range m.tabs {
+		if i == m.activeTab {
+			tabUI += tabActiveStyle.Render(t) + separator
+		} else {
+			tabUI += tabInactiveStyle.Render(t) + separator
+		}
+	}
+
+	// Remove the trailing separator
+	tabUI = strings.TrimSuffix(tabUI, separator)
+	tabUI = wrapView(tabUI, m.width)
+
+	// Render the parts below the content first to know how much room is left for it
+	var below []string
+	if status := m.statusView(); status != "" {
+		below = append(below, wrapView(status, m.width))
+	}
+	if logs := m.logs.View(); logs != "" {
+		below = append(below, logs)
+	}
+	if footer := m.footerView(); footer != "" {
+		below = append(below, footer)
+	}
+	height := 0
+	if m.height > 0 {
+		used := lipgloss.Height(tabUI) + 1
+		for _, part := range below {
+			used += lipgloss.Height(part) + 1
+		}
+		height = max(m.height-used, minContentHeight)
+	}
+
+	// Render content based on activeTab
+	var content string
+	switch m.activeTab {
+	case tabSecrets:
+		content = renderSecretsTab(m, height)
+	case tabWorkflows:
+		form := m.workflowForm
+		form.SetSize(m.width, height)
+		content = form.View(m.editing)
+	case tabRepositories:
+		content = renderRepositoriesTab(m, height)
+	case tabRuns:
+		content = renderRunsTab(m, height)
+	case tabHistory:
+		content = renderHistoryTab(m, height)
+	case tabSettings:
+		content = renderSettingsTab(m, height)
+	case tabHelp:
+		content = renderHelpTab(m, height)
+	default:
+		content = "Unknown Tab"
+	}
+
+	// Combine tabs, content, the status line, the log pane and the key help
+	view := lipgloss.JoinVertical(lipgloss.Left, tabUI, "", fitView(content, m.width, height))
+	for _, part := range below {
+		view += "\n\n" + part
+	}
+	return view
+}
+
+// wrapView wraps text to width; zero leaves it as is
+func wrapView(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	return lipgloss.NewStyle().Width(width).Render(text)
+}
+
+// fitView cuts lines longer than width and drops the lines below height; zero means no limit
+func fitView(text string, width, height int) string {
+	if height > 0 {
+		lines := strings.Split(text, "\n")
+		if len(lines) > height {
+			text = strings.Join(lines[:height], "\n")
+		}
+	}
+	if width > 0 {
+		text = lipgloss.NewStyle().MaxWidth(width).Render(text)
+	}
+	return text
+}
+
+// listWindow returns the rows to show of a list of count rows so that the cursor stays
+// within height rows; height zero shows all rows
+func listWindow(cursor, count, height int) (start, end int) {
+	if height <= 0 || count <= height {
+		return 0, count
+	}
+	start = max(min(cursor-height/2, count-height), 0)
+	return start, start + height
+}
+
+// listPosition describes which rows of a list are shown, or "" when all of them are
+func listPosition(start, end, count int, noun string) string {
+	if start == 0 && end == count {
+		return ""
+	}
+	return fmt.Sprintf("  %d-%d of %d %s", start+1, end, count, noun)
+}
+
+// statusView renders the running operation with its progress, or the result of the last one
+func (m model) statusView() string {
+	if m.busy {
+		line := m.spinner.View() + " " + statusBusyStyle.Render(m.status)
+		if m.progress.Step != "" {
+			line += statusBusyStyle.Render(": " + m.progress.Step)
+		}
+		if m.progress.Percent >= 0 {
+			line += "\n" + m.progressBar.ViewAs(m.progress.Percent)
+		}
+		return line
+	}
+	if m.status == "" {
+		return ""
+	}
+	if m.statusError {
+		return statusErrorStyle.Render(m.status)
+	}
+	return statusSuccessStyle.Render(m.status)
+}
+
+// footerView renders the keys of the current screen from the active key map. Screens that
+// take typed text show their own keys instead.
+func (m model) footerView() string {
+	if m.editing {
+		return ""
+	}
+
+	var bindings []key.Binding
+	global := true
+	switch m.activeTab {
+	case tabSecrets:
+		bindings, global = m.secretsKeys()
+	case tabWorkflows:
+		bindings = []key.Binding{m.keys.Hint(keySelect, "add a workflow")}
+	case tabRepositories:
+		bindings, global = m.reposKeys()
+	case tabRuns:
+		bindings = m.runsKeys()
+	case tabHistory:
+		bindings, global = m.historyKeys()
+	case tabSettings:
+		bindings = []key.Binding{m.keys.Hint(keySelect, "edit settings")}
+	case tabHelp:
+		bindings = []key.Binding{m.keys.Hint(keyUp, "scroll up"), m.keys.Hint(keyDown, "scroll down")}
+	}
+	if !global {
+		return footerView(bindings, m.width)
+	}
+
+	if m.busy {
+		bindings = append(bindings, m.keys.Hint(keyCancel, "cancel"))
+	}
+	bindings = append(bindings,
+		m.keys.Hint(keyPrevTab, "prev tab"),
+		m.keys.Hint(keyNextTab, "next tab"),
+		m.keys.Hint(keyToggleLog, "log"),
+		m.keys.Hint(keyQuit, "quit"),
+	)
+	return footerView(bindings, m.width)
+}
+
+// renderHelpTab renders the part of the help text that fits, from the scroll position on
+func renderHelpTab(m model, height int) string {
+	lines := m.helpLines()
+	start := min(m.helpOffset, max(len(lines)-1, 0))
+	end := len(lines)
+	if height > 0 {
+		end = min(start+height, len(lines))
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// helpSections is the text of the Help tab by heading; the keys are listed after it
+var helpSections = []struct{ title, text string }{
+	{"Secrets Tab", "Browse the saved secrets with their tags, creation and rotation dates and the " +
+		"repositories using them. Hold the reveal key to show the selected value; it hides when the key " +
+		"is released and after 10 seconds. Edit changes the tags or rotates the value: a new value is " +
+		"pushed to every repository using the secret after a confirmation. New adds a secret to a " +
+		"repository; the value is masked while typing."},
+	{"Workflows Tab", "Add a GitHub Actions workflow to a repository. Paste or type the YAML."},
+	{"Repositories Tab", "Browse, filter and sort the tracked repositories. Opening a repository shows the " +
+		"remote status of each item, which can be refreshed from GitHub, pushed again or removed. Apply opens " +
+		"the bulk apply wizard with the selected repositories: pick repositories, tracked or on GitHub, and " +
+		"saved items, review the plan with the diff of each workflow, follow the apply repository by " +
+		"repository and save the final report as JSON."},
+	{"Runs Tab", "Follow the recent workflow runs of the tracked repositories. The list refreshes every " +
+		"15 seconds. Open a run to see its jobs and steps, and a job to see its log. Runs can be re-run " +
+		"or cancelled."},
+	{"History Tab", "Review the operations ghm made on GitHub: who ran them, when, on which repository " +
+		"and with what outcome, and what they replaced. An operation can be undone when the previous state " +
+		"is known: a replaced workflow file is restored and a newly added secret is removed, after a " +
+		"confirmation. The same journal is shown by ghm history."},
+	{"Settings Tab", "Edit the known settings. Every field starts with the configured value and shows its " +
+		"default when empty; the description of the focused setting is shown below the form. Submitting " +
+		"validates and stores the changed values, and clearing a field unsets it. Stored tokens are never shown."},
+	{"Operations", "Operations run in the background with a spinner and, where known, a progress bar, and " +
+		"can be cancelled. Log messages appear in the pane at the bottom, which can be hidden and scrolled."},
+	{"Forms", "Tab and shift+tab move between fields, enter submits from the last single-line field, " +
+		"ctrl+s submits from anywhere and esc stops editing."},
+	{"Keys", "The keys below are the active ones; change them with the keys.<action> settings, e.g. " +
+		"ghm config store --key keys.next_tab --value tab,l. Ctrl+c always quits."},
+}
+
+// helpLines returns the help text with the keys of the active key map, wrapped to the terminal
+func (m model) helpLines() []string {
+	width := 0
+	if m.width > 0 {
+		width = max(m.width-2, 20)
+	}
+
+	lines := []string{formTitleStyle.Render("GitHub Management CLI Help"), ""}
+	for _, section := range helpSections {
+		lines = append(lines, formFocusedLabelStyle.Render(section.title))
+		text := lipgloss.NewStyle().PaddingLeft(2).Render(wrapView(section.text, width))
+		lines = append(lines, strings.Split(text, "\n")...)
+		lines = append(lines, "")
+	}
+
+	labelWidth := 0
+	for _, action := range keyActions {
+		labelWidth = max(labelWidth, len(m.keys.Label(action.name)))
+	}
+	for _, action := range keyActions {
+		lines = append(lines, fmt.Sprintf("  %s  %s", formTitleStyle.Render(fmt.Sprintf("%-*s", labelWidth, m.keys.Label(action.name))), action.help))
+	}
+	return lines
+}
+
+// Initialize TUI Command
+func initTUICmd(logger *logrus.Logger) *cobra.Command {
+	tuiCmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Run the terminal user interface",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !isInteractive() {
+				return fmt.Errorf("the TUI needs a terminal; use the ghm subcommands in non-interactive mode")
+			}
+			printASCIIHeader()
+
+			// The TUI talks to GitHub from every tab, so ask for a token up front
+			ghm, err := requireGHM(logger)
+			if err != nil {
+				logger.Errorf("Failed to get GitHub token: %v", err)
+				return err
+			}
+
+			return runTUI(logger, ghm)
+		},
+	}
+
+	return tuiCmd
 }
 
 // Run the TUI program
-func runTUI(logger *logrus.Logger) error {
-    // Load reposConfig
-    reposConfig, err := LoadReposConfig(logger)
-    if err != nil {
-        logger.Errorf("Error loading repositories config: %v", err)
-        fmt.Println("Failed to load repositories config. Exiting TUI.")
-        return err // Return the error
-    }
-
-    p := tea.NewProgram(newModel(logger, reposConfig))
-    if err := p.Start(); err != nil {
-        logger.Errorf("Error running TUI: %v", err)
-        return fmt.Errorf("Error running TUI: %v", err) // Return the error
-    }
-    return nil // Return nil if no error
+func runTUI(logger *logrus.Logger, ghm GHM) error {
+	// Load reposConfig
+	state := DataDirState(logger)
+	reposConfig, err := LoadReposConfigFrom(state, logger)
+	if err != nil {
+		logger.Errorf("Error loading repositories config: %v", err)
+		return err
+	}
+
+	tuiModel := newModel(logger, ghm, state, reposConfig)
+
+	// Send log output to the log pane instead of drawing it over the screen
+	previousOutput, previousMessages := logger.Out, messageOutput
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range logger.Hooks {
+		hooks[level] = append([]logrus.Hook{}, levelHooks...)
+	}
+	hooks.Add(&logPaneHook{events: tuiModel.events})
+	previousHooks := logger.ReplaceHooks(hooks)
+	logger.SetOutput(ioutil.Discard)
+	messageOutput = ioutil.Discard
+	defer func() {
+		logger.SetOutput(previousOutput)
+		logger.ReplaceHooks(previousHooks)
+		messageOutput = previousMessages
+	}()
+
+	p := tea.NewProgram(tuiModel)
+	if _, err := p.Run(); err != nil {
+		logger.Errorf("Error running TUI: %v", err)
+		return fmt.Errorf("running TUI: %w", err)
+	}
+	return nil
 }
 
 // Helper functions
 func min(a, b int) int {
-    if a < b {
-        return a
-    }
-    return b
+	if a < b {
+		return a
+	}
+	return b
 }
 
 func max(a, b int) int {
-    if a > b {
-        return a
-    }
-    return b
-}
\ No newline at end of file
+	if a > b {
+		return a
+	}
+	return b
+}
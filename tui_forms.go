@@ -0,0 +1,287 @@
+// tui_forms.go
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Form styles, set by applyTheme
+var (
+	formTitleStyle        lipgloss.Style
+	formLabelStyle        lipgloss.Style
+	formFocusedLabelStyle lipgloss.Style
+	formErrorStyle        lipgloss.Style
+	formHintStyle         lipgloss.Style
+)
+
+// setFormStyles derives the form styles from a theme
+func setFormStyles(t Theme) {
+	formTitleStyle = t.fg(t.Accent).Bold(true)
+	formLabelStyle = t.fg(t.Muted)
+	formFocusedLabelStyle = t.fg(t.Accent).Bold(true).Underline(t.Accent == "")
+	formErrorStyle = t.fg(t.Error).Bold(t.Bold)
+	formHintStyle = t.fg(t.Muted)
+}
+
+// Largest size of text areas; they shrink to fit smaller terminals
+const (
+	formAreaWidth  = 80
+	formAreaHeight = 10
+)
+
+// formField is a labelled single-line input or multi-line text area
+type formField struct {
+	label     string
+	input     textinput.Model
+	area      textarea.Model
+	multiline bool
+}
+
+// textField creates a single-line form field
+func textField(label, placeholder string) formField {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = placeholder
+	return formField{label: label, input: input}
+}
+
+// maskedField creates a single-line form field that hides what is typed
+func maskedField(label string) formField {
+	field := textField(label, "")
+	field.input.EchoMode = textinput.EchoPassword
+	field.input.EchoCharacter = '*'
+	return field
+}
+
+// textAreaField creates a multi-line form field without a size limit, such as for workflow YAML
+func textAreaField(label, placeholder string) formField {
+	area := textarea.New()
+	area.Placeholder = placeholder
+	area.CharLimit = 0
+	area.MaxHeight = 0
+	area.SetWidth(formAreaWidth)
+	area.SetHeight(formAreaHeight)
+	return formField{label: label, area: area, multiline: true}
+}
+
+// Value returns the field content exactly as entered
+func (f formField) Value() string {
+	if f.multiline {
+		return f.area.Value()
+	}
+	return f.input.Value()
+}
+
+// formModel is a set of fields edited one at a time. Tab and shift+tab move between fields;
+// enter moves on from a single-line field and submits from the last one; ctrl+s submits
+// from anywhere.
+type formModel struct {
+	title  string
+	fields []formField
+	focus  int
+	err    string
+	width  int // zero until the terminal size is known
+	height int // lines the form may take; zero shows every field
+}
+
+// newForm creates a form; call Focus before it receives keys
+func newForm(title string, fields ...formField) formModel {
+	return formModel{title: title, fields: fields}
+}
+
+// Focus gives the keyboard to the current field
+func (f *formModel) Focus() tea.Cmd {
+	return f.focusField(f.focus)
+}
+
+// Blur takes the keyboard away from the form
+func (f *formModel) Blur() {
+	for i := range f.fields {
+		if f.fields[i].multiline {
+			f.fields[i].area.Blur()
+		} else {
+			f.fields[i].input.Blur()
+		}
+	}
+}
+
+// Reset clears all fields and the error, and moves back to the first field
+func (f *formModel) Reset() {
+	for i := range f.fields {
+		if f.fields[i].multiline {
+			f.fields[i].area.Reset()
+		} else {
+			f.fields[i].input.Reset()
+		}
+	}
+	f.err = ""
+	f.focus = 0
+}
+
+// Value returns the content of the field at index
+func (f formModel) Value(index int) string {
+	return f.fields[index].Value()
+}
+
+// SetValue replaces the content of the field at index
+func (f *formModel) SetValue(index int, value string) {
+	if f.fields[index].multiline {
+		f.fields[index].area.SetValue(value)
+	} else {
+		f.fields[index].input.SetValue(value)
+	}
+}
+
+// SetSize fits the fields to the terminal width; fields that do not fit in height are
+// scrolled so that the focused one stays visible
+func (f *formModel) SetSize(width, height int) {
+	f.width, f.height = width, height
+	areaHeight := formAreaHeight
+	if height > 0 {
+		// Shrink text areas to what is left after the title, the other fields and the hint
+		areaHeight = height - 3
+		for _, field := range f.fields {
+			areaHeight -= 3
+			if field.multiline {
+				areaHeight++
+			}
+		}
+		areaHeight = max(min(areaHeight, formAreaHeight), 3)
+	}
+
+	for i := range f.fields {
+		if f.fields[i].multiline {
+			f.fields[i].area.SetHeight(areaHeight)
+			if width > 0 {
+				f.fields[i].area.SetWidth(max(min(width-2, formAreaWidth), 20))
+			}
+		} else if width > 0 {
+			f.fields[i].input.Width = max(width-len(f.fields[i].input.Prompt)-2, 10)
+		}
+	}
+}
+
+// SetError shows a validation error below the fields
+func (f *formModel) SetError(err error) {
+	f.err = ""
+	if err != nil {
+		f.err = err.Error()
+	}
+}
+
+// focusField focuses the field at index, wrapping around at either end
+func (f *formModel) focusField(index int) tea.Cmd {
+	f.Blur()
+	f.focus = (index + len(f.fields)) % len(f.fields)
+	if f.fields[f.focus].multiline {
+		return f.fields[f.focus].area.Focus()
+	}
+	return f.fields[f.focus].input.Focus()
+}
+
+// Update passes a message to the focused field; submitted reports that the form was sent
+func (f formModel) Update(msg tea.Msg) (form formModel, cmd tea.Cmd, submitted bool) {
+	field := &f.fields[f.focus]
+
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "ctrl+s":
+			return f, nil, true
+		case "tab":
+			return f, f.focusField(f.focus + 1), false
+		case "shift+tab":
+			return f, f.focusField(f.focus - 1), false
+		case "down", "up":
+			if !field.multiline {
+				delta := 1
+				if key.String() == "up" {
+					delta = -1
+				}
+				return f, f.focusField(f.focus + delta), false
+			}
+		case "enter":
+			if !field.multiline {
+				if f.focus == len(f.fields)-1 {
+					return f, nil, true
+				}
+				return f, f.focusField(f.focus + 1), false
+			}
+		}
+	}
+
+	if field.multiline {
+		field.area, cmd = field.area.Update(msg)
+	} else {
+		field.input, cmd = field.input.Update(msg)
+	}
+	return f, cmd, false
+}
+
+// View renders the form; active is false while the form does not have the keyboard
+func (f formModel) View(active bool) string {
+	var footer strings.Builder
+	if f.err != "" {
+		footer.WriteString(formErrorStyle.Render(f.err) + "\n\n")
+	}
+	if active {
+		footer.WriteString(wrapView(formHintStyle.Render("tab: next field • enter: next/submit • ctrl+s: submit • esc: stop editing"), f.width))
+	}
+
+	blocks := make([]string, len(f.fields))
+	for i, field := range f.fields {
+		label := formLabelStyle.Render(field.label)
+		if active && i == f.focus {
+			label = formFocusedLabelStyle.Render(field.label)
+		}
+		if field.multiline {
+			blocks[i] = label + "\n" + field.area.View() + "\n\n"
+		} else {
+			blocks[i] = label + "\n" + field.input.View() + "\n\n"
+		}
+	}
+	footerHeight := 0
+	if footer.Len() > 0 {
+		footerHeight = lipgloss.Height(footer.String())
+	}
+	start, end := f.fieldWindow(blocks, f.height-2-footerHeight)
+
+	var b strings.Builder
+	b.WriteString(formTitleStyle.Render(f.title))
+	if start > 0 || end < len(blocks) {
+		b.WriteString(formHintStyle.Render(fmt.Sprintf("  fields %d-%d of %d", start+1, end, len(blocks))))
+	}
+	b.WriteString("\n\n" + strings.Join(blocks[start:end], "") + footer.String())
+	return b.String()
+}
+
+// fieldWindow returns the fields to render so that they fit in height lines and include
+// the focused field; zero or less shows all of them
+func (f formModel) fieldWindow(blocks []string, height int) (start, end int) {
+	if f.height <= 0 {
+		return 0, len(blocks)
+	}
+	start, end = f.focus, f.focus+1
+	used := lipgloss.Height(blocks[f.focus]) - 1
+	for {
+		grown := false
+		if end < len(blocks) && used+lipgloss.Height(blocks[end])-1 <= height {
+			used += lipgloss.Height(blocks[end]) - 1
+			end++
+			grown = true
+		}
+		if start > 0 && used+lipgloss.Height(blocks[start-1])-1 <= height {
+			start--
+			used += lipgloss.Height(blocks[start]) - 1
+			grown = true
+		}
+		if !grown {
+			return start, end
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// tui_forms_test.go
+
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formKeys passes keys to a form and reports whether the last one submitted it
+func formKeys(f formModel, keys ...tea.KeyMsg) (formModel, bool) {
+	submitted := false
+	for _, key := range keys {
+		f, _, submitted = f.Update(key)
+	}
+	return f, submitted
+}
+
+// runes types text as one key message
+func runes(text string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+}
+
+// TestFormNavigation tests moving between fields and submitting from the last one
+func TestFormNavigation(t *testing.T) {
+	form := newForm("Add a secret", textField("Repository", ""), textField("Name", ""), maskedField("Value"))
+	form.Focus()
+
+	form, submitted := formKeys(form, runes("acme/api"), tea.KeyMsg{Type: tea.KeyEnter}, runes("API_KEY"),
+		tea.KeyMsg{Type: tea.KeyTab}, runes("s3cret"))
+	assert.False(t, submitted)
+	assert.Equal(t, []string{"acme/api", "API_KEY", "s3cret"}, []string{form.Value(0), form.Value(1), form.Value(2)})
+
+	// The masked value is not shown
+	view := form.View(true)
+	assert.NotContains(t, view, "s3cret")
+	assert.Contains(t, view, "******")
+
+	// shift+tab wraps around from the first field
+	form, _ = formKeys(form, tea.KeyMsg{Type: tea.KeyShiftTab}, tea.KeyMsg{Type: tea.KeyShiftTab}, tea.KeyMsg{Type: tea.KeyShiftTab})
+	assert.Equal(t, 2, form.focus)
+
+	_, submitted = formKeys(form, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.True(t, submitted)
+
+	form.Reset()
+	assert.Equal(t, "", form.Value(0))
+	assert.Equal(t, 0, form.focus)
+}
+
+// TestFormTextArea tests that enter adds lines to a text area and ctrl+s submits it
+func TestFormTextArea(t *testing.T) {
+	form := newForm("Add a workflow", textField("Name", ""), textAreaField("YAML", ""))
+	form.Focus()
+
+	form, submitted := formKeys(form, tea.KeyMsg{Type: tea.KeyTab}, runes("name: CI"), tea.KeyMsg{Type: tea.KeyEnter},
+		runes("on: [push]"), tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, submitted)
+	assert.Equal(t, "name: CI\non: [push]\n", form.Value(1))
+
+	_, submitted = formKeys(form, tea.KeyMsg{Type: tea.KeyCtrlS})
+	assert.True(t, submitted)
+}
+
+// TestTUIWorkflowForm tests that the workflow form is validated, and that a valid form runs
+// the operation in the background and reports its result
+func TestTUIWorkflowForm(t *testing.T) {
+	ghm := &fakeGHM{}
+	h := newTUIHarness(t, ghm)
+
+	h.press("l", "enter")
+	h.typeText("acme/api")
+	h.press("ctrl+s")
+	assert.Contains(t, h.model.View(), "workflow file name is required")
+
+	h.press("tab")
+	h.typeText("release.yml")
+	h.press("ctrl+s")
+	assert.Contains(t, h.model.View(), "workflow YAML is required")
+
+	h.press("tab")
+	h.typeText("name: Release")
+	h.press("enter")
+	h.typeText("on: [push]")
+	h.press("ctrl+s")
+	assert.Empty(t, ghm.calls, "the operation runs as a command, not inside Update")
+	assert.Contains(t, h.model.View(), "Running: workflow 'release.yml'")
+
+	h.settle()
+	require.Equal(t, []string{"add-workflow acme/api release.yml"}, ghm.calls)
+	assert.Contains(t, h.model.View(), "Done: workflow 'release.yml' added to acme/api.")
+}
@@ -0,0 +1,239 @@
+// tui_history.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historyLimit is the number of recent operations listed on the History tab
+const historyLimit = 200
+
+// historyView is the state of the History tab: the journal, newest first, with the details
+// of the selected operation, or the confirmation of an undo
+type historyView struct {
+	entries JournalEntries
+	err     string
+	cursor  int
+	loaded  bool
+	confirm bool // waiting for the undo of the selected operation to be confirmed
+}
+
+// historyLoadedMsg carries the journal
+type historyLoadedMsg struct {
+	entries JournalEntries
+	err     error
+}
+
+// loadHistory reads the journal in the background
+func loadHistory(state StateProvider) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := LoadJournal(state, "", historyLimit)
+		return historyLoadedMsg{entries: entries, err: err}
+	}
+}
+
+// selectedEntry returns the operation under the cursor
+func (v historyView) selectedEntry() (JournalEntry, bool) {
+	if v.cursor < len(v.entries) {
+		return v.entries[v.cursor], true
+	}
+	return JournalEntry{}, false
+}
+
+// updateHistoryMsg handles the messages of the History tab; handled is false for other messages
+func (m model) updateHistoryMsg(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
+	loaded, ok := msg.(historyLoadedMsg)
+	if !ok {
+		return m, nil, false
+	}
+	m.history.loaded = true
+	m.history.err = ""
+	if loaded.err != nil {
+		m.history.err = loaded.err.Error()
+		return m, nil, true
+	}
+	m.history.entries = loaded.entries
+	m.history.cursor = min(m.history.cursor, max(len(loaded.entries)-1, 0))
+	return m, nil, true
+}
+
+// updateHistory handles a key on the History tab; handled is false for keys left to the
+// global key map
+func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if m.history.confirm {
+		switch {
+		case m.keys.Matches(msg, keyConfirm, keySelect):
+			m.history.confirm = false
+			result, cmd := m.undoOperation()
+			return result, cmd, true
+		case m.keys.Matches(msg, keyDeny, keyBack):
+			m.history.confirm = false
+		}
+		return m, nil, true
+	}
+
+	switch {
+	case m.keys.Matches(msg, keyUp):
+		m.history.cursor = max(m.history.cursor-1, 0)
+	case m.keys.Matches(msg, keyDown):
+		m.history.cursor = max(min(m.history.cursor+1, len(m.history.entries)-1), 0)
+	case m.keys.Matches(msg, keyRefresh):
+		return m, loadHistory(m.state), true
+	case m.keys.Matches(msg, keyUndo):
+		entry, ok := m.history.selectedEntry()
+		if !ok {
+			return m, nil, true
+		}
+		if _, err := entry.Undo(); err != nil {
+			m.status, m.statusError = "Cannot undo: "+err.Error(), true
+			return m, nil, true
+		}
+		m.history.confirm = true
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+// undoOperation undoes the selected operation in the background
+func (m model) undoOperation() (tea.Model, tea.Cmd) {
+	entry, ok := m.history.selectedEntry()
+	if !ok {
+		return m, nil
+	}
+	ghm, state, logger := m.ghm, m.state, m.logger
+	return m.start(fmt.Sprintf("Undoing operation %d", entry.ID), func(ctx context.Context) tea.Msg {
+		result := OperationResult{Kind: entry.Kind, Name: entry.Name, Repo: entry.Repo, Action: entry.undoAction()}
+		_, err := UndoJournalEntry(ctx, ghm, state, entry.ID, false)
+		if err != nil {
+			result.Action, result.Error = actionFailed, err.Error()
+		}
+		return resultsDone(state, OperationResults{result}, err, logger)
+	})
+}
+
+// historyKeys returns the keys of the History tab for the footer; global is false while an
+// undo waits for confirmation
+func (m model) historyKeys() (bindings []key.Binding, global bool) {
+	if m.history.confirm {
+		return []key.Binding{m.keys.Hint(keyConfirm, "undo"), m.keys.Hint(keyDeny, "keep")}, false
+	}
+	bindings = []key.Binding{m.keys.Hint(keyRefresh, "refresh")}
+	if len(m.history.entries) > 0 {
+		bindings = append([]key.Binding{
+			m.keys.Hint(keyUp, "up"),
+			m.keys.Hint(keyDown, "down"),
+			m.keys.Hint(keyUndo, "undo"),
+		}, bindings...)
+	}
+	return bindings, true
+}
+
+// renderHistoryTab renders the journal and the details of the selected operation, or the
+// undo confirmation, in height lines
+func renderHistoryTab(m model, height int) string {
+	entry, selected := m.history.selectedEntry()
+	if m.history.confirm && selected {
+		description, _ := entry.Undo()
+		return formTitleStyle.Render(fmt.Sprintf("Undo operation %d?", entry.ID)) + "\n\n" +
+			"This will " + description + " on GitHub."
+	}
+
+	var b strings.Builder
+	if m.history.err != "" {
+		b.WriteString(formErrorStyle.Render("Error loading the history: "+m.history.err) + "\n\n")
+	}
+	switch {
+	case !m.history.loaded:
+		b.WriteString(runDimStyle.Render("Loading the history..."))
+		return b.String()
+	case len(m.history.entries) == 0:
+		b.WriteString("No operations recorded.")
+		return b.String()
+	}
+
+	details := "\n\n" + historyDetails(entry)
+	header := []string{"ID", "TIME", "OPERATION", "REPOSITORY", "NAME", "OUTCOME", "UNDO"}
+	rows := make([][]string, 0, len(m.history.entries))
+	for _, entry := range m.history.entries {
+		outcome := runSuccessStyle.Render(entry.Outcome)
+		if entry.Outcome != outcomeSucceeded {
+			outcome = runFailureStyle.Render(entry.Outcome)
+		}
+		rows = append(rows, []string{fmt.Sprint(entry.ID), entry.Time.Local().Format("2006-01-02 15:04"),
+			entry.Operation, entry.Repo, entry.Name, outcome, entry.UndoStatus()})
+	}
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len(title)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	// Keep the rows that fit below the header and any error, above the details
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-lipgloss.Height(b.String())-lipgloss.Height(details)+1, 1)
+	}
+	start, end := listWindow(m.history.cursor, len(rows), rowsHeight)
+
+	line := "  "
+	for i, title := range header {
+		line += padCell(title, widths[i])
+	}
+	b.WriteString(repoHeaderStyle.Render(strings.TrimRight(line, " ")))
+	b.WriteString(formHintStyle.Render(listPosition(start, end, len(rows), "operations")) + "\n")
+	for r := start; r < end; r++ {
+		line := "  "
+		if r == m.history.cursor {
+			line = repoCursorStyle.Render("> ")
+		}
+		for i, cell := range rows[r] {
+			line += padCell(cell, widths[i])
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n") + details
+}
+
+// historyDetails describes an operation: who ran it, what it replaced and whether it can be undone
+func historyDetails(entry JournalEntry) string {
+	lines := []string{fmt.Sprintf("%s by %s at %s", entry.Operation, entry.User, entry.Time.Local().Format("2006-01-02 15:04:05"))}
+	if entry.Error != "" {
+		lines = append(lines, runFailureStyle.Render("Error: "+entry.Error))
+	}
+	if previous := entry.Previous; previous != nil {
+		switch {
+		case !previous.Exists:
+			lines = append(lines, fmt.Sprintf("The %s did not exist before.", entry.Kind))
+		case previous.Commit != "":
+			lines = append(lines, fmt.Sprintf("Replaced the %s of commit %s.", entry.Kind, shortCommit(previous.Commit)))
+		default:
+			lines = append(lines, fmt.Sprintf("Replaced an existing %s.", entry.Kind))
+		}
+	}
+	if entry.Commit != "" {
+		lines = append(lines, "Pushed as commit "+shortCommit(entry.Commit)+".")
+	}
+	if entry.Undoes != 0 {
+		lines = append(lines, fmt.Sprintf("Undid operation %d.", entry.Undoes))
+	}
+	switch description, err := entry.Undo(); {
+	case entry.UndoneAt != nil:
+		lines = append(lines, "Undone at "+entry.UndoneAt.Local().Format("2006-01-02 15:04:05")+".")
+	case err == nil:
+		lines = append(lines, "Undo: "+description+".")
+	default:
+		lines = append(lines, runDimStyle.Render("Cannot be undone: "+err.Error()+"."))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,133 @@
+// tui_log.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sirupsen/logrus"
+)
+
+// Log pane settings
+const (
+	logPaneHeight   = 8
+	logPaneMaxLines = 500
+	tuiEventBuffer  = 256
+)
+
+// Log pane styles, set by applyTheme
+var (
+	logPaneStyle    lipgloss.Style
+	logTimeStyle    lipgloss.Style
+	logWarnStyle    lipgloss.Style
+	logErrorStyle   lipgloss.Style
+	logDefaultStyle = lipgloss.NewStyle()
+)
+
+// setLogStyles derives the log pane styles from a theme
+func setLogStyles(t Theme) {
+	logPaneStyle = t.border(lipgloss.NormalBorder(), true, false, false, false)
+	logTimeStyle = t.fg(t.Muted)
+	logWarnStyle = t.fg(t.Warning)
+	logErrorStyle = t.fg(t.Error).Bold(t.Bold)
+}
+
+// logEntryMsg carries a log entry from the logger to the log pane
+type logEntryMsg struct {
+	time    time.Time
+	level   logrus.Level
+	message string
+}
+
+// progressMsg carries progress of the running operation to the TUI
+type progressMsg ProgressEvent
+
+// logPaneHook forwards log entries to the TUI instead of printing them under the screen
+type logPaneHook struct {
+	events chan<- tea.Msg
+}
+
+// Levels implements logrus.Hook
+func (h *logPaneHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook; entries are dropped rather than blocking when the TUI falls behind
+func (h *logPaneHook) Fire(entry *logrus.Entry) error {
+	select {
+	case h.events <- logEntryMsg{time: entry.Time, level: entry.Level, message: entry.Message}:
+	default:
+	}
+	return nil
+}
+
+// listenForEvents waits for the next log entry or progress event
+func listenForEvents(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// logPane is a scrollable view of the captured log entries
+type logPane struct {
+	viewport viewport.Model
+	lines    []string
+	visible  bool
+}
+
+// newLogPane creates an empty, visible log pane
+func newLogPane() logPane {
+	return logPane{viewport: viewport.New(80, logPaneHeight), visible: true}
+}
+
+// Append adds an entry and keeps the view at the bottom unless it was scrolled up
+func (p *logPane) Append(entry logEntryMsg) {
+	style := logDefaultStyle
+	switch {
+	case entry.level <= logrus.ErrorLevel:
+		style = logErrorStyle
+	case entry.level == logrus.WarnLevel:
+		style = logWarnStyle
+	}
+	line := fmt.Sprintf("%s %s", logTimeStyle.Render(entry.time.Format("15:04:05")), style.Render(entry.message))
+
+	atBottom := p.viewport.AtBottom()
+	p.lines = append(p.lines, line)
+	if len(p.lines) > logPaneMaxLines {
+		p.lines = p.lines[len(p.lines)-logPaneMaxLines:]
+	}
+	p.viewport.SetContent(strings.Join(p.lines, "\n"))
+	if atBottom {
+		p.viewport.GotoBottom()
+	}
+}
+
+// SetSize fits the pane to the terminal, taking at most a quarter of its height
+func (p *logPane) SetSize(width, height int) {
+	p.viewport.Width = width
+	p.viewport.Height = max(min(logPaneHeight, height/4), 1)
+}
+
+// Scroll moves the view by a page; up scrolls towards older entries
+func (p *logPane) Scroll(up bool) {
+	if up {
+		p.viewport.HalfViewUp()
+	} else {
+		p.viewport.HalfViewDown()
+	}
+}
+
+// View renders the pane, or nothing when hidden
+func (p logPane) View() string {
+	if !p.visible {
+		return ""
+	}
+	if len(p.lines) == 0 {
+		return logPaneStyle.Render(logTimeStyle.Render("No log messages yet."))
+	}
+	return logPaneStyle.Render(p.viewport.View())
+}
@@ -0,0 +1,525 @@
+// tui_repos.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// Repository table sort columns
+const (
+	RepoSortName = iota
+	RepoSortSecrets
+	RepoSortWorkflows
+	RepoSortUpdated
+)
+
+// repoSortNames are the column names shown in the sort hint
+var repoSortNames = []string{"name", "secrets", "workflows", "last update"}
+
+// Views of the Repositories tab
+const (
+	reposList = iota
+	reposDetail
+	reposApply
+)
+
+// Remote status of an item in the repository detail view
+const (
+	remoteUnknown    = "unknown"
+	remotePresent    = "present"
+	remoteMissing    = "missing"
+	remoteNotTracked = "not tracked"
+)
+
+// Repository table styles, set by applyTheme
+var (
+	repoHeaderStyle   lipgloss.Style
+	repoCursorStyle   lipgloss.Style
+	repoSelectedStyle lipgloss.Style
+	repoMissingStyle  lipgloss.Style
+	repoDimStyle      lipgloss.Style
+)
+
+// setRepoStyles derives the repository table styles from a theme
+func setRepoStyles(t Theme) {
+	repoHeaderStyle = t.fg(t.Muted).Bold(true)
+	repoCursorStyle = t.emphasis()
+	repoSelectedStyle = t.fg(t.Success).Bold(t.Bold)
+	repoMissingStyle = t.fg(t.Error)
+	repoDimStyle = t.fg(t.Muted)
+}
+
+// RepoRow is a tracked repository as listed in the Repositories tab
+type RepoRow struct {
+	Repo       string
+	Secrets    int
+	Workflows  int
+	LastUpdate time.Time
+}
+
+// ListRepoRows returns the tracked repositories matching query, fuzzy matched on the
+// name, in the order of the sortBy column. Ties are ordered by name.
+func ListRepoRows(reposConfig *ReposConfig, query string, sortBy int, descending bool) []RepoRow {
+	names := make([]string, 0, len(reposConfig.Repositories))
+	for repo := range reposConfig.Repositories {
+		names = append(names, repo)
+	}
+	if query != "" {
+		matched := []string{}
+		for _, match := range fuzzy.Find(query, names) {
+			matched = append(matched, match.Str)
+		}
+		names = matched
+	}
+
+	rows := make([]RepoRow, 0, len(names))
+	for _, repo := range names {
+		config := reposConfig.Repositories[repo]
+		rows = append(rows, RepoRow{Repo: repo, Secrets: len(config.Secrets), Workflows: len(config.Workflows), LastUpdate: config.LastUpdate})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if descending {
+			a, b = b, a
+		}
+		switch {
+		case sortBy == RepoSortSecrets && a.Secrets != b.Secrets:
+			return a.Secrets < b.Secrets
+		case sortBy == RepoSortWorkflows && a.Workflows != b.Workflows:
+			return a.Workflows < b.Workflows
+		case sortBy == RepoSortUpdated && !a.LastUpdate.Equal(b.LastUpdate):
+			return a.LastUpdate.Before(b.LastUpdate)
+		}
+		return a.Repo < b.Repo
+	})
+	return rows
+}
+
+// RepoItem is a secret or workflow of a repository with its status on GitHub
+type RepoItem struct {
+	Kind   string
+	Name   string
+	Remote string // one of the remote* statuses
+}
+
+// ListRepoItems returns the tracked secrets and workflows of a repository followed by the
+// ones found only on GitHub. remoteSecrets and remoteWorkflows are nil until fetched.
+func ListRepoItems(config RepoConfig, remoteSecrets, remoteWorkflows []string) []RepoItem {
+	var items []RepoItem
+	add := func(kind string, tracked, remote []string) {
+		for _, name := range tracked {
+			status := remoteUnknown
+			if remote != nil {
+				status = remoteMissing
+				if contains(remote, name) {
+					status = remotePresent
+				}
+			}
+			items = append(items, RepoItem{Kind: kind, Name: name, Remote: status})
+		}
+		untracked := []string{}
+		for _, name := range remote {
+			if !contains(tracked, name) {
+				untracked = append(untracked, name)
+			}
+		}
+		sort.Strings(untracked)
+		for _, name := range untracked {
+			items = append(items, RepoItem{Kind: kind, Name: name, Remote: remoteNotTracked})
+		}
+	}
+	add(kindSecret, config.Secrets, remoteSecrets)
+	add(kindWorkflow, config.Workflows, remoteWorkflows)
+	return items
+}
+
+// reposView is the state of the Repositories tab: a table of tracked repositories, the
+// detail view of one of them, or the bulk apply wizard
+type reposView struct {
+	mode       int
+	sortBy     int
+	descending bool
+	filter     textinput.Model
+	filtering  bool
+	cursor     int
+	selected   map[string]bool
+
+	// Detail view; the remote lists are nil until fetched from GitHub
+	detailRepo      string
+	detailCursor    int
+	remoteSecrets   []string
+	remoteWorkflows []string
+
+	// Bulk apply wizard
+	wizard applyWizard
+}
+
+// newReposView creates the Repositories tab showing the table sorted by name
+func newReposView() reposView {
+	filter := textinput.New()
+	filter.Prompt = "Filter: "
+	filter.Placeholder = "type to search, enter to keep, esc to clear"
+	return reposView{filter: filter, selected: make(map[string]bool)}
+}
+
+// remoteItemsMsg carries the secrets and workflows found on GitHub for a repository
+type remoteItemsMsg struct {
+	repo      string
+	secrets   []string
+	workflows []string
+	err       error
+}
+
+// repoRows returns the rows of the table as currently filtered and sorted
+func (m model) repoRows() []RepoRow {
+	return ListRepoRows(m.reposConfig, m.repos.filter.Value(), m.repos.sortBy, m.repos.descending)
+}
+
+// repoItems returns the items of the repository in the detail view
+func (m model) repoItems() []RepoItem {
+	return ListRepoItems(m.reposConfig.Repositories[m.repos.detailRepo], m.repos.remoteSecrets, m.repos.remoteWorkflows)
+}
+
+// updateRepos handles a key on the Repositories tab; handled is false for keys left to the
+// global key map, such as switching tabs or quitting
+func (m model) updateRepos(msg tea.KeyMsg) (result tea.Model, cmd tea.Cmd, handled bool) {
+	switch m.repos.mode {
+	case reposDetail:
+		return m.updateRepoDetail(msg)
+	case reposApply:
+		return m.updateWizard(msg)
+	}
+
+	if m.repos.filtering {
+		switch msg.String() {
+		case "esc":
+			m.repos.filter.Reset()
+			fallthrough
+		case "enter":
+			m.repos.filter.Blur()
+			m.repos.filtering = false
+			m.repos.cursor = 0
+			return m, nil, true
+		}
+		m.repos.filter, cmd = m.repos.filter.Update(msg)
+		m.repos.cursor = 0
+		return m, cmd, true
+	}
+
+	rows := m.repoRows()
+	switch {
+	case m.keys.Matches(msg, keyUp):
+		m.repos.cursor = max(m.repos.cursor-1, 0)
+	case m.keys.Matches(msg, keyDown):
+		m.repos.cursor = max(min(m.repos.cursor+1, len(rows)-1), 0)
+	case m.keys.Matches(msg, keyFilter):
+		m.repos.filtering = true
+		cmd = m.repos.filter.Focus()
+		return m, cmd, true
+	case m.keys.Matches(msg, keySort):
+		m.repos.sortBy = (m.repos.sortBy + 1) % len(repoSortNames)
+	case m.keys.Matches(msg, keyReverse):
+		m.repos.descending = !m.repos.descending
+	case m.keys.Matches(msg, keyMark):
+		if m.repos.cursor < len(rows) {
+			repo := rows[m.repos.cursor].Repo
+			if m.repos.selected[repo] {
+				delete(m.repos.selected, repo)
+			} else {
+				m.repos.selected[repo] = true
+			}
+		}
+	case m.keys.Matches(msg, keyBack):
+		m.repos.selected = make(map[string]bool)
+	case m.keys.Matches(msg, keySelect):
+		if m.repos.cursor < len(rows) {
+			m.repos.mode = reposDetail
+			m.repos.detailRepo = rows[m.repos.cursor].Repo
+			m.repos.detailCursor = 0
+			m.repos.remoteSecrets, m.repos.remoteWorkflows = nil, nil
+			if !m.busy {
+				result, cmd = m.refreshRepo()
+				return result, cmd, true
+			}
+		}
+	case m.keys.Matches(msg, keyApply):
+		cmd, err := m.openWizard(rows)
+		if err != nil {
+			m.status, m.statusError = "Failed: "+err.Error(), true
+		}
+		return m, cmd, true
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+// updateRepoDetail handles a key in the detail view of a repository
+func (m model) updateRepoDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	items := m.repoItems()
+	switch {
+	case m.keys.Matches(msg, keyUp):
+		m.repos.detailCursor = max(m.repos.detailCursor-1, 0)
+	case m.keys.Matches(msg, keyDown):
+		m.repos.detailCursor = max(min(m.repos.detailCursor+1, len(items)-1), 0)
+	case m.keys.Matches(msg, keyBack):
+		m.repos.mode = reposList
+	case m.keys.Matches(msg, keyRefresh):
+		result, cmd := m.refreshRepo()
+		return result, cmd, true
+	case m.keys.Matches(msg, keyDelete, keyPush):
+		if m.repos.detailCursor >= len(items) {
+			return m, nil, true
+		}
+		item := items[m.repos.detailCursor]
+		if m.keys.Matches(msg, keyDelete) {
+			result, cmd := m.removeRepoItem(item)
+			return result, cmd, true
+		}
+		result, cmd := m.pushRepoItems(m.repos.detailRepo, []string{item.Kind + ": " + item.Name}, []string{m.repos.detailRepo})
+		return result, cmd, true
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+// refreshRepo fetches the secrets and workflows of the repository in the detail view from GitHub
+func (m model) refreshRepo() (tea.Model, tea.Cmd) {
+	repo, ghm := m.repos.detailRepo, m.ghm
+	return m.start(fmt.Sprintf("Refreshing %s", repo), func(ctx context.Context) tea.Msg {
+		secrets, err := ghm.ListRepoSecrets(ctx, repo)
+		if err != nil {
+			return remoteItemsMsg{repo: repo, err: err}
+		}
+		workflows, err := ghm.ListRepoWorkflows(ctx, repo)
+		if err != nil {
+			return remoteItemsMsg{repo: repo, err: err}
+		}
+		// Keep the lists non-nil so that an empty repository shows items as missing
+		return remoteItemsMsg{repo: repo, secrets: append([]string{}, secrets...), workflows: append([]string{}, workflows...)}
+	})
+}
+
+// removeRepoItem removes a secret from the repository in the detail view
+func (m model) removeRepoItem(item RepoItem) (tea.Model, tea.Cmd) {
+	if item.Kind != kindSecret {
+		m.status, m.statusError = "Workflows cannot be removed from here; delete the file from the repository.", true
+		return m, nil
+	}
+	repo, ghm, state, logger := m.repos.detailRepo, m.ghm, m.state, m.logger
+	return m.start(fmt.Sprintf("Removing secret '%s' from %s", item.Name, repo), func(ctx context.Context) tea.Msg {
+		result := OperationResult{Kind: kindSecret, Name: item.Name, Repo: repo, Action: actionRemoved}
+		err := ghm.RemoveSecret(ctx, repo, item.Name)
+		if err != nil {
+			result.Action, result.Error = actionFailed, err.Error()
+		}
+		return resultsDone(state, OperationResults{result}, err, logger)
+	})
+}
+
+// pushRepoItems pushes saved items, given as "kind: name", to each repository
+func (m model) pushRepoItems(target string, items []string, repos []string) (tea.Model, tea.Cmd) {
+	secrets, workflows := splitSavedItems(items)
+	what := fmt.Sprintf("%d items", len(items))
+	if len(items) == 1 {
+		what = items[0]
+	}
+	ghm, state, logger := m.ghm, m.state, m.logger
+	return m.start(fmt.Sprintf("Pushing %s to %s", what, target), func(ctx context.Context) tea.Msg {
+		var results OperationResults
+		for _, repo := range repos {
+			if err := ctx.Err(); err != nil {
+				return resultsDone(state, results, err, logger)
+			}
+			// The TUI reloads the tracked repositories afterwards, so the copy can be thrown away
+			scratch := &ReposConfig{Repositories: make(map[string]RepoConfig)}
+			if len(secrets) > 0 {
+				added, err := ghm.AddSecretsToRepo(ctx, repo, secrets, scratch)
+				if err != nil {
+					return resultsDone(state, results, err, logger)
+				}
+				results = append(results, added...)
+			}
+			if len(workflows) > 0 {
+				added, err := ghm.AddWorkflowsToRepo(ctx, repo, workflows, scratch)
+				if err != nil {
+					return resultsDone(state, results, err, logger)
+				}
+				results = append(results, added...)
+			}
+		}
+		return resultsDone(state, results, nil, logger)
+	})
+}
+
+// applyRemoteResults updates the remote status shown in the detail view after an operation
+func (v *reposView) applyRemoteResults(results OperationResults) {
+	for _, result := range results {
+		if result.Repo != v.detailRepo {
+			continue
+		}
+		remote := &v.remoteSecrets
+		if result.Kind == kindWorkflow {
+			remote = &v.remoteWorkflows
+		}
+		if *remote == nil {
+			continue
+		}
+		switch result.Action {
+		case actionAdded:
+			*remote = appendMissing(*remote, result.Name)
+		case actionRemoved:
+			*remote = removeItem(*remote, result.Name)
+		}
+	}
+}
+
+// reposKeys returns the keys of the current Repositories view for the footer; global is
+// false when the view takes every key
+func (m model) reposKeys() (bindings []key.Binding, global bool) {
+	switch {
+	case m.repos.mode == reposApply:
+		return m.wizardKeys()
+	case m.repos.filtering:
+		return nil, false
+	case m.repos.mode == reposDetail:
+		return []key.Binding{
+			m.keys.Hint(keyRefresh, "refresh from GitHub"),
+			m.keys.Hint(keyPush, "re-push"),
+			m.keys.Hint(keyDelete, "remove secret"),
+			m.keys.Hint(keyBack, "back"),
+		}, true
+	}
+	bindings = []key.Binding{
+		m.keys.Hint(keySelect, "details"),
+		m.keys.Hint(keyMark, "select"),
+		m.keys.Hint(keyApply, "apply to selected"),
+		m.keys.Hint(keyFilter, "filter"),
+		m.keys.Hint(keySort, "sort column"),
+		m.keys.Hint(keyReverse, "reverse"),
+	}
+	if len(m.repos.selected) > 0 {
+		bindings = append(bindings, m.keys.Hint(keyBack, "clear selection"))
+	}
+	return bindings, true
+}
+
+// renderRepositoriesTab renders the table, detail view or bulk apply wizard of the Repositories
+// tab in height lines
+func renderRepositoriesTab(m model, height int) string {
+	switch m.repos.mode {
+	case reposDetail:
+		return renderRepoDetail(m, height)
+	case reposApply:
+		return renderWizard(m, height)
+	}
+
+	if len(m.reposConfig.Repositories) == 0 {
+		return "No repositories configured."
+	}
+
+	rows := m.repoRows()
+	nameWidth := len("REPOSITORY")
+	for _, row := range rows {
+		nameWidth = max(nameWidth, len(row.Repo))
+	}
+	format := fmt.Sprintf("%%s%%-%ds  %%7s  %%9s  %%s", nameWidth)
+
+	var b strings.Builder
+	if m.repos.filtering || m.repos.filter.Value() != "" {
+		b.WriteString(m.repos.filter.View() + "\n\n")
+	}
+	b.WriteString(repoHeaderStyle.Render(fmt.Sprintf(format, "    ", "REPOSITORY", "SECRETS", "WORKFLOWS", "LAST UPDATE")) + "\n")
+	if len(rows) == 0 {
+		b.WriteString(repoDimStyle.Render("    No repositories match the filter.") + "\n")
+	}
+
+	// Keep the rows that fit between the filter and header above and the summary below
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-lipgloss.Height(b.String())-1, 1)
+	}
+	start, end := listWindow(m.repos.cursor, len(rows), rowsHeight)
+	for i := start; i < end; i++ {
+		row := rows[i]
+		marker := "  "
+		if i == m.repos.cursor {
+			marker = repoCursorStyle.Render("> ")
+		}
+		check := "  "
+		if m.repos.selected[row.Repo] {
+			check = repoSelectedStyle.Render("* ")
+		}
+		lastUpdate := "-"
+		if !row.LastUpdate.IsZero() {
+			lastUpdate = row.LastUpdate.Local().Format("2006-01-02 15:04")
+		}
+		line := fmt.Sprintf(format, "", row.Repo, fmt.Sprint(row.Secrets), fmt.Sprint(row.Workflows), lastUpdate)
+		if m.repos.selected[row.Repo] {
+			line = repoSelectedStyle.Render(line)
+		}
+		b.WriteString(marker + check + line + "\n")
+	}
+
+	order := "ascending"
+	if m.repos.descending {
+		order = "descending"
+	}
+	b.WriteString("\n" + repoDimStyle.Render(fmt.Sprintf(
+		"%d of %d repositories, %d selected • sorted by %s, %s%s",
+		len(rows), len(m.reposConfig.Repositories), len(m.repos.selected), repoSortNames[m.repos.sortBy], order,
+		listPosition(start, end, len(rows), "shown"))))
+	return b.String()
+}
+
+// renderRepoDetail renders the secrets and workflows of a repository with their remote
+// status in height lines
+func renderRepoDetail(m model, height int) string {
+	var b strings.Builder
+	b.WriteString(formTitleStyle.Render(m.repos.detailRepo) + "\n\n")
+
+	items := m.repoItems()
+	if len(items) == 0 {
+		b.WriteString(repoDimStyle.Render("No secrets or workflows tracked.") + "\n")
+	}
+	nameWidth := 0
+	for _, item := range items {
+		nameWidth = max(nameWidth, len(item.Name))
+	}
+	// Keep the items that fit below the title
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-2, 1)
+	}
+	start, end := listWindow(m.repos.detailCursor, len(items), rowsHeight)
+	for i := start; i < end; i++ {
+		item := items[i]
+		marker := "  "
+		if i == m.repos.detailCursor {
+			marker = repoCursorStyle.Render("> ")
+		}
+		status := item.Remote
+		switch item.Remote {
+		case remotePresent:
+			status = repoSelectedStyle.Render(status)
+		case remoteMissing:
+			status = repoMissingStyle.Render(status)
+		default:
+			status = repoDimStyle.Render(status)
+		}
+		b.WriteString(fmt.Sprintf("%s%-8s  %-*s  %s\n", marker, item.Kind, nameWidth, item.Name, status))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,73 @@
+// tui_repos_test.go
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var repoRowsTestConfig = &ReposConfig{Repositories: map[string]RepoConfig{
+	"acme/api":     {Secrets: []string{"A", "B"}, Workflows: []string{"ci.yml"}, LastUpdate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	"acme/web":     {Secrets: []string{"A"}, LastUpdate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	"other/worker": {Secrets: []string{"A", "B"}, Workflows: []string{"ci.yml", "deploy.yml"}},
+}}
+
+// repoNames returns the repository names of rows in order
+func repoNames(rows []RepoRow) []string {
+	names := []string{}
+	for _, row := range rows {
+		names = append(names, row.Repo)
+	}
+	return names
+}
+
+// TestListRepoRowsSort tests sorting by each column, with ties ordered by name
+func TestListRepoRowsSort(t *testing.T) {
+	rows := ListRepoRows(repoRowsTestConfig, "", RepoSortName, false)
+	assert.Equal(t, []string{"acme/api", "acme/web", "other/worker"}, repoNames(rows))
+	assert.Equal(t, RepoRow{Repo: "acme/api", Secrets: 2, Workflows: 1, LastUpdate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, rows[0])
+
+	rows = ListRepoRows(repoRowsTestConfig, "", RepoSortSecrets, true)
+	assert.Equal(t, []string{"other/worker", "acme/api", "acme/web"}, repoNames(rows))
+
+	rows = ListRepoRows(repoRowsTestConfig, "", RepoSortWorkflows, false)
+	assert.Equal(t, []string{"acme/web", "acme/api", "other/worker"}, repoNames(rows))
+
+	rows = ListRepoRows(repoRowsTestConfig, "", RepoSortUpdated, true)
+	assert.Equal(t, []string{"acme/web", "acme/api", "other/worker"}, repoNames(rows))
+}
+
+// TestListRepoRowsFilter tests fuzzy filtering on the repository name
+func TestListRepoRowsFilter(t *testing.T) {
+	rows := ListRepoRows(repoRowsTestConfig, "acme", RepoSortName, false)
+	assert.Equal(t, []string{"acme/api", "acme/web"}, repoNames(rows))
+
+	rows = ListRepoRows(repoRowsTestConfig, "owkr", RepoSortName, false)
+	assert.Equal(t, []string{"other/worker"}, repoNames(rows))
+
+	rows = ListRepoRows(repoRowsTestConfig, "nothing", RepoSortName, false)
+	assert.Empty(t, rows)
+}
+
+// TestListRepoItems tests the remote status of tracked and untracked items
+func TestListRepoItems(t *testing.T) {
+	config := RepoConfig{Secrets: []string{"A", "B"}, Workflows: []string{"ci.yml"}}
+
+	items := ListRepoItems(config, nil, nil)
+	assert.Equal(t, []RepoItem{
+		{Kind: "secret", Name: "A", Remote: "unknown"},
+		{Kind: "secret", Name: "B", Remote: "unknown"},
+		{Kind: "workflow", Name: "ci.yml", Remote: "unknown"},
+	}, items)
+
+	items = ListRepoItems(config, []string{"Z", "A"}, []string{})
+	assert.Equal(t, []RepoItem{
+		{Kind: "secret", Name: "A", Remote: "present"},
+		{Kind: "secret", Name: "B", Remote: "missing"},
+		{Kind: "secret", Name: "Z", Remote: "not tracked"},
+		{Kind: "workflow", Name: "ci.yml", Remote: "missing"},
+	}, items)
+}
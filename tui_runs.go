@@ -0,0 +1,516 @@
+// tui_runs.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Runs tab settings
+const (
+	runsPollInterval = 15 * time.Second
+	runsPollTimeout  = 30 * time.Second
+	runLogHeight     = 20 // until the terminal size is known
+	runLogChrome     = 10 // lines around the job log: tab bar, title, scroll position, status and footer
+)
+
+// Views of the Runs tab
+const (
+	runsList = iota
+	runsJobs
+	runsLog
+)
+
+// Run state styles, set by applyTheme
+var (
+	runSuccessStyle lipgloss.Style
+	runFailureStyle lipgloss.Style
+	runActiveStyle  lipgloss.Style
+	runDimStyle     lipgloss.Style
+	runCursorStyle  lipgloss.Style
+)
+
+// setRunStyles derives the run state styles from a theme
+func setRunStyles(t Theme) {
+	runSuccessStyle = t.fg(t.Success)
+	runFailureStyle = t.fg(t.Error).Bold(t.Bold)
+	runActiveStyle = t.fg(t.Warning)
+	runDimStyle = t.fg(t.Muted)
+	runCursorStyle = t.emphasis()
+}
+
+// runsView is the state of the Runs tab: recent runs of the tracked repositories, the jobs
+// and steps of one run, or the log of one job
+type runsView struct {
+	mode        int
+	runs        []WorkflowRun
+	cursor      int
+	fetching    bool
+	loaded      bool
+	lastRefresh time.Time
+	errors      []string // repositories whose runs could not be listed
+
+	// Jobs view of the selected run
+	run        WorkflowRun
+	jobs       []WorkflowJob
+	jobsErr    string
+	jobCursor  int
+	jobsLoaded bool
+
+	// Log view of the selected job
+	logJob string
+	log    viewport.Model
+}
+
+// newRunsView creates the Runs tab; runs are fetched when the tab is first shown
+func newRunsView() runsView {
+	return runsView{log: viewport.New(100, runLogHeight)}
+}
+
+// runsPollMsg triggers the next refresh of the Runs tab
+type runsPollMsg time.Time
+
+// runsMsg carries the runs listed for the tracked repositories
+type runsMsg struct {
+	runs    []WorkflowRun
+	changed bool
+	errors  []string
+	at      time.Time
+}
+
+// jobsMsg carries the jobs of a run
+type jobsMsg struct {
+	runID int64
+	jobs  []WorkflowJob
+	err   error
+}
+
+// jobLogMsg carries the log of a job
+type jobLogMsg struct {
+	job string
+	log string
+	err error
+}
+
+// pollRuns schedules the next refresh of the Runs tab
+func pollRuns() tea.Cmd {
+	return tea.Tick(runsPollInterval, func(t time.Time) tea.Msg { return runsPollMsg(t) })
+}
+
+// fetchRuns lists the recent runs of every tracked repository. Unchanged lists are answered
+// from the ETag cache and do not count against the rate limit.
+func (m *model) fetchRuns() tea.Cmd {
+	if m.runs.fetching {
+		return nil
+	}
+	m.runs.fetching = true
+
+	repos := make([]string, 0, len(m.reposConfig.Repositories))
+	for repo := range m.reposConfig.Repositories {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	ghm := m.ghm
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), runsPollTimeout)
+		defer cancel()
+
+		msg := runsMsg{}
+		for _, repo := range repos {
+			runs, changed, err := ghm.ListWorkflowRuns(ctx, repo)
+			if err != nil {
+				msg.errors = append(msg.errors, fmt.Sprintf("%s: %v", repo, err))
+				continue
+			}
+			msg.runs = append(msg.runs, runs...)
+			msg.changed = msg.changed || changed
+		}
+		sort.SliceStable(msg.runs, func(i, j int) bool { return msg.runs[i].StartedAt.After(msg.runs[j].StartedAt) })
+		msg.at = time.Now()
+		return msg
+	}
+}
+
+// fetchJobs lists the jobs of the run in the jobs view
+func (m model) fetchJobs() tea.Cmd {
+	run, ghm := m.runs.run, m.ghm
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), runsPollTimeout)
+		defer cancel()
+		jobs, err := ghm.ListRunJobs(ctx, run.Repo, run.ID)
+		return jobsMsg{runID: run.ID, jobs: jobs, err: err}
+	}
+}
+
+// updateRunsMsg handles the messages of the Runs tab; handled is false for other messages
+func (m model) updateRunsMsg(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
+	switch msg := msg.(type) {
+	case runsPollMsg:
+		cmds := []tea.Cmd{pollRuns()}
+		if m.activeTab == tabRuns {
+			cmds = append(cmds, m.fetchRuns())
+			if m.runs.mode == runsJobs {
+				cmds = append(cmds, m.fetchJobs())
+			}
+		}
+		return m, tea.Batch(cmds...), true
+
+	case runsMsg:
+		m.runs.fetching = false
+		m.runs.loaded = true
+		m.runs.lastRefresh = msg.at
+		m.runs.errors = msg.errors
+		if msg.changed || len(msg.runs) != len(m.runs.runs) {
+			// Keep the cursor on the same run when new runs come in
+			var selected int64
+			if m.runs.cursor < len(m.runs.runs) {
+				selected = m.runs.runs[m.runs.cursor].ID
+			}
+			m.runs.runs = msg.runs
+			m.runs.cursor = 0
+			for i, run := range msg.runs {
+				if run.ID == selected {
+					m.runs.cursor = i
+				}
+			}
+		}
+		return m, nil, true
+
+	case jobsMsg:
+		if msg.runID != m.runs.run.ID {
+			return m, nil, true
+		}
+		m.runs.jobsLoaded = true
+		m.runs.jobsErr = ""
+		if msg.err != nil {
+			m.runs.jobsErr = msg.err.Error()
+			return m, nil, true
+		}
+		m.runs.jobs = msg.jobs
+		m.runs.jobCursor = min(m.runs.jobCursor, max(len(msg.jobs)-1, 0))
+		// Keep the run status in the title in step with its jobs
+		for _, run := range m.runs.runs {
+			if run.ID == m.runs.run.ID {
+				m.runs.run = run
+			}
+		}
+		return m, nil, true
+
+	case jobLogMsg:
+		m.finishOperation()
+		if msg.err != nil {
+			m.status, m.statusError = fmt.Sprintf("Failed: log of job '%s': %v", msg.job, msg.err), true
+			return m, nil, true
+		}
+		m.status, m.statusError = "", false
+		m.runs.mode = runsLog
+		m.runs.logJob = msg.job
+		m.runs.log.SetContent(msg.log)
+		m.runs.log.GotoBottom()
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// updateRuns handles a key on the Runs tab; handled is false for keys left to the global key map
+func (m model) updateRuns(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch m.runs.mode {
+	case runsLog:
+		switch {
+		case m.keys.Matches(msg, keyBack):
+			m.runs.mode = runsJobs
+			return m, nil, true
+		case m.keys.Matches(msg, keyPrevTab, keyNextTab, keyQuit, keyToggleLog, keyCancel):
+			return m, nil, false
+		case m.keys.Matches(msg, keyUp):
+			m.runs.log.LineUp(1)
+			return m, nil, true
+		case m.keys.Matches(msg, keyDown):
+			m.runs.log.LineDown(1)
+			return m, nil, true
+		}
+		var cmd tea.Cmd
+		m.runs.log, cmd = m.runs.log.Update(msg)
+		return m, cmd, true
+
+	case runsJobs:
+		switch {
+		case m.keys.Matches(msg, keyUp):
+			m.runs.jobCursor = max(m.runs.jobCursor-1, 0)
+		case m.keys.Matches(msg, keyDown):
+			m.runs.jobCursor = max(min(m.runs.jobCursor+1, len(m.runs.jobs)-1), 0)
+		case m.keys.Matches(msg, keyBack):
+			m.runs.mode = runsList
+		case m.keys.Matches(msg, keyRefresh):
+			return m, m.fetchJobs(), true
+		case m.keys.Matches(msg, keyRerun, keyCancelRun):
+			result, cmd := m.runAction(m.runs.run, m.keys.Matches(msg, keyRerun))
+			return result, cmd, true
+		case m.keys.Matches(msg, keySelect):
+			if m.runs.jobCursor < len(m.runs.jobs) {
+				result, cmd := m.openJobLog(m.runs.jobs[m.runs.jobCursor])
+				return result, cmd, true
+			}
+		default:
+			return m, nil, false
+		}
+		return m, nil, true
+	}
+
+	switch {
+	case m.keys.Matches(msg, keyUp):
+		m.runs.cursor = max(m.runs.cursor-1, 0)
+	case m.keys.Matches(msg, keyDown):
+		m.runs.cursor = max(min(m.runs.cursor+1, len(m.runs.runs)-1), 0)
+	case m.keys.Matches(msg, keyRefresh):
+		cmd := m.fetchRuns()
+		return m, cmd, true
+	case m.keys.Matches(msg, keyRerun, keyCancelRun):
+		if m.runs.cursor < len(m.runs.runs) {
+			result, cmd := m.runAction(m.runs.runs[m.runs.cursor], m.keys.Matches(msg, keyRerun))
+			return result, cmd, true
+		}
+	case m.keys.Matches(msg, keySelect):
+		if m.runs.cursor < len(m.runs.runs) {
+			m.runs.mode = runsJobs
+			m.runs.run = m.runs.runs[m.runs.cursor]
+			m.runs.jobs, m.runs.jobsErr, m.runs.jobCursor, m.runs.jobsLoaded = nil, "", 0, false
+			return m, m.fetchJobs(), true
+		}
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+// runAction re-runs or cancels a run in the background
+func (m model) runAction(run WorkflowRun, rerun bool) (tea.Model, tea.Cmd) {
+	name := fmt.Sprintf("%s #%d", run.Workflow, run.Number)
+	ghm, state, logger := m.ghm, m.state, m.logger
+	verb, action := "Cancelling", actionCancelled
+	if rerun {
+		verb, action = "Re-running", actionRerun
+	}
+	return m.start(fmt.Sprintf("%s %s in %s", verb, name, run.Repo), func(ctx context.Context) tea.Msg {
+		result := OperationResult{Kind: kindRun, Name: name, Repo: run.Repo, Action: action}
+		var err error
+		if rerun {
+			err = ghm.RerunWorkflowRun(ctx, run.Repo, run.ID)
+		} else {
+			err = ghm.CancelWorkflowRun(ctx, run.Repo, run.ID)
+		}
+		if err != nil {
+			result.Action, result.Error = actionFailed, err.Error()
+		}
+		return resultsDone(state, OperationResults{result}, err, logger)
+	})
+}
+
+// openJobLog downloads the log of a job in the background and shows it when done
+func (m model) openJobLog(job WorkflowJob) (tea.Model, tea.Cmd) {
+	run, ghm := m.runs.run, m.ghm
+	return m.start(fmt.Sprintf("Downloading the log of job '%s'", job.Name), func(ctx context.Context) tea.Msg {
+		log, err := ghm.JobLog(ctx, run.Repo, job.ID)
+		return jobLogMsg{job: job.Name, log: log, err: err}
+	})
+}
+
+// hasRunResults reports whether results include a run that was re-run or cancelled
+func hasRunResults(results OperationResults) bool {
+	for _, result := range results {
+		if result.Kind == kindRun {
+			return true
+		}
+	}
+	return false
+}
+
+// runStateView renders the state of a run, job or step as a symbol and word
+func runStateView(state string) string {
+	switch state {
+	case "success":
+		return runSuccessStyle.Render("✓ " + state)
+	case "failure", "timed_out", "startup_failure", "action_required":
+		return runFailureStyle.Render("✗ " + state)
+	case "in_progress":
+		return runActiveStyle.Render("● " + state)
+	case "cancelled", "skipped", "neutral", "stale":
+		return runDimStyle.Render("⊘ " + state)
+	}
+	return runDimStyle.Render("○ " + state)
+}
+
+// formatRunDuration renders a duration in whole seconds
+func formatRunDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
+
+// runsKeys returns the keys of the current Runs view for the footer
+func (m model) runsKeys() []key.Binding {
+	switch m.runs.mode {
+	case runsLog:
+		return []key.Binding{m.keys.Hint(keyUp, "scroll up"), m.keys.Hint(keyDown, "scroll down"), m.keys.Hint(keyBack, "back")}
+	case runsJobs:
+		return []key.Binding{
+			m.keys.Hint(keySelect, "view job log"),
+			m.keys.Hint(keyRefresh, "refresh"),
+			m.keys.Hint(keyRerun, "re-run"),
+			m.keys.Hint(keyCancelRun, "cancel run"),
+			m.keys.Hint(keyBack, "back"),
+		}
+	}
+	return []key.Binding{
+		m.keys.Hint(keySelect, "jobs and steps"),
+		m.keys.Hint(keyRefresh, "refresh"),
+		m.keys.Hint(keyRerun, "re-run"),
+		m.keys.Hint(keyCancelRun, "cancel run"),
+	}
+}
+
+// renderRunsTab renders the list, jobs or log view of the Runs tab in height lines
+func renderRunsTab(m model, height int) string {
+	switch m.runs.mode {
+	case runsJobs:
+		return renderRunJobs(m, height)
+	case runsLog:
+		log := m.runs.log
+		if height > 0 {
+			log.Height = max(min(log.Height, height-4), 1)
+		}
+		return formTitleStyle.Render("Log of job '"+m.runs.logJob+"'") + "\n\n" + log.View() + "\n\n" +
+			runDimStyle.Render(fmt.Sprintf("%3.f%%", log.ScrollPercent()*100))
+	}
+
+	if len(m.reposConfig.Repositories) == 0 {
+		return "No repositories configured."
+	}
+
+	var b strings.Builder
+	switch {
+	case !m.runs.loaded:
+		b.WriteString(runDimStyle.Render("Loading workflow runs...") + "\n")
+	case m.runs.fetching:
+		b.WriteString(runDimStyle.Render("Refreshing...") + "\n")
+	default:
+		b.WriteString(runDimStyle.Render(fmt.Sprintf("Updated %s • refreshes every %s", m.runs.lastRefresh.Format("15:04:05"), runsPollInterval)) + "\n")
+	}
+	for _, err := range m.runs.errors {
+		b.WriteString(runFailureStyle.Render("Error: "+err) + "\n")
+	}
+	b.WriteString("\n")
+
+	if m.runs.loaded && len(m.runs.runs) == 0 {
+		b.WriteString(runDimStyle.Render("No workflow runs found.") + "\n")
+	}
+	if len(m.runs.runs) > 0 {
+		// Keep the runs that fit below the refresh line, the errors and the table header
+		rowsHeight := 0
+		if height > 0 {
+			rowsHeight = max(height-lipgloss.Height(b.String()), 1)
+		}
+		b.WriteString(renderRunTable(m.runs.runs, m.runs.cursor, time.Now(), rowsHeight))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// renderRunTable renders the runs around the cursor that fit in height rows; zero shows all
+func renderRunTable(runs []WorkflowRun, cursor int, now time.Time, height int) string {
+	start, end := listWindow(cursor, len(runs), height)
+
+	header := []string{"STATE", "REPOSITORY", "WORKFLOW", "RUN", "BRANCH", "ACTOR", "DURATION", "STARTED"}
+	rows := [][]string{}
+	for _, run := range runs[start:end] {
+		started := "-"
+		if !run.StartedAt.IsZero() {
+			started = run.StartedAt.Local().Format("2006-01-02 15:04")
+		}
+		rows = append(rows, []string{runStateView(run.State()), run.Repo, run.Workflow, fmt.Sprintf("#%d", run.Number), run.Branch, run.Actor, formatRunDuration(run.Duration(now)), started})
+	}
+
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len(title)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	var b strings.Builder
+	line := "  "
+	for i, title := range header {
+		line += padCell(title, widths[i])
+	}
+	b.WriteString(repoHeaderStyle.Render(strings.TrimRight(line, " ")))
+	b.WriteString(runDimStyle.Render(listPosition(start, end, len(runs), "runs")) + "\n")
+	for r, row := range rows {
+		marker := "  "
+		if start+r == cursor {
+			marker = runCursorStyle.Render("> ")
+		}
+		line := marker
+		for i, cell := range row {
+			line += padCell(cell, widths[i])
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// renderRunJobs renders the jobs of the selected run with their steps in height lines,
+// scrolled so that the job under the cursor is shown
+func renderRunJobs(m model, height int) string {
+	run := m.runs.run
+	var b strings.Builder
+	b.WriteString(formTitleStyle.Render(fmt.Sprintf("%s #%d", run.Workflow, run.Number)) + "  " +
+		runDimStyle.Render(fmt.Sprintf("%s • %s • %s by %s", run.Repo, run.Branch, run.Event, run.Actor)) + "\n\n")
+
+	switch {
+	case m.runs.jobsErr != "":
+		b.WriteString(runFailureStyle.Render("Error: "+m.runs.jobsErr) + "\n")
+	case !m.runs.jobsLoaded:
+		b.WriteString(runDimStyle.Render("Loading jobs...") + "\n")
+	case len(m.runs.jobs) == 0:
+		b.WriteString(runDimStyle.Render("No jobs.") + "\n")
+	}
+
+	now := time.Now()
+	var lines []string
+	cursorLine := 0
+	for i, job := range m.runs.jobs {
+		marker := "  "
+		if i == m.runs.jobCursor {
+			marker = runCursorStyle.Render("> ")
+			cursorLine = len(lines)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s  %s  %s", marker, runStateView(job.State()), job.Name, runDimStyle.Render(formatRunDuration(job.Duration(now)))))
+		for _, step := range job.Steps {
+			lines = append(lines, fmt.Sprintf("      %s  %s", runStateView(step.State()), step.Name))
+		}
+	}
+
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-lipgloss.Height(b.String())+1, 1)
+	}
+	start, end := listWindow(cursorLine, len(lines), rowsHeight)
+	b.WriteString(strings.Join(lines[start:end], "\n"))
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// padCell pads a possibly styled table cell to width and adds the column gap
+func padCell(cell string, width int) string {
+	return cell + strings.Repeat(" ", max(width-lipgloss.Width(cell), 0)+2)
+}
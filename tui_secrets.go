@@ -0,0 +1,486 @@
+// tui_secrets.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Reveal-on-hold timing. Terminals report no key releases, so a held key is recognized
+// by its auto-repeat: the value hides once no repeat came within revealHoldWindow, and
+// after revealTimeout even while the key is still held.
+const (
+	revealHoldWindow    = 600 * time.Millisecond
+	revealTimeout       = 10 * time.Second
+	revealCheckInterval = 100 * time.Millisecond
+)
+
+// Views of the Secrets tab
+const (
+	secretsList = iota
+	secretsAdd
+	secretsEdit
+	secretsConfirm
+)
+
+// Secrets list styles, set by applyTheme
+var (
+	secretRevealStyle lipgloss.Style
+	secretTagStyle    lipgloss.Style
+)
+
+// setSecretStyles derives the secrets list styles from a theme
+func setSecretStyles(t Theme) {
+	secretRevealStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	if t.Warning != "" {
+		secretRevealStyle = secretRevealStyle.BorderForeground(lipgloss.Color(t.Warning))
+	}
+	secretTagStyle = t.fg(t.Info)
+}
+
+// SecretEntry is a saved secret as listed in the Secrets tab; it never holds the value
+type SecretEntry struct {
+	Name      string
+	Tags      []string
+	CreatedAt time.Time
+	RotatedAt time.Time
+	Repos     []string // tracked repositories using the secret
+}
+
+// LoadSecretEntries lists the saved secrets by name with their tags, dates and the tracked
+// repositories using them
+func LoadSecretEntries(state StateProvider, reposConfig *ReposConfig) ([]SecretEntry, error) {
+	var names []string
+	var metas map[string]SecretMeta
+	err := state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			names = sortedKeys(tx.Secrets())
+			var err error
+			metas, err = tx.SecretMetas()
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]SecretEntry, 0, len(names))
+	for _, name := range names {
+		meta := metas[name]
+		entry := SecretEntry{Name: name, Tags: meta.Tags, CreatedAt: meta.CreatedAt, RotatedAt: meta.RotatedAt}
+		for repo, config := range reposConfig.Repositories {
+			if contains(config.Secrets, name) {
+				entry.Repos = append(entry.Repos, repo)
+			}
+		}
+		sort.Strings(entry.Repos)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ParseTags splits a comma-separated list of tags, dropping blanks and duplicates
+func ParseTags(text string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// saveSecretChange saves a new value, unless value is empty, and the tags of a saved secret
+func saveSecretChange(state StateProvider, name, value string, tags []string) error {
+	return state.WithStore(func(store *StateStore) error {
+		return store.Update(func(tx *StateTx) error {
+			if value != "" {
+				if err := tx.PutSecret(name, value); err != nil {
+					return err
+				}
+			}
+			meta, err := tx.SecretMeta(name)
+			if err != nil {
+				return err
+			}
+			meta.Tags = tags
+			return tx.PutSecretMeta(name, meta)
+		})
+	})
+}
+
+// secretsView is the state of the Secrets tab: the list of saved secrets, the form adding
+// a secret to a repository, the form editing a saved secret, or the rotation confirmation
+type secretsView struct {
+	mode    int
+	entries []SecretEntry
+	err     string
+	cursor  int
+
+	// Value shown while the reveal key is held
+	revealName    string
+	revealValue   string
+	revealStarted time.Time
+	revealUntil   time.Time
+	revealBlocked time.Time // after a timeout, the key must be released before it reveals again
+
+	// Edit form and the rotation waiting for confirmation
+	editForm    formModel
+	editName    string
+	rotateValue string
+	rotateTags  []string
+	rotateRepos []string
+}
+
+// secretsLoadedMsg carries the saved secrets
+type secretsLoadedMsg struct {
+	entries []SecretEntry
+	err     error
+}
+
+// revealCheckMsg checks whether the revealed value should be hidden again
+type revealCheckMsg struct{}
+
+// loadSecrets lists the saved secrets in the background
+func loadSecrets(state StateProvider, reposConfig *ReposConfig) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := LoadSecretEntries(state, reposConfig)
+		return secretsLoadedMsg{entries: entries, err: err}
+	}
+}
+
+// checkReveal schedules the next reveal check
+func checkReveal() tea.Cmd {
+	return tea.Tick(revealCheckInterval, func(time.Time) tea.Msg { return revealCheckMsg{} })
+}
+
+// hideSecret forgets the revealed value
+func (v *secretsView) hideSecret() {
+	v.revealName, v.revealValue = "", ""
+}
+
+// selectedSecret returns the secret under the cursor
+func (v secretsView) selectedSecret() (SecretEntry, bool) {
+	if v.cursor < len(v.entries) {
+		return v.entries[v.cursor], true
+	}
+	return SecretEntry{}, false
+}
+
+// updateSecretsMsg handles the messages of the Secrets tab; handled is false for other messages
+func (m model) updateSecretsMsg(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
+	switch msg := msg.(type) {
+	case secretsLoadedMsg:
+		m.secrets.err = ""
+		if msg.err != nil {
+			m.secrets.err = msg.err.Error()
+			return m, nil, true
+		}
+		m.secrets.entries = msg.entries
+		m.secrets.cursor = min(m.secrets.cursor, max(len(msg.entries)-1, 0))
+		return m, nil, true
+
+	case revealCheckMsg:
+		if m.secrets.revealName == "" {
+			return m, nil, true
+		}
+		now := time.Now()
+		if now.After(m.secrets.revealUntil) || now.Sub(m.secrets.revealStarted) >= revealTimeout {
+			if now.Sub(m.secrets.revealStarted) >= revealTimeout {
+				m.secrets.revealBlocked = now.Add(revealHoldWindow)
+			}
+			m.secrets.hideSecret()
+			return m, nil, true
+		}
+		return m, checkReveal(), true
+	}
+	return m, nil, false
+}
+
+// updateSecrets handles a key on the Secrets tab while no form is edited; handled is false
+// for keys left to the global key map
+func (m model) updateSecrets(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch m.secrets.mode {
+	case secretsConfirm:
+		switch {
+		case m.keys.Matches(msg, keyConfirm, keySelect):
+			m.secrets.mode = secretsList
+			result, cmd := m.rotateSecret()
+			return result, cmd, true
+		case m.keys.Matches(msg, keyDeny, keyBack):
+			m.secrets.mode = secretsEdit
+			m.editing = true
+			cmd := m.secrets.editForm.Focus()
+			return m, cmd, true
+		}
+		return m, nil, true
+
+	case secretsAdd, secretsEdit:
+		if m.keys.Matches(msg, keyBack) {
+			m.secrets.mode = secretsList
+			return m, nil, true
+		}
+		return m, nil, false
+	}
+
+	// Any key but the reveal key hides the value
+	if !m.keys.Matches(msg, keyReveal) {
+		m.secrets.hideSecret()
+	}
+
+	switch {
+	case m.keys.Matches(msg, keyUp):
+		m.secrets.cursor = max(m.secrets.cursor-1, 0)
+	case m.keys.Matches(msg, keyDown):
+		m.secrets.cursor = max(min(m.secrets.cursor+1, len(m.secrets.entries)-1), 0)
+	case m.keys.Matches(msg, keyNew):
+		m.secrets.mode = secretsAdd
+		m.editing = true
+		cmd := m.secretForm.Focus()
+		return m, cmd, true
+	case m.keys.Matches(msg, keySelect, keyEdit):
+		if entry, ok := m.secrets.selectedSecret(); ok {
+			m.secrets.editName = entry.Name
+			m.secrets.editForm = newForm(fmt.Sprintf("Edit secret '%s'", entry.Name),
+				maskedField("New value (leave empty to keep the current one)"),
+				textField("Tags", "comma-separated, e.g. prod, payments"),
+			)
+			m.secrets.editForm.SetValue(1, strings.Join(entry.Tags, ", "))
+			m.secrets.mode = secretsEdit
+			m.editing = true
+			cmd := m.secrets.editForm.Focus()
+			return m, cmd, true
+		}
+	case m.keys.Matches(msg, keyReveal):
+		entry, ok := m.secrets.selectedSecret()
+		if !ok {
+			return m, nil, true
+		}
+		now := time.Now()
+		if now.Before(m.secrets.revealBlocked) {
+			m.secrets.revealBlocked = now.Add(revealHoldWindow)
+			return m, nil, true
+		}
+		m.secrets.revealUntil = now.Add(revealHoldWindow)
+		if m.secrets.revealName == entry.Name {
+			return m, nil, true
+		}
+		value, err := loadSecretValue(m.state, entry.Name)
+		if err != nil {
+			m.status, m.statusError = "Failed: "+err.Error(), true
+			return m, nil, true
+		}
+		m.secrets.revealName, m.secrets.revealValue, m.secrets.revealStarted = entry.Name, value, now
+		return m, checkReveal(), true
+	default:
+		return m, nil, false
+	}
+	return m, nil, true
+}
+
+// submitSecretEdit saves the edited tags right away and a new value after a confirmation
+// when the secret is used by tracked repositories, which then all receive the new value
+func (m model) submitSecretEdit() (tea.Model, tea.Cmd) {
+	form := &m.secrets.editForm
+	name := m.secrets.editName
+	value := form.Value(0) // used exactly as typed
+	tags := ParseTags(form.Value(1))
+
+	var repos []string
+	for _, entry := range m.secrets.entries {
+		if entry.Name == name {
+			repos = entry.Repos
+		}
+	}
+
+	if value != "" {
+		if err := checkSecretSize(value); err != nil {
+			form.SetError(err)
+			return m, nil
+		}
+		if len(repos) > 0 {
+			form.SetError(nil)
+			form.Blur()
+			m.editing = false
+			m.secrets.mode = secretsConfirm
+			m.secrets.rotateValue, m.secrets.rotateTags, m.secrets.rotateRepos = value, tags, repos
+			return m, nil
+		}
+	}
+
+	if err := saveSecretChange(m.state, name, value, tags); err != nil {
+		form.SetError(err)
+		return m, nil
+	}
+	form.Blur()
+	m.editing = false
+	m.secrets.mode = secretsList
+	m.status, m.statusError = fmt.Sprintf("Done: secret '%s' saved.", name), false
+	return m, loadSecrets(m.state, m.reposConfig)
+}
+
+// rotateSecret pushes the confirmed new value to every repository using the secret and saves
+// it locally only once all of them have it; otherwise the old value is kept and the
+// repositories that did not get the new one are reported
+func (m model) rotateSecret() (tea.Model, tea.Cmd) {
+	name, value, tags, repos := m.secrets.editName, m.secrets.rotateValue, m.secrets.rotateTags, m.secrets.rotateRepos
+	m.secrets.rotateValue = ""
+	ghm, state, logger := m.ghm, m.state, m.logger
+	return m.start(fmt.Sprintf("Rotating secret '%s' in %d repositories", name, len(repos)), func(ctx context.Context) tea.Msg {
+		var results OperationResults
+		var failed []string
+		pushCtx := withoutLocalSave(ctx)
+		for _, repo := range repos {
+			if err := ctx.Err(); err != nil {
+				return resultsDone(state, results, err, logger)
+			}
+			result := OperationResult{Kind: kindSecret, Name: name, Repo: repo, Action: actionAdded}
+			if err := ghm.AddSecret(pushCtx, repo, name, value); err != nil {
+				logger.Errorf("Error adding secret '%s' to '%s': %v", name, repo, err)
+				result.Action, result.Error = actionFailed, err.Error()
+				failed = append(failed, repo)
+			}
+			results = append(results, result)
+		}
+
+		saved := OperationResult{Kind: kindSecret, Name: name, Action: actionRotated}
+		if len(failed) > 0 {
+			logger.Warnf("Secret '%s' keeps its old value locally; not rotated in %s.", name, strings.Join(failed, ", "))
+			saved.Action, saved.Error = actionFailed, "the old value is kept; not rotated in "+strings.Join(failed, ", ")
+			return resultsDone(state, append(results, saved), nil, logger)
+		}
+		if err := saveSecretChange(state, name, value, tags); err != nil {
+			saved.Action, saved.Error = actionFailed, err.Error()
+			return resultsDone(state, append(results, saved), err, logger)
+		}
+		return resultsDone(state, append(results, saved), nil, logger)
+	})
+}
+
+// secretsKeys returns the keys of the current Secrets view for the footer; global is false
+// when the view takes every key
+func (m model) secretsKeys() (bindings []key.Binding, global bool) {
+	switch m.secrets.mode {
+	case secretsConfirm:
+		return []key.Binding{m.keys.Hint(keyConfirm, "rotate"), m.keys.Hint(keyDeny, "back to the form")}, false
+	case secretsAdd, secretsEdit:
+		return []key.Binding{m.keys.Hint(keyBack, "back to the list")}, true
+	}
+	bindings = []key.Binding{m.keys.Hint(keyNew, "add to a repository")}
+	if len(m.secrets.entries) > 0 {
+		bindings = append([]key.Binding{
+			m.keys.Hint(keyUp, "up"),
+			m.keys.Hint(keyDown, "down"),
+			m.keys.Hint(keyReveal, "hold to reveal"),
+			m.keys.Hint(keyEdit, "edit or rotate"),
+		}, bindings...)
+	}
+	return bindings, true
+}
+
+// renderSecretsTab renders the list, forms or confirmation of the Secrets tab in height lines
+func renderSecretsTab(m model, height int) string {
+	switch m.secrets.mode {
+	case secretsAdd:
+		form := m.secretForm
+		form.SetSize(m.width, height)
+		return form.View(m.editing)
+	case secretsEdit:
+		form := m.secrets.editForm
+		form.SetSize(m.width, height)
+		return form.View(m.editing)
+	case secretsConfirm:
+		var b strings.Builder
+		b.WriteString(formTitleStyle.Render(fmt.Sprintf("Rotate secret '%s'?", m.secrets.editName)) + "\n\n")
+		b.WriteString(fmt.Sprintf("The new value is pushed to %d repositories and saved locally once all of them have it:\n\n", len(m.secrets.rotateRepos)))
+		for _, repo := range m.secrets.rotateRepos {
+			b.WriteString("  " + repo + "\n")
+		}
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+
+	var b strings.Builder
+	if m.secrets.err != "" {
+		b.WriteString(formErrorStyle.Render("Error loading secrets: "+m.secrets.err) + "\n\n")
+	}
+	if len(m.secrets.entries) == 0 {
+		b.WriteString("No saved secrets.")
+		return b.String()
+	}
+	reveal := ""
+	if m.secrets.revealName != "" {
+		reveal = "\n\n" + secretRevealStyle.Render(m.secrets.revealName+" = "+m.secrets.revealValue)
+	}
+
+	header := []string{"NAME", "TAGS", "CREATED", "ROTATED", "USED BY"}
+	rows := make([][]string, 0, len(m.secrets.entries))
+	for _, entry := range m.secrets.entries {
+		tags := "-"
+		if len(entry.Tags) > 0 {
+			tags = secretTagStyle.Render(strings.Join(entry.Tags, ", "))
+		}
+		rows = append(rows, []string{entry.Name, tags, formatSecretDate(entry.CreatedAt), formatSecretDate(entry.RotatedAt), usedByView(entry.Repos)})
+	}
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len(title)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	// Keep the rows that fit below the header and any error, above the revealed value
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-lipgloss.Height(b.String())-lipgloss.Height(reveal)+1, 1)
+	}
+	start, end := listWindow(m.secrets.cursor, len(rows), rowsHeight)
+
+	line := "  "
+	for i, title := range header {
+		line += padCell(title, widths[i])
+	}
+	b.WriteString(repoHeaderStyle.Render(strings.TrimRight(line, " ")))
+	b.WriteString(formHintStyle.Render(listPosition(start, end, len(rows), "secrets")) + "\n")
+	for r := start; r < end; r++ {
+		row := rows[r]
+		line := "  "
+		if r == m.secrets.cursor {
+			line = repoCursorStyle.Render("> ")
+		}
+		for i, cell := range row {
+			line += padCell(cell, widths[i])
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n") + reveal
+}
+
+// formatSecretDate renders a date of a saved secret, or "-" when not recorded
+func formatSecretDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+// usedByView lists up to two repositories using a secret and counts the rest
+func usedByView(repos []string) string {
+	switch len(repos) {
+	case 0:
+		return "-"
+	case 1, 2:
+		return strings.Join(repos, ", ")
+	}
+	return fmt.Sprintf("%s, %s +%d", repos[0], repos[1], len(repos)-2)
+}
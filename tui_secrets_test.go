@@ -0,0 +1,56 @@
+// tui_secrets_test.go
+
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadSecretEntries tests listing saved secrets with their tags and using repositories
+func TestLoadSecretEntries(t *testing.T) {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	err = store.Update(func(tx *StateTx) error {
+		require.NoError(t, tx.PutSecret("DB_PASS", "pw"))
+		require.NoError(t, tx.PutSecret("API_KEY", "key"))
+		meta, err := tx.SecretMeta("API_KEY")
+		require.NoError(t, err)
+		meta.Tags = []string{"prod", "payments"}
+		return tx.PutSecretMeta("API_KEY", meta)
+	})
+	require.NoError(t, err)
+	defer store.Close()
+
+	reposConfig := &ReposConfig{Repositories: map[string]RepoConfig{
+		"acme/web": {Secrets: []string{"API_KEY"}},
+		"acme/api": {Secrets: []string{"API_KEY", "OTHER"}},
+	}}
+	entries, err := LoadSecretEntries(store, reposConfig)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "API_KEY", entries[0].Name)
+	assert.Equal(t, []string{"prod", "payments"}, entries[0].Tags)
+	assert.Equal(t, []string{"acme/api", "acme/web"}, entries[0].Repos)
+	assert.False(t, entries[0].CreatedAt.IsZero())
+
+	assert.Equal(t, "DB_PASS", entries[1].Name)
+	assert.Empty(t, entries[1].Tags)
+	assert.Empty(t, entries[1].Repos)
+}
+
+// TestParseTags tests splitting the tags typed in the edit form
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"prod", "payments"}, ParseTags(" prod, payments ,,prod"))
+	assert.Equal(t, []string{}, ParseTags("  "))
+}
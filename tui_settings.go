@@ -0,0 +1,157 @@
+// tui_settings.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// SettingChange is an edited setting; an empty Value unsets the key
+type SettingChange struct {
+	Key   string
+	Value string
+}
+
+// SettingChanges compares the edited values of the known settings with the current ones
+// and validates every change. Secret settings are never shown, so leaving them empty
+// keeps the stored credential.
+func SettingChanges(current, edited map[string]string) ([]SettingChange, error) {
+	var changes []SettingChange
+	for _, setting := range settingsRegistry {
+		value := edited[setting.Key]
+		if setting.Type != SettingSecret {
+			value = strings.TrimSpace(value)
+		}
+		if value == current[setting.Key] || (setting.Type == SettingSecret && value == "") {
+			continue
+		}
+		if err := ValidateSetting(setting.Key, value, false); err != nil {
+			return nil, err
+		}
+		changes = append(changes, SettingChange{Key: setting.Key, Value: value})
+	}
+	return changes, nil
+}
+
+// settingsView is the state of the Settings tab: one form field per known setting
+type settingsView struct {
+	form    formModel
+	current map[string]string
+}
+
+// newSettingsView creates the settings form; call load to fill in the current values
+func newSettingsView() settingsView {
+	fields := make([]formField, 0, len(settingsRegistry))
+	for _, setting := range settingsRegistry {
+		if setting.Type == SettingSecret {
+			fields = append(fields, maskedField(setting.Key))
+			continue
+		}
+		placeholder := setting.Default
+		if setting.Type == SettingChoice {
+			placeholder = strings.Join(setting.Choices, " | ")
+		}
+		fields = append(fields, textField(setting.Key, placeholder))
+	}
+	return settingsView{form: newForm("Settings", fields...)}
+}
+
+// load fills the form with the configured values; defaults show as placeholders
+func (v *settingsView) load(logger *logrus.Logger) {
+	v.current = make(map[string]string, len(settingsRegistry))
+	for i, setting := range settingsRegistry {
+		value := ""
+		switch {
+		case setting.Type == SettingSecret:
+			v.form.fields[i].input.Placeholder = "not set"
+			if getCredential(setting.Key, logger) != "" {
+				v.form.fields[i].input.Placeholder = "stored; type to replace"
+			}
+		case viper.IsSet(setting.Key) && viper.Get(setting.Key) != nil:
+			value = viper.GetString(setting.Key)
+		}
+		v.current[setting.Key] = value
+		v.form.SetValue(i, value)
+	}
+	v.form.SetError(nil)
+}
+
+// edited returns the values in the form by setting key
+func (v settingsView) edited() map[string]string {
+	values := make(map[string]string, len(settingsRegistry))
+	for i, setting := range settingsRegistry {
+		values[setting.Key] = v.form.Value(i)
+	}
+	return values
+}
+
+// submitSettings stores the changed settings and unsets the cleared ones
+func (m model) submitSettings() (tea.Model, tea.Cmd) {
+	form := &m.settings.form
+	changes, err := SettingChanges(m.settings.current, m.settings.edited())
+	if err != nil {
+		form.SetError(err)
+		return m, nil
+	}
+	if len(changes) == 0 {
+		form.SetError(fmt.Errorf("no settings changed"))
+		return m, nil
+	}
+
+	form.SetError(nil)
+	form.Blur()
+	m.editing = false
+	ghm, state, logger := m.ghm, m.state, m.logger
+	return m.start(fmt.Sprintf("Saving %d settings", len(changes)), func(ctx context.Context) tea.Msg {
+		results := make(OperationResults, 0, len(changes))
+		for _, change := range changes {
+			if err := ctx.Err(); err != nil {
+				return resultsDone(state, results, err, logger)
+			}
+			result := OperationResult{Kind: kindConfig, Name: change.Key, Action: actionStored}
+			var err error
+			if change.Value == "" {
+				result.Action = actionRemoved
+				err = ghm.UnsetConfig(ctx, change.Key)
+			} else {
+				err = ghm.StoreConfig(ctx, change.Key, change.Value)
+			}
+			if err != nil {
+				result.Action, result.Error = actionFailed, err.Error()
+			}
+			results = append(results, result)
+		}
+		return resultsDone(state, results, nil, logger)
+	})
+}
+
+// hasConfigResults reports whether any of the results changed a setting
+func hasConfigResults(results OperationResults) bool {
+	for _, result := range results {
+		if result.Kind == kindConfig {
+			return true
+		}
+	}
+	return false
+}
+
+// renderSettingsTab renders the settings form and the description of the focused setting
+// in height lines
+func renderSettingsTab(m model, height int) string {
+	form := m.settings.form
+	if height > 0 && m.editing {
+		height = max(height-2, 1)
+	}
+	form.SetSize(m.width, height)
+	view := form.View(m.editing)
+	if m.editing {
+		setting := settingsRegistry[m.settings.form.focus]
+		view += "\n" + formHintStyle.Render(setting.Key+": "+setting.Description)
+	}
+	return view
+}
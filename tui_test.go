@@ -0,0 +1,401 @@
+// tui_test.go
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files from the current frames: go test . -run TUI -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the TUI tests")
+
+// settleQuiet is how long the harness waits for more messages before it considers the TUI idle
+const settleQuiet = 100 * time.Millisecond
+
+// ListWorkflowRuns lets the Runs tab load without runs
+func (f *fakeGHM) ListWorkflowRuns(ctx context.Context, repo string) ([]WorkflowRun, bool, error) {
+	return nil, false, nil
+}
+
+// tuiHarness feeds scripted messages to the TUI model and compares its frames with golden
+// files in testdata/tui. Commands returned by the model run only when settle is called.
+type tuiHarness struct {
+	t       *testing.T
+	model   tea.Model
+	store   *StateStore
+	pending []tea.Cmd
+	msgs    chan tea.Msg
+}
+
+// newTUIHarness starts the TUI on an 80x24 terminal against a state store with two tracked
+// repositories, two saved secrets, a saved workflow and two journaled operations
+func newTUIHarness(t *testing.T, ghm GHM) *tuiHarness {
+	t.Setenv("GHM_STATE_DIR", t.TempDir())
+	t.Setenv("NO_COLOR", "")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("github_token", "ghp_test")
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	store, err := OpenStateStore(logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	day := time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local)
+	err = store.Update(func(tx *StateTx) error {
+		for name, tags := range map[string][]string{"API_KEY": {"prod"}, "DB_PASS": nil} {
+			require.NoError(t, tx.PutSecret(name, "value of "+name))
+			require.NoError(t, tx.PutSecretMeta(name, SecretMeta{Tags: tags, CreatedAt: day, RotatedAt: day}))
+		}
+		require.NoError(t, tx.PutWorkflow("ci.yml", "name: CI\non: [push]\n"))
+		require.NoError(t, tx.PutRepo("acme/api", RepoConfig{Secrets: []string{"API_KEY", "DB_PASS"}, LastUpdate: day}))
+		_, err := tx.AppendJournal(JournalEntry{Time: day, User: "dev", Operation: "add-workflow", Kind: "workflow",
+			Name: "ci.yml", Repo: "acme/web", Outcome: "failed", Error: "forbidden"})
+		require.NoError(t, err)
+		_, err = tx.AppendJournal(JournalEntry{Time: day.Add(time.Hour), User: "dev", Operation: "add-secret", Kind: "secret",
+			Name: "DB_PASS", Repo: "acme/api", Outcome: "succeeded", Previous: &PreviousState{}})
+		require.NoError(t, err)
+		return tx.PutRepo("acme/web", RepoConfig{Secrets: []string{"API_KEY"}, Workflows: []string{"ci.yml"}, LastUpdate: day.Add(time.Hour)})
+	})
+	require.NoError(t, err)
+
+	model, err := NewTUIModel(logger, ghm, store)
+	require.NoError(t, err)
+
+	h := &tuiHarness{t: t, model: model, store: store, msgs: make(chan tea.Msg, 16)}
+	h.pending = append(h.pending, model.Init())
+	h.send(tea.WindowSizeMsg{Width: 80, Height: 24})
+	h.settle()
+	return h
+}
+
+// secretValue returns the value of a saved secret
+func (h *tuiHarness) secretValue(name string) string {
+	var value string
+	require.NoError(h.t, h.store.View(func(tx *StateTx) error {
+		value, _ = tx.Secret(name)
+		return nil
+	}))
+	return value
+}
+
+// send passes a message to the model and keeps the command it returns for settle
+func (h *tuiHarness) send(msg tea.Msg) {
+	var cmd tea.Cmd
+	h.model, cmd = h.model.Update(msg)
+	if cmd != nil {
+		h.pending = append(h.pending, cmd)
+	}
+}
+
+// press sends keys by name, such as "enter", "ctrl+s" or "l"
+func (h *tuiHarness) press(keys ...string) {
+	named := map[string]tea.KeyType{
+		"enter": tea.KeyEnter, "esc": tea.KeyEsc, "tab": tea.KeyTab, "shift+tab": tea.KeyShiftTab,
+		"up": tea.KeyUp, "down": tea.KeyDown, "left": tea.KeyLeft, "right": tea.KeyRight,
+		"backspace": tea.KeyBackspace, "ctrl+s": tea.KeyCtrlS, "ctrl+u": tea.KeyCtrlU,
+	}
+	for _, k := range keys {
+		if keyType, ok := named[k]; ok {
+			h.send(tea.KeyMsg{Type: keyType})
+		} else {
+			h.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+}
+
+// typeText types text into the focused field
+func (h *tuiHarness) typeText(text string) {
+	h.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+// settle runs the pending commands and feeds their messages back until the TUI is idle.
+// Spinner ticks are dropped so that frames do not depend on timing; commands that wait
+// longer, such as the runs poll, are left running.
+func (h *tuiHarness) settle() {
+	for {
+		for _, cmd := range h.pending {
+			if cmd == nil {
+				continue
+			}
+			go func(cmd tea.Cmd) { h.msgs <- cmd() }(cmd)
+		}
+		h.pending = nil
+
+		select {
+		case msg := <-h.msgs:
+			switch msg := msg.(type) {
+			case tea.BatchMsg:
+				h.pending = append(h.pending, msg...)
+			case spinner.TickMsg, nil:
+			default:
+				h.send(msg)
+			}
+		case <-time.After(settleQuiet):
+			return
+		}
+	}
+}
+
+// expectFrame compares the rendered frame with testdata/tui/<name>.golden
+func (h *tuiHarness) expectFrame(name string) {
+	h.t.Helper()
+	lines := strings.Split(h.model.View(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	frame := strings.Join(lines, "\n") + "\n"
+
+	path := filepath.Join("testdata", "tui", name+".golden")
+	if *updateGolden {
+		require.NoError(h.t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(h.t, ioutil.WriteFile(path, []byte(frame), 0644))
+		return
+	}
+	golden, err := ioutil.ReadFile(path)
+	require.NoError(h.t, err, "run with -update to create the golden file")
+	assert.Equal(h.t, string(golden), frame, "frame %s differs from %s", name, path)
+}
+
+// TestTUITabNavigation tests moving between tabs and the default view of each
+func TestTUITabNavigation(t *testing.T) {
+	h := newTUIHarness(t, &fakeGHM{})
+	h.expectFrame("secrets_list")
+
+	h.press("l")
+	h.expectFrame("workflows_tab")
+
+	h.press("right")
+	h.expectFrame("repositories_tab")
+
+	// Runs fetch in the background; the harness only renders the tabs after them
+	h.press("l", "l")
+	h.settle()
+	h.expectFrame("history_tab")
+
+	h.press("l")
+	h.expectFrame("settings_tab")
+
+	h.press("l", "l")
+	h.expectFrame("help_tab")
+
+	h.press("h", "h", "h", "h", "h", "h", "h")
+	h.expectFrame("secrets_list")
+}
+
+// TestTUISecretFormSubmission tests adding a secret from the Secrets tab
+func TestTUISecretFormSubmission(t *testing.T) {
+	ghm := &fakeGHM{}
+	h := newTUIHarness(t, ghm)
+
+	h.press("n")
+	h.typeText("acme/api")
+	h.press("enter")
+	h.typeText("NEW_TOKEN")
+	h.press("enter")
+	h.typeText("s3cret")
+	h.expectFrame("secret_form_filled")
+
+	h.press("enter")
+	h.settle()
+	h.expectFrame("secret_form_done")
+	assert.Equal(t, []string{"add-secret acme/api NEW_TOKEN=s3cret"}, ghm.calls)
+}
+
+// TestTUIReboundKeys tests that keys.<action> settings drive the TUI and its footer
+func TestTUIReboundKeys(t *testing.T) {
+	h := newTUIHarness(t, &fakeGHM{})
+	viper.Set("keys.next_tab", "tab")
+	viper.Set("keys.down", "J")
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	model, err := NewTUIModel(logger, &fakeGHM{}, h.store)
+	require.NoError(t, err)
+	h.model = model
+	h.pending = append(h.pending, model.Init())
+	h.send(tea.WindowSizeMsg{Width: 80, Height: 24})
+	h.settle()
+	assert.Contains(t, h.model.View(), "J down")
+	assert.Contains(t, h.model.View(), "tab next tab")
+
+	// The built-in keys no longer apply
+	h.press("j", "l")
+	assert.Contains(t, h.model.View(), "> API_KEY")
+
+	h.press("J")
+	assert.Contains(t, h.model.View(), "> DB_PASS")
+	h.press("tab")
+	assert.Contains(t, h.model.View(), "Add a workflow")
+}
+
+// TestTUIErrorDisplay tests validation errors in a form and a failing operation in the status line
+func TestTUIErrorDisplay(t *testing.T) {
+	ghm := &fakeGHM{fail: map[string]bool{"deploy.yml": true}}
+	h := newTUIHarness(t, ghm)
+
+	h.press("l", "enter")
+	h.typeText("not-a-repo")
+	h.press("ctrl+s")
+	h.expectFrame("workflow_invalid_repo")
+	assert.Empty(t, ghm.calls, "invalid forms are not submitted")
+
+	h.send(tea.KeyMsg{Type: tea.KeyCtrlU})
+	h.typeText("acme/web")
+	h.press("tab")
+	h.typeText("deploy.yml")
+	h.press("tab")
+	h.typeText("name: Deploy")
+	h.press("ctrl+s")
+	h.expectFrame("workflow_running")
+
+	h.settle()
+	h.expectFrame("workflow_failed")
+	assert.Equal(t, []string{"add-workflow acme/web deploy.yml"}, ghm.calls)
+}
+
+// TestTUIBulkApply tests the bulk apply wizard from picking repositories to saving the report
+func TestTUIBulkApply(t *testing.T) {
+	ghm := &fakeGHM{
+		repos:     []string{"acme/api", "acme/new"},
+		secrets:   map[string][]string{"acme/api": {"API_KEY"}},
+		workflows: map[string]string{"acme/api/ci.yml": "name: CI\non: [pull_request]\n"},
+	}
+	h := newTUIHarness(t, ghm)
+
+	h.press("l", "l", " ", "a")
+	h.settle()
+	h.expectFrame("wizard_repos")
+
+	h.press("down", "down", " ", "enter", " ", "down", "down", " ", "enter")
+	h.settle()
+	h.expectFrame("wizard_plan")
+	assert.Empty(t, ghm.calls, "nothing is applied before the plan is confirmed")
+
+	h.press("enter")
+	h.expectFrame("wizard_running")
+	h.settle()
+	h.expectFrame("wizard_report")
+	assert.Equal(t, []string{
+		"add-secret acme/api API_KEY=value of API_KEY", "add-workflow acme/api ci.yml",
+		"add-secret acme/new API_KEY=value of API_KEY", "add-workflow acme/new ci.yml",
+	}, ghm.calls)
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	h.press("w", "ctrl+u")
+	h.typeText(path)
+	h.press("enter")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"repo": "acme/new"`)
+
+	h.press("esc")
+	assert.NotContains(t, h.model.View(), "Bulk apply", "esc closes the report")
+}
+
+// TestTUIHistory tests undoing a journaled operation after a confirmation
+func TestTUIHistory(t *testing.T) {
+	ghm := &fakeGHM{}
+	h := newTUIHarness(t, ghm)
+	h.press("l", "l", "l", "l")
+	h.settle()
+	h.expectFrame("history_tab")
+
+	h.press("u")
+	h.expectFrame("history_confirm")
+
+	h.press("n")
+	assert.Contains(t, h.model.View(), "Undo: remove secret 'DB_PASS' from 'acme/api'.")
+	assert.Empty(t, ghm.calls)
+
+	h.press("u", "y")
+	h.settle()
+	assert.Equal(t, []string{"remove-secret acme/api DB_PASS"}, ghm.calls)
+	view := h.model.View()
+	assert.Contains(t, view, "Done: secret 'DB_PASS' removed from acme/api.")
+	assert.Contains(t, view, "Undone at ")
+
+	// The failed operation cannot be undone
+	h.press("down", "u")
+	assert.Contains(t, h.model.View(), "Cannot undo: operation 1 did not succeed")
+	assert.Len(t, ghm.calls, 1)
+}
+
+// secretsServer fakes the GitHub secrets API, refusing the repositories in forbidden
+func secretsServer(t *testing.T, forbidden map[string]bool) *httptest.Server {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/repos/"), "/")
+		if len(parts) < 2 || forbidden[parts[0]+"/"+parts[1]] {
+			http.Error(w, `{"message": "Forbidden"}`, http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/public-key") {
+			fmt.Fprintf(w, `{"key_id": "1", "key": %q}`, key)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	return server
+}
+
+// TestTUISecretRotation tests that a rotated value is saved locally only once every
+// repository using the secret has it, and that GHMImpl works in the store of the TUI
+func TestTUISecretRotation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	ghm := &GHMImpl{Token: "ghp_test", Encryptor: &EncryptorImpl{}, Logger: logger}
+	h := newTUIHarness(t, ghm)
+	dataDir := t.TempDir()
+	t.Setenv("GHM_STATE_DIR", dataDir)
+
+	secretsServer(t, map[string]bool{"acme/web": true})
+	h.press("e")
+	h.typeText("rotated")
+	h.press("enter", "enter")
+	assert.Contains(t, h.model.View(), "Rotate secret 'API_KEY'?")
+	h.press("y")
+	h.settle()
+	assert.Contains(t, h.model.View(), "Failed: 2 of 3 items; secret 'API_KEY' on acme/web: ")
+	assert.Equal(t, "value of API_KEY", h.secretValue("API_KEY"))
+
+	// Once every push succeeds the new value replaces the old one
+	secretsServer(t, nil)
+	h.press("e")
+	h.typeText("rotated")
+	h.press("enter", "enter", "y")
+	h.settle()
+	assert.Contains(t, h.model.View(), "Done: secret 'API_KEY' rotated and pushed to 2 repositories.")
+	assert.Equal(t, "rotated", h.secretValue("API_KEY"))
+
+	// The pushes were journaled in the store of the TUI, and the data directory was not touched
+	entries, err := LoadJournal(h.store, "", 4)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, []string{"succeeded", "succeeded", "failed", "succeeded"},
+		[]string{entries[0].Outcome, entries[1].Outcome, entries[2].Outcome, entries[3].Outcome})
+	files, err := ioutil.ReadDir(dataDir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,621 @@
+// tui_wizard.go
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Steps of the bulk apply wizard
+const (
+	wizardRepos = iota
+	wizardItems
+	wizardPlan
+	wizardRunning
+	wizardReport
+)
+
+// wizardStepNames are the steps shown in the title of the wizard
+var wizardStepNames = []string{"Repositories", "Items", "Plan", "Apply", "Report"}
+
+// diffContext is the number of unchanged lines shown around the changes of a diff
+const diffContext = 2
+
+// Wizard styles, set by applyTheme
+var (
+	wizardStepStyle       lipgloss.Style
+	wizardActiveStepStyle lipgloss.Style
+	diffAddedStyle        lipgloss.Style
+	diffRemovedStyle      lipgloss.Style
+)
+
+// setWizardStyles derives the wizard styles from a theme
+func setWizardStyles(t Theme) {
+	wizardStepStyle = t.fg(t.Muted)
+	wizardActiveStepStyle = t.emphasis().Underline(t.Accent == "")
+	diffAddedStyle = t.fg(t.Success)
+	diffRemovedStyle = t.fg(t.Error)
+}
+
+// applyWizard is the bulk apply wizard of the Repositories tab: pick repositories and
+// saved items, review the plan, apply it while following each repository, and read or
+// save the report
+type applyWizard struct {
+	step          int
+	repos         PickerModel
+	items         PickerModel
+	loadingRemote bool   // the repositories on GitHub are still being listed
+	remoteError   string // listing the repositories on GitHub failed
+	plan          ApplyPlan
+	report        ApplyReport
+	offset        int  // scroll position of the plan and the report
+	saving        bool // the report path has the keyboard
+	path          textinput.Model
+}
+
+// wizardReposMsg carries the repositories the token can access on GitHub
+type wizardReposMsg struct {
+	repos []string
+	err   error
+}
+
+// applyPlanMsg carries the plan of a bulk apply
+type applyPlanMsg struct {
+	plan ApplyPlan
+	err  error
+}
+
+// applyRepoMsg reports a repository starting or ending during a bulk apply
+type applyRepoMsg ApplyRepoReport
+
+// applyDoneMsg reports the end of a bulk apply with its report
+type applyDoneMsg struct {
+	report ApplyReport
+	done   resultsDoneMsg
+}
+
+// openWizard opens the bulk apply wizard with the selected repositories picked, or the one
+// under the cursor when none are selected, and starts listing the repositories on GitHub
+func (m *model) openWizard(rows []RepoRow) (tea.Cmd, error) {
+	var picked []string
+	for repo := range m.repos.selected {
+		picked = append(picked, repo)
+	}
+	if len(picked) == 0 && m.repos.cursor < len(rows) {
+		picked = []string{rows[m.repos.cursor].Repo}
+	}
+
+	items, err := m.savedApplyItems()
+	if err != nil {
+		return nil, fmt.Errorf("loading saved items: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no saved secrets or workflows to apply")
+	}
+
+	tracked := make([]string, 0, len(m.reposConfig.Repositories))
+	for repo := range m.reposConfig.Repositories {
+		tracked = append(tracked, repo)
+	}
+	sort.Strings(tracked)
+	repoItems := make([]PickerItem, 0, len(tracked))
+	for _, repo := range tracked {
+		config := m.reposConfig.Repositories[repo]
+		repoItems = append(repoItems, PickerItem{Name: repo, Preview: []string{
+			fmt.Sprintf("Tracked with %d secrets and %d workflows", len(config.Secrets), len(config.Workflows)),
+		}})
+	}
+
+	repos := NewPickerModel("Select the repositories to apply to", repoItems)
+	repos.Select(picked...)
+	path := textinput.New()
+	path.Prompt = "Save the report to: "
+	m.repos.wizard = applyWizard{
+		step:          wizardRepos,
+		repos:         repos,
+		items:         NewPickerModel("Select the saved secrets and workflows to apply", items),
+		loadingRemote: true,
+		path:          path,
+	}
+	m.repos.mode = reposApply
+
+	ghm := m.ghm
+	return func() tea.Msg {
+		repos, err := ghm.ListRepos(context.Background())
+		return wizardReposMsg{repos: repos, err: err}
+	}, nil
+}
+
+// savedApplyItems returns the saved secrets and workflows as picker items named "kind: name"
+func (m model) savedApplyItems() ([]PickerItem, error) {
+	var secrets, workflows []string
+	err := m.state.WithStore(func(store *StateStore) error {
+		return store.View(func(tx *StateTx) error {
+			secrets = sortedKeys(tx.Secrets())
+			workflows = sortedKeys(tx.Workflows())
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []PickerItem
+	for _, kind := range []string{kindSecret, kindWorkflow} {
+		names := secrets
+		if kind == kindWorkflow {
+			names = workflows
+		}
+		previews, err := savedItemPreviews(m.state, kind, names, m.reposConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range previews {
+			item.Name = kind + ": " + item.Name
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// updateWizardMsg handles the messages of the bulk apply wizard; handled is false for other messages
+func (m model) updateWizardMsg(msg tea.Msg) (tea.Model, tea.Cmd, bool) {
+	w := &m.repos.wizard
+	switch msg := msg.(type) {
+	case wizardReposMsg:
+		w.loadingRemote = false
+		if msg.err != nil {
+			w.remoteError = msg.err.Error()
+			return m, nil, true
+		}
+		var items []PickerItem
+		for _, repo := range msg.repos {
+			if _, tracked := m.reposConfig.Repositories[repo]; !tracked {
+				items = append(items, PickerItem{Name: repo, Preview: []string{"On GitHub, not tracked yet"}})
+			}
+		}
+		w.repos.AddItems(items)
+		return m, nil, true
+
+	case applyPlanMsg:
+		m.finishOperation()
+		switch {
+		case errors.Is(msg.err, context.Canceled):
+			m.status, m.statusError = "Cancelled planning the apply.", true
+		case msg.err != nil:
+			m.status, m.statusError = "Failed: planning the apply: "+msg.err.Error(), true
+		case m.repos.mode == reposApply:
+			w.plan, w.step, w.offset = msg.plan, wizardPlan, 0
+			m.status, m.statusError = "Review the plan before applying it.", false
+		}
+		return m, nil, true
+
+	case applyRepoMsg:
+		if w.step == wizardRunning {
+			for i := range w.report.Repos {
+				if w.report.Repos[i].Repo == msg.Repo {
+					w.report.Repos[i] = ApplyRepoReport(msg)
+				}
+			}
+		}
+		return m, listenForEvents(m.events), true
+
+	case applyDoneMsg:
+		w.report, w.step, w.offset = msg.report, wizardReport, 0
+		result, cmd := m.Update(msg.done)
+		return result, cmd, true
+	}
+	return m, nil, false
+}
+
+// updateWizard handles a key in the bulk apply wizard. The pickers and the report path
+// take every key; while the apply runs, keys are left to the global key map.
+func (m model) updateWizard(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	w := &m.repos.wizard
+	switch w.step {
+	case wizardRepos, wizardItems:
+		picker := &w.repos
+		if w.step == wizardItems {
+			picker = &w.items
+		}
+		switch msg.String() {
+		case "esc":
+			if w.step == wizardRepos {
+				m.repos.mode = reposList
+			} else {
+				w.step = wizardRepos
+			}
+			return m, nil, true
+		case "enter":
+			switch {
+			case len(picker.Selected()) == 0 && w.step == wizardRepos:
+				m.status, m.statusError = "Select at least one repository.", true
+			case len(picker.Selected()) == 0:
+				m.status, m.statusError = "Select at least one secret or workflow to apply.", true
+			case w.step == wizardRepos:
+				w.step = wizardItems
+			default:
+				result, cmd := m.planApply()
+				return result, cmd, true
+			}
+			return m, nil, true
+		}
+		updated, cmd := picker.Update(msg)
+		*picker = updated.(PickerModel)
+		return m, cmd, true
+
+	case wizardPlan:
+		switch {
+		case m.keys.Matches(msg, keyUp):
+			w.offset = max(w.offset-1, 0)
+		case m.keys.Matches(msg, keyDown):
+			w.offset = min(w.offset+1, max(len(planLines(w.plan))-1, 0))
+		case m.keys.Matches(msg, keySelect):
+			result, cmd := m.executePlan()
+			return result, cmd, true
+		case m.keys.Matches(msg, keyBack):
+			w.step = wizardItems
+		default:
+			return m, nil, false
+		}
+		return m, nil, true
+
+	case wizardReport:
+		if w.saving {
+			switch msg.String() {
+			case "esc":
+				w.saving = false
+				w.path.Blur()
+				return m, nil, true
+			case "enter":
+				path := strings.TrimSpace(w.path.Value())
+				if err := SaveApplyReport(w.report, path); err != nil {
+					m.logger.Errorf("Error saving the apply report: %v", err)
+					m.status, m.statusError = "Failed: saving the report: "+err.Error(), true
+					return m, nil, true
+				}
+				m.status, m.statusError = fmt.Sprintf("Saved the report to %s.", path), false
+				w.saving = false
+				w.path.Blur()
+				return m, nil, true
+			}
+			var cmd tea.Cmd
+			w.path, cmd = w.path.Update(msg)
+			return m, cmd, true
+		}
+		switch {
+		case m.keys.Matches(msg, keyUp):
+			w.offset = max(w.offset-1, 0)
+		case m.keys.Matches(msg, keyDown):
+			w.offset = min(w.offset+1, max(len(reportLines(w.report))-1, 0))
+		case m.keys.Matches(msg, keySave):
+			w.saving = true
+			w.path.SetValue(fmt.Sprintf("ghm-apply-%s.json", w.report.FinishedAt.Format("20060102-150405")))
+			w.path.CursorEnd()
+			cmd := w.path.Focus()
+			return m, cmd, true
+		case m.keys.Matches(msg, keyBack):
+			m.repos.mode = reposList
+		default:
+			return m, nil, false
+		}
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// planApply compares the picked items with the picked repositories in the background
+func (m model) planApply() (tea.Model, tea.Cmd) {
+	repos := m.repos.wizard.repos.Selected()
+	secrets, workflows := splitSavedItems(m.repos.wizard.items.Selected())
+	ghm, state := m.ghm, m.state
+	return m.start(fmt.Sprintf("Planning changes to %d repositories", len(repos)), func(ctx context.Context) tea.Msg {
+		plan, err := PlanApply(ctx, ghm, state, repos, secrets, workflows)
+		return applyPlanMsg{plan: plan, err: err}
+	})
+}
+
+// executePlan applies the reviewed plan in the background. Each repository is reported as
+// it starts and ends, so the wizard can follow the apply.
+func (m model) executePlan() (tea.Model, tea.Cmd) {
+	if m.busy {
+		m.status, m.statusError = "Another operation is still running.", true
+		return m, nil
+	}
+	w := &m.repos.wizard
+	w.report = ApplyReport{}
+	for _, repo := range w.plan {
+		w.report.Repos = append(w.report.Repos, ApplyRepoReport{Repo: repo.Repo, Status: applyPending})
+	}
+	w.step = wizardRunning
+
+	plan, ghm, state, logger, events := w.plan, m.ghm, m.state, m.logger, m.events
+	return m.start(fmt.Sprintf("Applying to %d repositories", len(plan)), func(ctx context.Context) tea.Msg {
+		report, err := ExecuteApplyPlan(ctx, ghm, plan, func(repo ApplyRepoReport) {
+			select {
+			case events <- applyRepoMsg(repo):
+			case <-ctx.Done():
+			}
+		})
+		done := resultsDone(state, report.Results(), err, logger).(resultsDoneMsg)
+		return applyDoneMsg{report: report, done: done}
+	})
+}
+
+// splitSavedItems splits items given as "kind: name" into secret and workflow names
+func splitSavedItems(items []string) (secrets, workflows []string) {
+	for _, item := range items {
+		kind, name, _ := strings.Cut(item, ": ")
+		if kind == kindSecret {
+			secrets = append(secrets, name)
+		} else {
+			workflows = append(workflows, name)
+		}
+	}
+	return secrets, workflows
+}
+
+// wizardKeys returns the keys of the current wizard step for the footer; global is false
+// when the step takes every key
+func (m model) wizardKeys() (bindings []key.Binding, global bool) {
+	w := m.repos.wizard
+	switch {
+	case w.step == wizardPlan:
+		return []key.Binding{
+			m.keys.Hint(keyUp, "scroll up"),
+			m.keys.Hint(keyDown, "scroll down"),
+			m.keys.Hint(keySelect, "apply"),
+			m.keys.Hint(keyBack, "back"),
+		}, true
+	case w.step == wizardReport && !w.saving:
+		return []key.Binding{
+			m.keys.Hint(keyUp, "scroll up"),
+			m.keys.Hint(keyDown, "scroll down"),
+			m.keys.Hint(keySave, "save as JSON"),
+			m.keys.Hint(keyBack, "close"),
+		}, true
+	case w.step == wizardRunning:
+		return nil, true
+	}
+	return nil, false
+}
+
+// renderWizard renders the current step of the bulk apply wizard in height lines
+func renderWizard(m model, height int) string {
+	w := m.repos.wizard
+	steps := make([]string, len(wizardStepNames))
+	for i, name := range wizardStepNames {
+		style := wizardStepStyle
+		if i == w.step {
+			style = wizardActiveStepStyle
+		}
+		steps[i] = style.Render(fmt.Sprintf("%d %s", i+1, name))
+	}
+	title := formTitleStyle.Render("Bulk apply") + "  " + strings.Join(steps, wizardStepStyle.Render(" › "))
+
+	// Keep the lines that fit below the title
+	bodyHeight := 0
+	if height > 0 {
+		bodyHeight = max(height-2, 1)
+	}
+	var body string
+	switch w.step {
+	case wizardRepos:
+		switch {
+		case w.loadingRemote:
+			body = repoDimStyle.Render("Listing the repositories on GitHub...") + "\n"
+		case w.remoteError != "":
+			body = repoMissingStyle.Render("Could not list the repositories on GitHub: "+w.remoteError) + "\n"
+		}
+		body += w.repos.View() + repoDimStyle.Render("enter: pick the items to apply • esc: close")
+	case wizardItems:
+		body = w.items.View() + repoDimStyle.Render("enter: review the plan • esc: back to the repositories")
+	case wizardPlan:
+		body = scrollView(planLines(w.plan), w.offset, bodyHeight)
+	case wizardRunning:
+		body = renderApplyProgress(m, bodyHeight)
+	case wizardReport:
+		if w.saving {
+			bodyHeight = max(bodyHeight-2, 1)
+		}
+		body = scrollView(reportLines(w.report), w.offset, bodyHeight)
+		if w.saving {
+			body += "\n\n" + w.path.View()
+		}
+	}
+	return title + "\n\n" + body
+}
+
+// scrollView shows the lines from offset that fit in height, with their position on the
+// last line when they do not all fit; height zero shows all lines
+func scrollView(lines []string, offset, height int) string {
+	if height <= 0 || len(lines) <= height {
+		return strings.Join(lines, "\n")
+	}
+	rows := max(height-1, 1)
+	start := max(min(offset, len(lines)-rows), 0)
+	end := min(start+rows, len(lines))
+	return strings.Join(lines[start:end], "\n") + "\n" + repoDimStyle.Render(fmt.Sprintf("lines %d-%d of %d", start+1, end, len(lines)))
+}
+
+// planLines renders the plan: a summary, then the changes of each repository with the
+// diffs of the workflows
+func planLines(plan ApplyPlan) []string {
+	lines := []string{
+		fmt.Sprintf("Plan for %d repositories: %d to create, %d to update, %d unchanged",
+			len(plan), plan.Count(planCreate), plan.Count(planUpdate), plan.Count(planUnchanged)),
+		repoDimStyle.Render("Existing secrets are always updated; GitHub does not return their values."),
+		"",
+	}
+
+	nameWidth := 0
+	for _, repo := range plan {
+		for _, change := range repo.Changes {
+			nameWidth = max(nameWidth, len(change.Name))
+		}
+	}
+	for _, repo := range plan {
+		lines = append(lines, formFocusedLabelStyle.Render(repo.Repo))
+		if repo.Error != "" {
+			lines = append(lines, repoMissingStyle.Render("  ! could not check, will be skipped: "+repo.Error))
+			continue
+		}
+		for _, change := range repo.Changes {
+			line := fmt.Sprintf("  %s %-8s  %-*s  %s", planSymbol(change.Action), change.Kind, nameWidth, change.Name, change.Action)
+			switch change.Action {
+			case planCreate:
+				line = diffAddedStyle.Render(line)
+			case planUnchanged:
+				line = repoDimStyle.Render(line)
+			}
+			lines = append(lines, line)
+			for _, diff := range compactDiff(change.Diff, diffContext) {
+				lines = append(lines, "      "+diffLineView(diff))
+			}
+		}
+	}
+	return lines
+}
+
+// planSymbol marks a planned action
+func planSymbol(action string) string {
+	switch action {
+	case planCreate:
+		return "+"
+	case planUpdate:
+		return "~"
+	}
+	return "="
+}
+
+// compactDiff keeps the changed lines of a diff with context unchanged lines around them;
+// left out lines become a single line with Op 0
+func compactDiff(diff []DiffLine, context int) []DiffLine {
+	keep := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == ' ' {
+			continue
+		}
+		for j := max(i-context, 0); j <= min(i+context, len(diff)-1); j++ {
+			keep[j] = true
+		}
+	}
+
+	var compact []DiffLine
+	for i, line := range diff {
+		switch {
+		case keep[i]:
+			compact = append(compact, line)
+		case len(compact) == 0 || compact[len(compact)-1].Op != 0:
+			compact = append(compact, DiffLine{Text: "..."})
+		}
+	}
+	return compact
+}
+
+// diffLineView renders a diff line with its marker
+func diffLineView(line DiffLine) string {
+	switch line.Op {
+	case '+':
+		return diffAddedStyle.Render("+ " + line.Text)
+	case '-':
+		return diffRemovedStyle.Render("- " + line.Text)
+	case ' ':
+		return repoDimStyle.Render("  " + line.Text)
+	}
+	return repoDimStyle.Render(line.Text)
+}
+
+// renderApplyProgress renders the overall progress of a running apply and the state of
+// each repository in height lines
+func renderApplyProgress(m model, height int) string {
+	repos := m.repos.wizard.report.Repos
+	finished := 0
+	nameWidth := 0
+	for _, repo := range repos {
+		if repo.Status != applyPending && repo.Status != applyRunning {
+			finished++
+		}
+		nameWidth = max(nameWidth, len(repo.Repo))
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s %d of %d repositories\n\n", m.progressBar.ViewAs(float64(finished)/float64(max(len(repos), 1))), finished, len(repos)))
+
+	// Keep the repositories that fit below the progress bar, following the running one
+	rowsHeight := 0
+	if height > 0 {
+		rowsHeight = max(height-2, 1)
+	}
+	start, end := listWindow(finished, len(repos), rowsHeight)
+	for _, repo := range repos[start:end] {
+		b.WriteString(fmt.Sprintf("  %s %-*s  %s\n", applyStatusSymbol(repo.Status), nameWidth, repo.Repo, applyRepoSummary(repo)))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// applyStatusSymbol marks the state of a repository
+func applyStatusSymbol(status string) string {
+	switch status {
+	case applyRunning:
+		return repoCursorStyle.Render("●")
+	case applyDone:
+		return repoSelectedStyle.Render("✓")
+	case applyFailed:
+		return repoMissingStyle.Render("✗")
+	case applySkipped:
+		return repoDimStyle.Render("-")
+	}
+	return repoDimStyle.Render("○")
+}
+
+// applyRepoSummary describes the state of a repository in one line
+func applyRepoSummary(repo ApplyRepoReport) string {
+	switch {
+	case repo.Status == applySkipped:
+		return repoDimStyle.Render("skipped: " + repo.Error)
+	case repo.Error != "":
+		return repoMissingStyle.Render("failed: " + repo.Error)
+	case repo.Status == applyFailed:
+		return repoMissingStyle.Render(fmt.Sprintf("failed: %d of %d items", repo.Results.failed(), len(repo.Results)))
+	case repo.Status == applyDone:
+		return repoSelectedStyle.Render(fmt.Sprintf("done: %d items", len(repo.Results)))
+	}
+	return repoDimStyle.Render(repo.Status)
+}
+
+// reportLines renders the report: a summary, then the result of each item by repository
+func reportLines(report ApplyReport) []string {
+	counts := make(map[string]int)
+	for _, result := range report.Results() {
+		counts[result.Action]++
+	}
+	lines := []string{
+		fmt.Sprintf("%d of %d repositories done, %d failed, %d skipped",
+			report.Count(applyDone), len(report.Repos), report.Count(applyFailed), report.Count(applySkipped)),
+		fmt.Sprintf("%d items added, %d unchanged, %d failed", counts[actionAdded], counts[actionUnchanged], counts[actionFailed]),
+		"",
+	}
+	for _, repo := range report.Repos {
+		lines = append(lines, fmt.Sprintf("%s %s  %s", applyStatusSymbol(repo.Status), formFocusedLabelStyle.Render(repo.Repo), applyRepoSummary(repo)))
+		for _, result := range repo.Results {
+			line := fmt.Sprintf("    %s '%s' %s", result.Kind, result.Name, result.Action)
+			switch result.Action {
+			case actionFailed:
+				line = repoMissingStyle.Render(line + ": " + result.Error)
+			case actionUnchanged:
+				line = repoDimStyle.Render(line)
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}